@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -10,9 +11,11 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/yourusername/ecomate/backend/internal/config"
 	"github.com/yourusername/ecomate/backend/internal/infrastructure"
 	"github.com/yourusername/ecomate/backend/internal/interfaces"
+	"github.com/yourusername/ecomate/backend/internal/realtime"
 	"github.com/yourusername/ecomate/backend/internal/usecase"
 )
 
@@ -34,6 +37,10 @@ func main() {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	if err := infrastructure.MigratePurchaseStatuses(db); err != nil {
+		log.Fatalf("Failed to migrate purchase statuses: %v", err)
+	}
+
 	// Seed achievements
 	if err := infrastructure.SeedAchievements(db); err != nil {
 		log.Fatalf("Failed to seed achievements: %v", err)
@@ -54,16 +61,33 @@ func main() {
 	blockchainRepo := infrastructure.NewBlockchainRepository(db)
 	nftRepo := infrastructure.NewNFTRepository(db)
 	chatHistoryRepo := infrastructure.NewChatHistoryRepository(db)
+	chatToolCallRepo := infrastructure.NewChatToolCallRepository(db)
+	productEmbeddingRepo := infrastructure.NewProductEmbeddingRepository(db)
 	co2GoalRepo := infrastructure.NewCO2GoalRepository(db)
 	shippingRepo := infrastructure.NewShippingTrackingRepository(db)
+	disputeRepo := infrastructure.NewDisputeRepository(db)
+	leaseRepo := infrastructure.NewLeaseRepository(db)
+	unitOfWork := infrastructure.NewUnitOfWork(db)
+	paymentProvider, err := infrastructure.NewPaymentProvider(&cfg.Payment, cfg.Server.Env, db)
+	if err != nil {
+		log.Fatalf("Failed to set up payments: %v", err)
+	}
 
 	// Add database indexes for performance
 	if err := infrastructure.AddIndexes(db); err != nil {
 		log.Printf("Warning: Failed to add indexes: %v", err)
 	}
 
+	// Initialize LLM provider
+	llmProvider, err := infrastructure.NewLLMProvider(&cfg.AI)
+	if err != nil {
+		log.Printf("Warning: LLM provider disabled: %v", err)
+	} else if llmProvider != nil {
+		log.Printf("Using %s LLM provider", llmProvider.Name())
+	}
+
 	// Initialize AI client
-	aiClient, err := infrastructure.NewAIClient(cfg.AI.ServiceURL)
+	aiClient, err := infrastructure.NewAIClient(cfg.AI.ServiceURL, llmProvider)
 	if err != nil {
 		log.Printf("Warning: AI service not available: %v", err)
 		aiClient = nil
@@ -72,30 +96,63 @@ func main() {
 		defer aiClient.Close()
 	}
 
+	// Replicas share events, such as WebSocket messages and product writes,
+	// through the broker
+	broker, err := realtime.NewBroker(&cfg.Realtime, db)
+	if err != nil {
+		log.Fatalf("Failed to start realtime broker: %v", err)
+	}
+	defer broker.Close()
+	hostname, _ := os.Hostname()
+	replicaID := fmt.Sprintf("%s-%s", hostname, uuid.New())
+
 	// Initialize use cases
 	authUseCase := usecase.NewAuthUseCase(userRepo, cfg.JWT.Secret, cfg.JWT.ExpirationHours)
-	productUseCase := usecase.NewProductUseCase(productRepo, aiClient)
-	purchaseUseCase := usecase.NewPurchaseUseCase(purchaseRepo, productRepo, userRepo)
-	messageUseCase := usecase.NewMessageUseCase(messageRepo, productRepo)
-	sustainabilityUseCase := usecase.NewSustainabilityUseCase(sustainabilityRepo, userRepo)
+	embedder, err := infrastructure.NewEmbedder(&cfg.AI)
+	if err != nil {
+		log.Fatalf("Failed to configure embeddings: %v", err)
+	}
+	semanticSearchUseCase := usecase.NewSemanticSearchUseCase(embedder, infrastructure.NewHNSWIndex(16, 200, 64), productEmbeddingRepo, productRepo)
+	productSearchUseCase := usecase.NewProductSearchUseCase(infrastructure.NewInvertedIndex(nil), productRepo)
+	productIndexSync := usecase.NewProductIndexSync(broker, replicaID, productRepo, productSearchUseCase, semanticSearchUseCase)
+	productUseCase := usecase.NewProductUseCase(productRepo, aiClient, productSearchUseCase, semanticSearchUseCase, productIndexSync)
 	notificationUseCase := usecase.NewNotificationUseCase(notificationRepo)
+	purchaseUseCase := usecase.NewPurchaseUseCase(unitOfWork, purchaseRepo, productRepo, userRepo, paymentProvider, notificationUseCase)
+	// Only the memory broker limits the API to a single replica
+	singleReplica := cfg.Realtime.Broker == "" || cfg.Realtime.Broker == "memory"
+	attachmentStorage, err := infrastructure.NewAttachmentStorage(&cfg.Storage, singleReplica, db)
+	if err != nil {
+		log.Fatalf("Failed to set up attachment storage: %v", err)
+	}
+	messageUseCase := usecase.NewMessageUseCase(messageRepo, productRepo, attachmentStorage)
+	sustainabilityUseCase := usecase.NewSustainabilityUseCase(sustainabilityRepo, userRepo)
 	reviewUseCase := usecase.NewReviewUseCase(reviewRepo, purchaseRepo)
 	recommendationUseCase := usecase.NewRecommendationUseCase(productRepo, purchaseRepo)
-	offerUseCase := usecase.NewOfferUseCase(offerRepo, productRepo, aiClient)
+	offerUseCase := usecase.NewOfferUseCase(unitOfWork, offerRepo, productRepo, messageUseCase, aiClient, time.Now)
 	analyticsUseCase := usecase.NewAnalyticsUseCase(analyticsRepo)
 	salesPredictionUseCase := usecase.NewSalesPredictionUseCase(productRepo, purchaseRepo, userRepo)
-	auctionUseCase := usecase.NewAuctionUseCase(auctionRepo, bidRepo, productRepo)
+	auctionUseCase := usecase.NewAuctionUseCase(unitOfWork, auctionRepo, bidRepo, productRepo, notificationUseCase, time.Now)
 	voiceSearchUseCase := usecase.NewVoiceSearchUseCase(productRepo)
 	blockchainUseCase := usecase.NewBlockchainUseCase(blockchainRepo, nftRepo, purchaseRepo, productRepo)
 	chatHistoryUseCase := usecase.NewChatHistoryUseCase(chatHistoryRepo)
+	chatAssistantUseCase := usecase.NewChatAssistantUseCase(aiClient, productRepo, reviewRepo, chatHistoryUseCase)
 	co2GoalUseCase := usecase.NewCO2GoalUseCase(co2GoalRepo)
 	shippingUseCase := usecase.NewShippingTrackingUseCase(shippingRepo)
+	chatAgentUseCase := usecase.NewChatAgentUseCase(aiClient, chatToolCallRepo, productRepo, sustainabilityRepo, offerUseCase, purchaseRepo, shippingRepo, time.Now)
+	disputeUseCase := usecase.NewDisputeUseCase(unitOfWork, disputeRepo, paymentProvider, notificationUseCase)
+
+	// WebSocket events reach clients of every replica through the broker
+	hub := realtime.NewHub(broker, realtime.ConnOptions{
+		SendBuffer:     cfg.Realtime.SendBuffer,
+		MaxMessageSize: int64(cfg.Realtime.MaxMessageBytes),
+		PongWait:       time.Duration(cfg.Realtime.PongWaitSec) * time.Second,
+	})
 
 	// Initialize handlers
 	authHandler := interfaces.NewAuthHandler(authUseCase)
-	productHandler := interfaces.NewProductHandler(productUseCase, authUseCase, sustainabilityRepo)
+	productHandler := interfaces.NewProductHandler(productUseCase, authUseCase, sustainabilityRepo, semanticSearchUseCase)
 	purchaseHandler := interfaces.NewPurchaseHandler(purchaseUseCase)
-	messageHandler := interfaces.NewMessageHandler(messageUseCase, authUseCase)
+	messageHandler := interfaces.NewMessageHandler(messageUseCase, authUseCase, hub, cfg.CORS.AllowedOrigins)
 	sustainabilityHandler := interfaces.NewSustainabilityHandler(sustainabilityUseCase)
 	notificationHandler := interfaces.NewNotificationHandler(notificationUseCase)
 	reviewHandler := interfaces.NewReviewHandler(reviewUseCase)
@@ -103,16 +160,17 @@ func main() {
 	offerHandler := interfaces.NewOfferHandler(offerUseCase)
 	analyticsHandler := interfaces.NewAnalyticsHandler(analyticsUseCase)
 	salesPredictionHandler := interfaces.NewSalesPredictionHandler(salesPredictionUseCase)
-	chatbotHandler := interfaces.NewChatbotHandler(aiClient, productRepo)
-	auctionHandler := interfaces.NewAuctionHandler(auctionUseCase)
+	chatbotHandler := interfaces.NewChatbotHandler(chatAssistantUseCase, chatAgentUseCase)
+	auctionHandler := interfaces.NewAuctionHandler(auctionUseCase, hub, cfg.CORS.AllowedOrigins)
 	voiceSearchHandler := interfaces.NewVoiceSearchHandler(voiceSearchUseCase)
 	blockchainHandler := interfaces.NewBlockchainHandler(blockchainUseCase)
 	adminHandler := interfaces.NewAdminHandler(productUseCase, authUseCase)
 	uploadHandler := interfaces.NewUploadHandler()
-	aiHandler := interfaces.NewAIHandler()
+	aiHandler := interfaces.NewAIHandler(llmProvider)
 	chatHistoryHandler := interfaces.NewChatHistoryHandler(chatHistoryUseCase)
 	co2GoalHandler := interfaces.NewCO2GoalHandler(co2GoalUseCase)
 	shippingHandler := interfaces.NewShippingHandler(shippingUseCase)
+	disputeHandler := interfaces.NewDisputeHandler(disputeUseCase, uploadHandler)
 
 	// Setup Gin
 	gin.SetMode(cfg.Server.GinMode)
@@ -149,6 +207,7 @@ func main() {
 		{
 			products.GET("", productHandler.List)
 			products.GET("/:id", productHandler.GetByID)
+			products.GET("/:id/similar", productHandler.Similar)
 			products.POST("", interfaces.AuthMiddleware(authUseCase), productHandler.Create)
 			products.PUT("/:id", interfaces.AuthMiddleware(authUseCase), productHandler.Update)
 			products.DELETE("/:id", interfaces.AuthMiddleware(authUseCase), productHandler.Delete)
@@ -164,7 +223,34 @@ func main() {
 			purchases.POST("", purchaseHandler.Create)
 			purchases.GET("", purchaseHandler.List)
 			purchases.GET("/:id", purchaseHandler.GetByID)
+			purchases.POST("/:id/pay", purchaseHandler.Pay)
+			purchases.POST("/:id/ship", purchaseHandler.Ship)
+			purchases.POST("/:id/deliver", purchaseHandler.Deliver)
 			purchases.PATCH("/:id/complete", purchaseHandler.Complete)
+			purchases.POST("/:id/cancel", purchaseHandler.Cancel)
+		}
+
+		// Dispute routes
+		disputes := v1.Group("/disputes")
+		disputes.Use(interfaces.AuthMiddleware(authUseCase))
+		{
+			disputes.POST("", disputeHandler.Open)
+			disputes.GET("", disputeHandler.List)
+			disputes.GET("/:id", disputeHandler.GetByID)
+			disputes.POST("/:id/evidence", disputeHandler.AddEvidence)
+			disputes.POST("/:id/messages", disputeHandler.PostMessage)
+		}
+
+		// Moderation routes
+		moderation := v1.Group("/moderation")
+		moderation.Use(interfaces.AuthMiddleware(authUseCase))
+		moderation.Use(interfaces.RequireModerator(authUseCase))
+		{
+			moderation.GET("/disputes", disputeHandler.Queue)
+			moderation.GET("/disputes/:id", disputeHandler.ModeratorGetByID)
+			moderation.POST("/disputes/:id/assign", disputeHandler.Assign)
+			moderation.POST("/disputes/:id/messages", disputeHandler.ModeratorPostMessage)
+			moderation.POST("/disputes/:id/resolve", disputeHandler.Resolve)
 		}
 
 		// Messaging routes
@@ -176,10 +262,16 @@ func main() {
 			conversations.GET("/product/:productId/seller/:sellerId", messageHandler.GetOrCreateConversation)
 			conversations.GET("/:id/messages", messageHandler.GetMessages)
 			conversations.POST("/:id/messages", messageHandler.SendMessage)
+			conversations.POST("/:id/read", messageHandler.MarkRead)
+			conversations.POST("/:id/attachments", messageHandler.SendAttachments)
+			conversations.GET("/:id/attachments/:attachmentId", messageHandler.GetAttachment)
+			conversations.GET("/:id/attachments/:attachmentId/thumbnail", messageHandler.GetAttachmentThumbnail)
+			conversations.POST("/:id/messages/:messageId/read", messageHandler.MarkMessageRead)
 		}
 
 		// WebSocket route
 		v1.GET("/ws/conversations/:id", messageHandler.WebSocketHandler)
+		v1.GET("/ws/user", messageHandler.UserWebSocketHandler)
 
 		// Sustainability routes
 		sustainability := v1.Group("/sustainability")
@@ -224,13 +316,22 @@ func main() {
 			offers.GET("/my", offerHandler.GetMyOffers)
 			offers.GET("/products/:id", offerHandler.GetProductOffers)
 			offers.GET("/products/:id/ai-suggestion", offerHandler.GetNegotiationSuggestion)
+			offers.GET("/:id", offerHandler.GetOffer)
 			offers.PATCH("/:id/respond", offerHandler.RespondOffer)
+			offers.POST("/:id/counter", offerHandler.CounterOffer)
+			offers.POST("/:id/checkout", purchaseHandler.CheckoutOffer)
 		}
 
-		// Chatbot routes (no auth required for public access)
+		// Chatbot routes (no auth required for public access; signed-in users get their history saved)
 		chatbot := v1.Group("/chatbot")
+		chatbot.Use(interfaces.OptionalAuthMiddleware(authUseCase))
 		{
 			chatbot.POST("/chat", chatbotHandler.Chat)
+			chatbot.POST("/chat/stream", chatbotHandler.ChatStream)
+			chatbot.POST("/agent", interfaces.AuthMiddleware(authUseCase), chatbotHandler.Agent)
+			chatbot.GET("/actions", interfaces.AuthMiddleware(authUseCase), chatbotHandler.GetActions)
+			chatbot.POST("/actions/:id/confirm", interfaces.AuthMiddleware(authUseCase), chatbotHandler.ConfirmAction)
+			chatbot.POST("/actions/:id/reject", interfaces.AuthMiddleware(authUseCase), chatbotHandler.RejectAction)
 		}
 
 		// Analytics routes
@@ -255,9 +356,13 @@ func main() {
 		auctions := v1.Group("/auctions")
 		{
 			auctions.GET("", auctionHandler.GetActiveAuctions)
+			auctions.GET("/upcoming", auctionHandler.GetUpcomingAuctions)
 			auctions.GET("/:id", auctionHandler.GetAuction)
 			auctions.POST("", interfaces.AuthMiddleware(authUseCase), auctionHandler.CreateAuction)
 			auctions.POST("/:id/bid", interfaces.AuthMiddleware(authUseCase), auctionHandler.PlaceBid)
+			auctions.POST("/:id/proxy-bid", interfaces.AuthMiddleware(authUseCase), auctionHandler.SetProxyBid)
+			auctions.POST("/:id/buy-now", interfaces.AuthMiddleware(authUseCase), auctionHandler.BuyNow)
+			auctions.POST("/:id/accept", interfaces.AuthMiddleware(authUseCase), auctionHandler.AcceptDutchPrice)
 			auctions.GET("/:id/bids", auctionHandler.GetAuctionBids)
 		}
 
@@ -332,6 +437,72 @@ func main() {
 	// Serve uploaded files
 	router.GET("/uploads/:filename", uploadHandler.ServeUploadedFile)
 
+	// Background jobs. Every replica runs them; database leases make sure
+	// only one replica at a time does the work.
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+
+	// Close ended auctions
+	auctionCloser := usecase.NewAuctionCloser(auctionUseCase, auctionRepo, auctionHandler.BroadcastAuctionEnded)
+	auctionClosing := usecase.NewLeasedJob(leaseRepo, "auction_closer", replicaID, 30*time.Second, time.Now,
+		func(now time.Time) {
+			if _, err := auctionCloser.CloseExpired(now); err != nil {
+				log.Printf("Warning: Failed to close auctions: %v", err)
+			}
+		})
+	go auctionClosing.Run(jobsCtx)
+
+	// The search indexes live in this replica's memory, so every replica
+	// keeps its own copy up to date, without a lease, until shutdown
+	indexCtx, stopIndexing := context.WithCancel(context.Background())
+
+	// Load listings into the full-text search index
+	go func() {
+		if err := productSearchUseCase.Rebuild(); err != nil {
+			log.Printf("Warning: Failed to build search index: %v", err)
+		}
+	}()
+
+	// Apply product writes made on other replicas to the indexes
+	go func() {
+		if err := productIndexSync.Run(indexCtx); err != nil {
+			log.Printf("Warning: Product index sync stopped: %v", err)
+		}
+	}()
+
+	// Keep product embeddings and the similar-items index up to date
+	go func() {
+		if err := semanticSearchUseCase.Run(indexCtx); err != nil {
+			log.Printf("Warning: Semantic search index stopped: %v", err)
+		}
+	}()
+
+	// Escalate disputes whose response deadline has passed
+	disputeEscalation := usecase.NewLeasedJob(leaseRepo, "dispute_escalation", replicaID, 10*time.Minute, time.Now,
+		func(now time.Time) {
+			if n, err := disputeUseCase.EscalateOverdue(now); err != nil {
+				log.Printf("Warning: Failed to escalate disputes: %v", err)
+			} else if n > 0 {
+				log.Printf("Escalated %d overdue disputes", n)
+			}
+		})
+	go disputeEscalation.Run(jobsCtx)
+
+	// Expire offers nobody responded to and release lapsed reservations
+	offerMaintenance := usecase.NewLeasedJob(leaseRepo, "offer_maintenance", replicaID, 5*time.Minute, time.Now,
+		func(now time.Time) {
+			if n, err := offerUseCase.ExpireOffers(now); err != nil {
+				log.Printf("Warning: Failed to expire offers: %v", err)
+			} else if n > 0 {
+				log.Printf("Expired %d offers", n)
+			}
+			if n, err := purchaseUseCase.ReleaseReservations(now); err != nil {
+				log.Printf("Warning: Failed to release reservations: %v", err)
+			} else if n > 0 {
+				log.Printf("Released %d offer reservations", n)
+			}
+		})
+	go offerMaintenance.Run(jobsCtx)
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -346,6 +517,13 @@ func main() {
 
 	<-quit
 	log.Println("Shutting down server...")
+	stopJobs()
+	stopIndexing()
+
+	if managed, ok := llmProvider.(*infrastructure.ManagedLLMProvider); ok {
+		usage := managed.Usage()
+		log.Printf("LLM usage: %d calls (%d failed), %d tokens", usage.Calls, usage.Failures, usage.Tokens.TotalTokens)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
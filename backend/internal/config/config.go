@@ -16,6 +16,8 @@ type Config struct {
 	AI       AIConfig
 	Storage  StorageConfig
 	CORS     CORSConfig
+	Realtime RealtimeConfig
+	Payment  PaymentConfig
 }
 
 type ServerConfig struct {
@@ -41,11 +43,53 @@ type JWTConfig struct {
 
 type AIConfig struct {
 	ServiceURL string
+
+	// LLMProvider selects the text model backend: gemini, openai (any
+	// OpenAI-compatible endpoint, including local servers) or fake. When it
+	// is empty, gemini is used if an API key is set and LLM calls are
+	// disabled otherwise.
+	LLMProvider       string
+	LLMAPIKey         string
+	LLMBaseURL        string
+	LLMModel          string
+	LLMTimeoutSeconds int
+	LLMMaxRetries     int
+
+	// EmbeddingProvider selects how product vectors are computed: local (a
+	// hashing model that needs no service) or openai (any OpenAI-compatible
+	// /embeddings endpoint)
+	EmbeddingProvider   string
+	EmbeddingAPIKey     string
+	EmbeddingBaseURL    string
+	EmbeddingModel      string
+	EmbeddingDimensions int
+}
+
+// RealtimeConfig selects how WebSocket events reach the other API replicas:
+// memory (a single replica) or mysql (replicas poll a shared table), and the
+// limits each WebSocket connection is held to
+type RealtimeConfig struct {
+	Broker          string
+	PollIntervalMs  int
+	SendBuffer      int
+	MaxMessageBytes int
+	PongWaitSec     int
+}
+
+// PaymentConfig selects where escrow is kept: database (shared by every
+// replica) or memory (lost on restart, development only)
+type PaymentConfig struct {
+	Provider string
 }
 
 type StorageConfig struct {
 	GCSBucketName string
 	CDNBaseURL    string
+	// AttachmentStorage is where message attachments, which are served to
+	// conversation participants only, are kept: database (shared by every
+	// replica) or local (AttachmentDir, a single replica only)
+	AttachmentStorage string
+	AttachmentDir     string
 }
 
 type CORSConfig struct {
@@ -76,15 +120,39 @@ func Load() (*Config, error) {
 			ExpirationHours: getEnvAsInt("JWT_EXPIRATION_HOURS", 72),
 		},
 		AI: AIConfig{
-			ServiceURL: getEnv("AI_SERVICE_URL", "localhost:50051"),
+			ServiceURL:        getEnv("AI_SERVICE_URL", "localhost:50051"),
+			LLMProvider:       getEnv("LLM_PROVIDER", ""),
+			LLMAPIKey:         getEnv("LLM_API_KEY", getEnv("GEMINI_API_KEY", os.Getenv("GOOGLE_API_KEY"))),
+			LLMBaseURL:        getEnv("LLM_BASE_URL", ""),
+			LLMModel:          getEnv("LLM_MODEL", ""),
+			LLMTimeoutSeconds: getEnvAsInt("LLM_TIMEOUT_SECONDS", 30),
+			LLMMaxRetries:     getEnvAsInt("LLM_MAX_RETRIES", 2),
+
+			EmbeddingProvider:   getEnv("EMBEDDING_PROVIDER", "local"),
+			EmbeddingAPIKey:     getEnv("EMBEDDING_API_KEY", getEnv("LLM_API_KEY", "")),
+			EmbeddingBaseURL:    getEnv("EMBEDDING_BASE_URL", ""),
+			EmbeddingModel:      getEnv("EMBEDDING_MODEL", ""),
+			EmbeddingDimensions: getEnvAsInt("EMBEDDING_DIMENSIONS", 256),
 		},
 		Storage: StorageConfig{
-			GCSBucketName: getEnv("GCS_BUCKET_NAME", ""),
-			CDNBaseURL:    getEnv("CDN_BASE_URL", ""),
+			GCSBucketName:     getEnv("GCS_BUCKET_NAME", ""),
+			CDNBaseURL:        getEnv("CDN_BASE_URL", ""),
+			AttachmentStorage: getEnv("ATTACHMENT_STORAGE", "database"),
+			AttachmentDir:     getEnv("ATTACHMENT_DIR", "./attachments"),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: strings.Split(getEnv("ALLOWED_ORIGINS", "http://localhost:3000"), ","),
 		},
+		Realtime: RealtimeConfig{
+			Broker:          getEnv("REALTIME_BROKER", "memory"),
+			PollIntervalMs:  getEnvAsInt("REALTIME_POLL_INTERVAL_MS", 200),
+			SendBuffer:      getEnvAsInt("REALTIME_SEND_BUFFER", 64),
+			MaxMessageBytes: getEnvAsInt("REALTIME_MAX_MESSAGE_BYTES", 65536),
+			PongWaitSec:     getEnvAsInt("REALTIME_PONG_WAIT_SEC", 60),
+		},
+		Payment: PaymentConfig{
+			Provider: getEnv("PAYMENT_PROVIDER", "database"),
+		},
 	}
 
 	// Validate required fields
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,32 +15,96 @@ const (
 	AuctionStatusCancelled AuctionStatus = "cancelled"
 )
 
+type AuctionType string
+
+const (
+	// AuctionTypeEnglish is an ascending auction won by the highest bid
+	AuctionTypeEnglish AuctionType = "english"
+	// AuctionTypeDutch starts high and drops on a schedule until someone accepts
+	AuctionTypeDutch AuctionType = "dutch"
+)
+
+// StartingSoonWindow is how far ahead the "starting soon" listing looks
+const StartingSoonWindow = 24 * time.Hour
+
 type Auction struct {
-	ID              uuid.UUID     `json:"id" gorm:"type:char(36);primary_key"`
-	ProductID       uuid.UUID     `json:"product_id" gorm:"type:char(36);not null;index"`
-	Product         *Product      `json:"product,omitempty" gorm:"foreignKey:ProductID"`
-	SellerID        uuid.UUID     `json:"seller_id" gorm:"type:char(36);not null;index"`
-	StartPrice      int           `json:"start_price" gorm:"not null"`
-	CurrentBid      int           `json:"current_bid" gorm:"default:0"`
-	MinBidIncrement int           `json:"min_bid_increment" gorm:"default:100"`
-	WinnerID        *uuid.UUID    `json:"winner_id" gorm:"type:char(36)"`
-	Winner          *User         `json:"winner,omitempty" gorm:"foreignKey:WinnerID"`
-	Status          AuctionStatus `json:"status" gorm:"default:active"`
-	StartTime       time.Time     `json:"start_time"`
-	EndTime         time.Time     `json:"end_time"`
-	CreatedAt       time.Time     `json:"created_at"`
-	UpdatedAt       time.Time     `json:"updated_at"`
+	ID                       uuid.UUID     `json:"id" gorm:"type:char(36);primary_key"`
+	ProductID                uuid.UUID     `json:"product_id" gorm:"type:char(36);not null;index"`
+	Product                  *Product      `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	SellerID                 uuid.UUID     `json:"seller_id" gorm:"type:char(36);not null;index"`
+	Type                     AuctionType   `json:"type" gorm:"default:english"`
+	StartPrice               int           `json:"start_price" gorm:"not null"`
+	CurrentBid               int           `json:"current_bid" gorm:"default:0"`
+	MinBidIncrement          int           `json:"min_bid_increment" gorm:"default:100"`
+	ReservePrice             int           `json:"-"` // hidden minimum; 0 means no reserve
+	HasReserve               bool          `json:"has_reserve"`
+	ReserveMet               bool          `json:"reserve_met"` // with no reserve, set by the first bid
+	BuyNowPrice              int           `json:"buy_now_price,omitempty"`
+	SoftCloseMinutes         int           `json:"soft_close_minutes" gorm:"default:2"`
+	FloorPrice               int           `json:"floor_price,omitempty"` // Dutch: the price stops dropping here
+	PriceDropAmount          int           `json:"price_drop_amount,omitempty"`
+	PriceDropIntervalMinutes int           `json:"price_drop_interval_minutes,omitempty"`
+	CurrentPrice             int           `json:"current_price" gorm:"-"` // filled in when read: the Dutch price now, or the current bid
+	WinnerID                 *uuid.UUID    `json:"winner_id" gorm:"type:char(36)"`
+	Winner                   *User         `json:"winner,omitempty" gorm:"foreignKey:WinnerID"`
+	PurchaseID               *uuid.UUID    `json:"purchase_id" gorm:"type:char(36)"` // created for the winner when the auction closes
+	Status                   AuctionStatus `json:"status" gorm:"default:active;index"`
+	StartTime                time.Time     `json:"start_time" gorm:"index"`
+	EndTime                  time.Time     `json:"end_time"`
+	CreatedAt                time.Time     `json:"created_at"`
+	UpdatedAt                time.Time     `json:"updated_at"`
+}
+
+// DefaultSoftCloseMinutes is used when the seller does not choose a window
+const DefaultSoftCloseMinutes = 2
+
+// BuyNowAvailable reports whether the auction can still be bought outright.
+// Buy-it-now disappears once the reserve is met (or, without a reserve, once
+// anyone has bid).
+func (a *Auction) BuyNowAvailable() bool {
+	return a.BuyNowPrice > 0 && a.Status == AuctionStatusActive && !a.ReserveMet
+}
+
+// HasStarted reports whether bidding has opened at t
+func (a *Auction) HasStarted(t time.Time) bool {
+	return !t.Before(a.StartTime)
+}
+
+// PriceAt is the price a Dutch auction asks at t: the start price less one
+// drop per elapsed interval, never below the floor. English auctions ask
+// their current bid.
+func (a *Auction) PriceAt(t time.Time) int {
+	if a.Type != AuctionTypeDutch || a.PriceDropIntervalMinutes <= 0 {
+		return a.CurrentBid
+	}
+	if t.Before(a.StartTime) {
+		return a.StartPrice
+	}
+
+	interval := time.Duration(a.PriceDropIntervalMinutes) * time.Minute
+	drops := int(t.Sub(a.StartTime) / interval)
+	price := a.StartPrice - drops*a.PriceDropAmount
+	if price < a.FloorPrice {
+		price = a.FloorPrice
+	}
+	return price
+}
+
+// SoftCloseWindow is how close to the end a bid must be to extend the auction
+func (a *Auction) SoftCloseWindow() time.Duration {
+	return time.Duration(a.SoftCloseMinutes) * time.Minute
 }
 
 type Bid struct {
-	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
-	AuctionID  uuid.UUID  `json:"auction_id" gorm:"type:char(36);not null;index"`
-	Auction    *Auction   `json:"auction,omitempty" gorm:"foreignKey:AuctionID"`
-	BidderID   uuid.UUID  `json:"bidder_id" gorm:"type:char(36);not null;index"`
-	Bidder     *User      `json:"bidder,omitempty" gorm:"foreignKey:BidderID"`
-	Amount     int        `json:"amount" gorm:"not null"`
-	IsWinning  bool       `json:"is_winning" gorm:"default:false"`
-	CreatedAt  time.Time  `json:"created_at"`
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	AuctionID uuid.UUID `json:"auction_id" gorm:"type:char(36);not null;index"`
+	Auction   *Auction  `json:"auction,omitempty" gorm:"foreignKey:AuctionID"`
+	BidderID  uuid.UUID `json:"bidder_id" gorm:"type:char(36);not null;index"`
+	Bidder    *User     `json:"bidder,omitempty" gorm:"foreignKey:BidderID"`
+	Amount    int       `json:"amount" gorm:"not null"`
+	IsWinning bool      `json:"is_winning" gorm:"default:false"`
+	IsAuto    bool      `json:"is_auto" gorm:"default:false"` // placed by the bidder's proxy
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type AuctionRepository interface {
@@ -47,9 +112,47 @@ type AuctionRepository interface {
 	FindByID(id uuid.UUID) (*Auction, error)
 	FindByProductID(productID uuid.UUID) (*Auction, error)
 	FindActiveAuctions() ([]*Auction, error)
+	// FindUpcoming lists active auctions that start after now but before until
+	FindUpcoming(now, until time.Time) ([]*Auction, error)
+	// FindExpired lists active auctions whose end time is not after now
+	FindExpired(now time.Time, limit int) ([]*Auction, error)
+	// FindByIDForUpdate locks the auction row until the surrounding transaction ends
+	FindByIDForUpdate(id uuid.UUID) (*Auction, error)
+	// RaiseBid sets the current bid to amount only if the auction is still
+	// running and amount beats the current bid by at least the increment. It
+	// reports false if a concurrent bid got there first.
+	RaiseBid(id uuid.UUID, amount int, now time.Time) (bool, error)
 	Update(auction *Auction) error
 }
 
+// ProxyBid is a bidder's private maximum. Whenever they are outbid, a bid is
+// placed on their behalf, up to MaxAmount.
+type ProxyBid struct {
+	ID           uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	AuctionID    uuid.UUID `json:"auction_id" gorm:"type:char(36);not null;uniqueIndex:idx_proxy_bids_auction_bidder"`
+	BidderID     uuid.UUID `json:"bidder_id" gorm:"type:char(36);not null;uniqueIndex:idx_proxy_bids_auction_bidder"`
+	MaxAmount    int       `json:"max_amount" gorm:"not null"`
+	RegisteredAt time.Time `json:"registered_at"` // reset when the maximum changes; earliest wins ties
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type ProxyBidRepository interface {
+	// Upsert registers a proxy or replaces the bidder's existing maximum
+	Upsert(proxy *ProxyBid) error
+	FindByAuctionID(auctionID uuid.UUID) ([]*ProxyBid, error)
+}
+
+// OutbidError is returned when a bid is too low, including when a concurrent
+// bid raised the price while it was being placed
+type OutbidError struct {
+	MinBid int
+}
+
+func (e *OutbidError) Error() string {
+	return fmt.Sprintf("bid amount must be at least %d", e.MinBid)
+}
+
 type BidRepository interface {
 	Create(bid *Bid) error
 	FindByAuctionID(auctionID uuid.UUID) ([]*Bid, error)
@@ -58,12 +161,34 @@ type BidRepository interface {
 }
 
 type CreateAuctionRequest struct {
-	ProductID       string `json:"product_id" binding:"required"`
-	StartPrice      int    `json:"start_price" binding:"required,min=1"`
-	MinBidIncrement int    `json:"min_bid_increment" binding:"required,min=1"`
-	DurationMinutes int    `json:"duration_minutes" binding:"required,min=1,max=10080"` // Max 1 week
+	ProductID        string      `json:"product_id" binding:"required"`
+	Type             AuctionType `json:"type" binding:"omitempty,oneof=english dutch"` // defaults to english
+	StartPrice       int         `json:"start_price" binding:"required,min=1"`
+	MinBidIncrement  int         `json:"min_bid_increment" binding:"omitempty,min=1"`         // required for english auctions
+	DurationMinutes  int         `json:"duration_minutes" binding:"required,min=1,max=10080"` // Max 1 week
+	StartTime        *time.Time  `json:"start_time"`                                          // defaults to now
+	ReservePrice     int         `json:"reserve_price" binding:"omitempty,min=1"`
+	BuyNowPrice      int         `json:"buy_now_price" binding:"omitempty,min=1"`
+	SoftCloseMinutes int         `json:"soft_close_minutes" binding:"omitempty,min=1,max=30"` // defaults to DefaultSoftCloseMinutes
+
+	// Dutch auctions only
+	FloorPrice               int `json:"floor_price" binding:"omitempty,min=1"`
+	PriceDropAmount          int `json:"price_drop_amount" binding:"omitempty,min=1"`
+	PriceDropIntervalMinutes int `json:"price_drop_interval_minutes" binding:"omitempty,min=1,max=1440"`
+}
+
+// BidOutcome is the result of a bid or proxy registration
+type BidOutcome struct {
+	Bid      *Bid
+	AutoBids []*Bid    // placed by proxies in response
+	EndTime  time.Time // after any soft-close extension
+	Extended bool
 }
 
 type PlaceBidRequest struct {
 	Amount int `json:"amount" binding:"required,min=1"`
 }
+
+type ProxyBidRequest struct {
+	MaxAmount int `json:"max_amount" binding:"required,min=1"`
+}
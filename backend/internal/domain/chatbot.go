@@ -25,6 +25,48 @@ type ChatHistoryRepository interface {
 	Delete(id uuid.UUID) error
 }
 
+// ChatToolCallStatus tracks an action the chatbot took, or proposed to take,
+// for a user
+type ChatToolCallStatus string
+
+const (
+	ChatToolCallPending  ChatToolCallStatus = "pending_confirmation"
+	ChatToolCallExecuted ChatToolCallStatus = "executed"
+	ChatToolCallFailed   ChatToolCallStatus = "failed"
+	ChatToolCallRejected ChatToolCallStatus = "rejected"
+	ChatToolCallExpired  ChatToolCallStatus = "expired"
+)
+
+// ChatToolConfirmationWindow is how long a proposed action can be confirmed
+const ChatToolConfirmationWindow = 10 * time.Minute
+
+// ChatToolCall is the audit record of one tool the chatbot invoked on behalf
+// of a user. Actions that change state wait in ChatToolCallPending until the
+// user confirms them.
+type ChatToolCall struct {
+	ID          uuid.UUID          `gorm:"type:char(36);primary_key" json:"id"`
+	UserID      uuid.UUID          `gorm:"type:char(36);not null;index" json:"user_id"`
+	Tool        string             `gorm:"type:varchar(50);not null" json:"tool"`
+	Arguments   string             `gorm:"type:text" json:"arguments"`
+	Summary     string             `gorm:"type:varchar(500)" json:"summary"`
+	Status      ChatToolCallStatus `gorm:"type:varchar(30);not null;index" json:"status"`
+	Result      string             `gorm:"type:text" json:"result,omitempty"`
+	Error       string             `gorm:"type:text" json:"error,omitempty"`
+	ConfirmedAt *time.Time         `json:"confirmed_at,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+type ChatToolCallRepository interface {
+	Create(call *ChatToolCall) error
+	FindByID(id uuid.UUID) (*ChatToolCall, error)
+	FindByUserID(userID uuid.UUID, limit int) ([]*ChatToolCall, error)
+	Update(call *ChatToolCall) error
+	// Transition moves the call from one status to another and reports
+	// whether it was still in the from status
+	Transition(id uuid.UUID, from, to ChatToolCallStatus) (bool, error)
+}
+
 // CO2Goal represents a user's CO2 reduction goal
 type CO2Goal struct {
 	ID             uuid.UUID      `gorm:"type:char(36);primary_key" json:"id"`
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DisputeStatus string
+
+// A dispute waits for the other party to respond, is then reviewed by a
+// moderator and finally resolved with an outcome that settles the escrow.
+const (
+	DisputeStatusOpen        DisputeStatus = "open"
+	DisputeStatusUnderReview DisputeStatus = "under_review"
+	DisputeStatusResolved    DisputeStatus = "resolved"
+)
+
+type DisputeReason string
+
+const (
+	DisputeReasonNotReceived    DisputeReason = "not_received"
+	DisputeReasonNotAsDescribed DisputeReason = "not_as_described"
+	DisputeReasonDamaged        DisputeReason = "damaged"
+	DisputeReasonPaymentProblem DisputeReason = "payment_problem"
+	DisputeReasonOther          DisputeReason = "other"
+)
+
+type DisputeOutcome string
+
+const (
+	DisputeOutcomeRefundBuyer   DisputeOutcome = "refund_buyer"
+	DisputeOutcomeReleaseSeller DisputeOutcome = "release_seller"
+)
+
+// SLA timers. The other party has DisputeResponseSLA to answer before the
+// dispute is escalated to moderators, who aim to resolve it within
+// DisputeResolutionSLA of it being opened.
+const (
+	DisputeResponseSLA   = 72 * time.Hour
+	DisputeResolutionSLA = 7 * 24 * time.Hour
+	MaxDisputeEvidence   = 10
+)
+
+type Dispute struct {
+	ID              uuid.UUID          `json:"id" gorm:"type:char(36);primary_key"`
+	PurchaseID      uuid.UUID          `json:"purchase_id" gorm:"type:char(36);not null;uniqueIndex"`
+	Purchase        *Purchase          `json:"purchase,omitempty" gorm:"foreignKey:PurchaseID"`
+	BuyerID         uuid.UUID          `json:"buyer_id" gorm:"type:char(36);not null;index"`
+	SellerID        uuid.UUID          `json:"seller_id" gorm:"type:char(36);not null;index"`
+	OpenedBy        uuid.UUID          `json:"opened_by" gorm:"type:char(36);not null"`
+	Reason          DisputeReason      `json:"reason" gorm:"not null"`
+	Description     string             `json:"description" gorm:"type:text"`
+	Status          DisputeStatus      `json:"status" gorm:"default:open;index"`
+	PurchaseStatus  PurchaseStatus     `json:"purchase_status"` // status the purchase was in when the dispute was opened
+	ModeratorID     *uuid.UUID         `json:"moderator_id" gorm:"type:char(36);index"`
+	Outcome         DisputeOutcome     `json:"outcome,omitempty"`
+	ResolutionNote  string             `json:"resolution_note,omitempty" gorm:"type:text"`
+	ResponseDueAt   time.Time          `json:"response_due_at"`
+	ResolutionDueAt time.Time          `json:"resolution_due_at" gorm:"index"`
+	RespondedAt     *time.Time         `json:"responded_at"`
+	EscalatedAt     *time.Time         `json:"escalated_at"`
+	ResolvedAt      *time.Time         `json:"resolved_at"`
+	Overdue         bool               `json:"overdue" gorm:"-"` // filled in for the moderator queue: the resolution SLA has passed
+	Evidence        []*DisputeEvidence `json:"evidence,omitempty" gorm:"foreignKey:DisputeID"`
+	Messages        []*DisputeMessage  `json:"messages,omitempty" gorm:"foreignKey:DisputeID"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+// IsParty reports whether userID is the buyer or seller of the disputed purchase
+func (d *Dispute) IsParty(userID uuid.UUID) bool {
+	return d.BuyerID == userID || d.SellerID == userID
+}
+
+// IsOverdue reports whether the dispute has missed its resolution SLA
+func (d *Dispute) IsOverdue(now time.Time) bool {
+	return d.Status != DisputeStatusResolved && now.After(d.ResolutionDueAt)
+}
+
+// DisputeEvidence is an image uploaded through the upload endpoints
+type DisputeEvidence struct {
+	ID         uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	DisputeID  uuid.UUID `json:"dispute_id" gorm:"type:char(36);not null;index"`
+	UploaderID uuid.UUID `json:"uploader_id" gorm:"type:char(36);not null"`
+	ImageURL   string    `json:"image_url" gorm:"not null"`
+	Caption    string    `json:"caption"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type DisputeMessage struct {
+	ID          uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	DisputeID   uuid.UUID `json:"dispute_id" gorm:"type:char(36);not null;index"`
+	SenderID    uuid.UUID `json:"sender_id" gorm:"type:char(36);not null"`
+	Sender      *User     `json:"sender,omitempty" gorm:"foreignKey:SenderID"`
+	Content     string    `json:"content" gorm:"type:text;not null"`
+	IsModerator bool      `json:"is_moderator"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type DisputeRepository interface {
+	Create(dispute *Dispute) error
+	FindByID(id uuid.UUID) (*Dispute, error)
+	// FindByIDForUpdate locks the dispute row until the surrounding transaction ends
+	FindByIDForUpdate(id uuid.UUID) (*Dispute, error)
+	FindByPurchaseID(purchaseID uuid.UUID) (*Dispute, error)
+	FindByUser(userID uuid.UUID) ([]*Dispute, error)
+	// FindQueue lists unresolved disputes (or those in status, if given), most urgent first
+	FindQueue(status DisputeStatus, page, limit int) ([]*Dispute, *PaginationResponse, error)
+	// FindAwaitingResponse lists open disputes whose response deadline passed before now
+	FindAwaitingResponse(before time.Time) ([]*Dispute, error)
+	Update(dispute *Dispute) error
+	AddEvidence(evidence *DisputeEvidence) error
+	CountEvidence(disputeID uuid.UUID) (int64, error)
+	AddMessage(message *DisputeMessage) error
+}
+
+type OpenDisputeRequest struct {
+	PurchaseID  uuid.UUID     `json:"purchase_id" binding:"required"`
+	Reason      DisputeReason `json:"reason" binding:"required,oneof=not_received not_as_described damaged payment_problem other"`
+	Description string        `json:"description" binding:"required,max=2000"`
+}
+
+type DisputeMessageRequest struct {
+	Content string `json:"content" binding:"required,max=2000"`
+}
+
+type ResolveDisputeRequest struct {
+	Outcome DisputeOutcome `json:"outcome" binding:"required,oneof=refund_buyer release_seller"`
+	Note    string         `json:"note" binding:"required,max=2000"`
+}
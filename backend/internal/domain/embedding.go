@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductEmbedding is the vector computed from a product's title and
+// description by the embedding model named in Model
+type ProductEmbedding struct {
+	ProductID   uuid.UUID `json:"product_id" gorm:"type:char(36);primary_key"`
+	Model       string    `json:"model" gorm:"type:varchar(100);not null;index"`
+	ContentHash string    `json:"content_hash" gorm:"type:char(64);not null"` // hash of the embedded text
+	Vector      []byte    `json:"-" gorm:"type:mediumblob;not null"`          // little-endian float32 values
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Values decodes the stored vector
+func (e *ProductEmbedding) Values() []float32 {
+	values := make([]float32, len(e.Vector)/4)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(e.Vector[i*4:]))
+	}
+	return values
+}
+
+// SetValues encodes values into the stored vector
+func (e *ProductEmbedding) SetValues(values []float32) {
+	e.Vector = make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(e.Vector[i*4:], math.Float32bits(v))
+	}
+}
+
+type ProductEmbeddingRepository interface {
+	// Save creates or replaces the embedding of a product
+	Save(embedding *ProductEmbedding) error
+	FindByProductID(productID uuid.UUID) (*ProductEmbedding, error)
+	FindByModel(model string) ([]*ProductEmbedding, error)
+	Delete(productID uuid.UUID) error
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// Lease is a named lock in the database that lets one replica at a time run
+// a background job. A holder keeps the lease by renewing it before it expires.
+type Lease struct {
+	Name      string    `json:"name" gorm:"primary_key;size:64"`
+	Holder    string    `json:"holder" gorm:"size:128;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+}
+
+type LeaseRepository interface {
+	// TryAcquire takes or renews the lease for holder until now+ttl. It
+	// reports false if another holder has an unexpired lease.
+	TryAcquire(name, holder string, ttl time.Duration, now time.Time) (bool, error)
+	Release(name, holder string) error
+}
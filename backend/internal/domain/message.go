@@ -14,6 +14,8 @@ type Conversation struct {
 	Messages      []Message                  `json:"messages,omitempty" gorm:"foreignKey:ConversationID"`
 	LastMessageAt time.Time                  `json:"last_message_at"`
 	CreatedAt     time.Time                  `json:"created_at"`
+	// UnreadCount is the number of messages the listing user has not read
+	UnreadCount   int64                      `json:"unread_count" gorm:"-"`
 }
 
 type ConversationParticipant struct {
@@ -32,9 +34,45 @@ type Message struct {
 	Sender         *User     `json:"sender,omitempty" gorm:"foreignKey:SenderID"`
 	Content        string    `json:"content" gorm:"not null"`
 	IsRead         bool      `json:"is_read" gorm:"default:false"`
+	Attachments    []MessageAttachment `json:"attachments,omitempty" gorm:"foreignKey:MessageID"`
 	CreatedAt      time.Time `json:"created_at"`
 }
 
+// MessageAttachment is an image or PDF sent with a message. The files are
+// private to the conversation, so clients fetch them through URL and
+// ThumbnailURL rather than from storage.
+type MessageAttachment struct {
+	ID             uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	MessageID      uuid.UUID `json:"message_id" gorm:"type:char(36);not null;index"`
+	ConversationID uuid.UUID `json:"conversation_id" gorm:"type:char(36);not null;index"`
+	Filename       string    `json:"filename" gorm:"not null"`
+	ContentType    string    `json:"content_type" gorm:"not null"`
+	Size           int64     `json:"size"`
+	Width          int       `json:"width,omitempty"`
+	Height         int       `json:"height,omitempty"`
+	StorageKey     string    `json:"-" gorm:"not null"`
+	ThumbnailKey   string    `json:"-"`
+	URL            string    `json:"url" gorm:"-"`
+	ThumbnailURL   string    `json:"thumbnail_url,omitempty" gorm:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// SetURLs points the attachment at the endpoints that serve it to
+// participants
+func (a *MessageAttachment) SetURLs() {
+	a.URL = "/v1/conversations/" + a.ConversationID.String() + "/attachments/" + a.ID.String()
+	a.ThumbnailURL = ""
+	if a.ThumbnailKey != "" {
+		a.ThumbnailURL = a.URL + "/thumbnail"
+	}
+}
+
+// AttachmentUpload is a file a participant sends
+type AttachmentUpload struct {
+	Filename string
+	Data     []byte
+}
+
 type MessageRepository interface {
 	CreateConversation(conversation *Conversation) error
 	FindConversationByID(id uuid.UUID) (*Conversation, error)
@@ -42,8 +80,17 @@ type MessageRepository interface {
 	GetUserConversations(userID uuid.UUID) ([]*Conversation, error)
 
 	CreateMessage(message *Message) error
-	GetMessages(conversationID uuid.UUID, page, limit int) ([]*Message, *PaginationResponse, error)
-	MarkAsRead(messageID, userID uuid.UUID) error
+	// GetMessages returns messages newest first, by page or, when cursor is
+	// set, the ones before it
+	GetMessages(conversationID uuid.UUID, page, limit int, cursor string) ([]*Message, *PaginationResponse, error)
+	FindMessageByID(id uuid.UUID) (*Message, error)
+	FindAttachmentByID(id uuid.UUID) (*MessageAttachment, error)
+	// MarkAsRead marks a message another participant sent as read and
+	// reports whether it was unread
+	MarkAsRead(messageID, userID uuid.UUID) (bool, error)
+	// MarkReadUpTo marks the messages others sent until upTo as read, moves
+	// the participant's LastReadAt there and returns the newly read IDs
+	MarkReadUpTo(conversationID, userID uuid.UUID, upTo time.Time) ([]uuid.UUID, error)
 }
 
 type CreateConversationRequest struct {
@@ -55,6 +102,23 @@ type SendMessageRequest struct {
 	Content string `json:"content" binding:"required,min=1"`
 }
 
+// MarkReadRequest marks the conversation read up to a message, or entirely
+// when no message is given
+type MarkReadRequest struct {
+	ReadUpTo *uuid.UUID `json:"read_up_to"`
+}
+
+// ReadReceipt tells the other participants which messages a user has read
+type ReadReceipt struct {
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	UserID         uuid.UUID   `json:"user_id"`
+	MessageID      *uuid.UUID  `json:"message_id,omitempty"`
+	MessageIDs     []uuid.UUID `json:"message_ids"`
+	ReadAt         time.Time   `json:"read_at"`
+	// LastReadAt is set when the receipt covers everything up to it
+	LastReadAt     *time.Time  `json:"last_read_at,omitempty"`
+}
+
 // WebSocket message types
 type WSMessageType string
 
@@ -67,10 +131,13 @@ const (
 	WSMessageTypeMarkRead WSMessageType = "mark_read"
 )
 
+// WSMessage is a WebSocket event. A message event carries the Message in
+// Data, attachments included with the URLs participants fetch them from.
 type WSMessage struct {
 	Type WSMessageType `json:"type"`
 	Data interface{}   `json:"data,omitempty"`
 	Token string       `json:"token,omitempty"`
 	Content string     `json:"content,omitempty"`
 	MessageID *uuid.UUID `json:"message_id,omitempty"`
+	ReadUpTo *uuid.UUID  `json:"read_up_to,omitempty"`
 }
@@ -13,6 +13,8 @@ const (
 	NotificationTypePurchase NotificationType = "purchase"
 	NotificationTypeFavorite NotificationType = "favorite"
 	NotificationTypeReview   NotificationType = "review"
+	NotificationTypeDispute  NotificationType = "dispute"
+	NotificationTypeAuction  NotificationType = "auction"
 )
 
 type Notification struct {
@@ -9,33 +9,100 @@ import (
 type OfferStatus string
 
 const (
-	OfferStatusPending  OfferStatus = "pending"
-	OfferStatusAccepted OfferStatus = "accepted"
-	OfferStatusRejected OfferStatus = "rejected"
+	OfferStatusPending   OfferStatus = "pending"
+	OfferStatusAccepted  OfferStatus = "accepted"
+	OfferStatusRejected  OfferStatus = "rejected"
 	OfferStatusCancelled OfferStatus = "cancelled"
+	OfferStatusExpired   OfferStatus = "expired"
 )
 
+// OfferAction is one step recorded in a negotiation thread
+type OfferAction string
+
+const (
+	OfferActionOffer   OfferAction = "offer"
+	OfferActionCounter OfferAction = "counter"
+	OfferActionAccept  OfferAction = "accept"
+	OfferActionReject  OfferAction = "reject"
+	OfferActionCancel  OfferAction = "cancel"
+	OfferActionExpire  OfferAction = "expire"
+)
+
+const (
+	// OfferExpiry is how long the party whose turn it is has to respond
+	OfferExpiry = 48 * time.Hour
+	// MaxOfferRounds caps the number of offers and counters in one thread
+	MaxOfferRounds = 10
+	// OfferReservationWindow is how long an accepted offer holds the product
+	// for the buyer. Checkout and payment both have to happen inside it.
+	OfferReservationWindow = 24 * time.Hour
+)
+
+// Offer is a negotiation thread between a buyer and the seller of a product.
+// OfferPrice is the price currently on the table and AwaitingUserID is the
+// party that has to accept, reject or counter it before ExpiresAt.
 type Offer struct {
-	ID            uuid.UUID   `json:"id" gorm:"type:char(36);primary_key"`
-	ProductID     uuid.UUID   `json:"product_id" gorm:"type:char(36);not null;index"`
-	Product       *Product    `json:"product,omitempty" gorm:"foreignKey:ProductID"`
-	BuyerID       uuid.UUID   `json:"buyer_id" gorm:"type:char(36);not null;index"`
-	Buyer         *User       `json:"buyer,omitempty" gorm:"foreignKey:BuyerID"`
-	OfferPrice    int         `json:"offer_price" gorm:"not null"`
-	Message       string      `json:"message"`
-	Status        OfferStatus `json:"status" gorm:"default:pending"`
-	ResponseMessage string    `json:"response_message"`
-	CreatedAt     time.Time   `json:"created_at"`
-	UpdatedAt     time.Time   `json:"updated_at"`
-	RespondedAt   *time.Time  `json:"responded_at"`
+	ID              uuid.UUID    `json:"id" gorm:"type:char(36);primary_key"`
+	ProductID       uuid.UUID    `json:"product_id" gorm:"type:char(36);not null;index"`
+	Product         *Product     `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	BuyerID         uuid.UUID    `json:"buyer_id" gorm:"type:char(36);not null;index"`
+	Buyer           *User        `json:"buyer,omitempty" gorm:"foreignKey:BuyerID"`
+	OfferPrice      int          `json:"offer_price" gorm:"not null"`
+	Message         string       `json:"message"`
+	Status          OfferStatus  `json:"status" gorm:"default:pending;index"`
+	ResponseMessage string       `json:"response_message"`
+	Round           int          `json:"round" gorm:"default:1"`
+	AwaitingUserID  *uuid.UUID   `json:"awaiting_user_id,omitempty" gorm:"type:char(36)"`
+	ExpiresAt       *time.Time   `json:"expires_at,omitempty" gorm:"index"`
+	Rounds          []OfferRound `json:"rounds,omitempty" gorm:"foreignKey:OfferID"`
+	ReservedUntil   *time.Time   `json:"reserved_until,omitempty" gorm:"index"`
+	PurchaseID      *uuid.UUID   `json:"purchase_id,omitempty" gorm:"type:char(36)"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+	RespondedAt     *time.Time   `json:"responded_at"`
+}
+
+// IsParty reports whether userID is the buyer or the seller of the offer
+func (o *Offer) IsParty(userID, sellerID uuid.UUID) bool {
+	return userID == o.BuyerID || userID == sellerID
+}
+
+// IsExpired reports whether a pending offer ran out of time at t
+func (o *Offer) IsExpired(t time.Time) bool {
+	return o.Status == OfferStatusPending && o.ExpiresAt != nil && !t.Before(*o.ExpiresAt)
+}
+
+// HoldsReservation reports whether an accepted offer still reserves the
+// product for the buyer at t
+func (o *Offer) HoldsReservation(t time.Time) bool {
+	return o.Status == OfferStatusAccepted && o.ReservedUntil != nil && t.Before(*o.ReservedUntil)
+}
+
+// OfferRound is one entry in the history of an offer thread
+type OfferRound struct {
+	ID        uuid.UUID   `json:"id" gorm:"type:char(36);primary_key"`
+	OfferID   uuid.UUID   `json:"offer_id" gorm:"type:char(36);not null;index"`
+	Round     int         `json:"round"`
+	ActorID   uuid.UUID   `json:"actor_id" gorm:"type:char(36);not null"`
+	Action    OfferAction `json:"action" gorm:"type:varchar(20);not null"`
+	Price     int         `json:"price"`
+	Message   string      `json:"message"`
+	CreatedAt time.Time   `json:"created_at"`
 }
 
 type OfferRepository interface {
 	Create(offer *Offer) error
 	FindByID(id uuid.UUID) (*Offer, error)
+	FindByIDForUpdate(id uuid.UUID) (*Offer, error)
 	FindByProductID(productID uuid.UUID) ([]*Offer, error)
 	FindByBuyerID(buyerID uuid.UUID) ([]*Offer, error)
 	FindBySellerID(sellerID uuid.UUID) ([]*Offer, error)
+	FindPendingByProductID(productID uuid.UUID) ([]*Offer, error)
+	FindExpired(now time.Time, limit int) ([]*Offer, error)
+	FindLapsedReservations(now time.Time, limit int) ([]*Offer, error)
+	// FindAcceptedPriceRatios returns agreed price / listing price for recently accepted offers in a category
+	FindAcceptedPriceRatios(category string, limit int) ([]float64, error)
+	AddRound(round *OfferRound) error
 	Update(offer *Offer) error
 }
 
@@ -49,3 +116,13 @@ type RespondOfferRequest struct {
 	Accept  bool   `json:"accept" binding:"required"`
 	Message string `json:"message"`
 }
+
+type CheckoutOfferRequest struct {
+	ShippingAddress string `json:"shipping_address" binding:"required"`
+	PaymentMethod   string `json:"payment_method" binding:"required,oneof=credit_card bank_transfer cash_on_delivery"`
+}
+
+type CounterOfferRequest struct {
+	Price   int    `json:"price" binding:"required,min=1"`
+	Message string `json:"message"`
+}
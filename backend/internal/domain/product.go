@@ -1,6 +1,9 @@
 package domain
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -64,28 +67,165 @@ type ProductImage struct {
 type ProductRepository interface {
 	Create(product *Product) error
 	FindByID(id uuid.UUID) (*Product, error)
+	// FindByIDForUpdate locks the product row until the surrounding transaction ends
+	FindByIDForUpdate(id uuid.UUID) (*Product, error)
+	// FindByIDs returns the products that exist among ids, in no particular order
+	FindByIDs(ids []uuid.UUID) ([]*Product, error)
 	List(filters *ProductFilters) ([]*Product, *PaginationResponse, error)
 	Update(product *Product) error
 	Delete(id uuid.UUID) error
 	IncrementViewCount(id uuid.UUID) error
+	// FindRecentSoldPrices returns what buyers actually paid for recent sales in a category
+	FindRecentSoldPrices(category string, limit int) ([]int, error)
+	// CountFacets counts the active products matching the search text (or
+	// IDs) in one grouped query. Facet filters are not applied; each row says whether it
+	// passes the price and seller rating filters so every facet can ignore its
+	// own filter.
+	CountFacets(filters *ProductFilters) ([]*ProductFacetCount, error)
 }
 
 type ProductFilters struct {
-	Category  string
-	MinPrice  int
-	MaxPrice  int
-	Condition ProductCondition
-	Search    string
-	Sort      string // price_asc, price_desc, created_desc, eco_impact_desc
-	Page      int
-	Limit     int
+	Category        string
+	Categories      []string // any of these, together with Category
+	MinPrice        int
+	MaxPrice        int
+	Condition       ProductCondition
+	Conditions      []ProductCondition // any of these, together with Condition
+	MinSellerRating float64
+	Search          string
+	Sort            string // price_asc, price_desc, created_desc, eco_impact_desc
+	Page            int
+	Limit           int
+	// Cursor continues after the page that returned it as NextCursor. It is
+	// used instead of Page and skips counting the total.
+	Cursor string
+	// IDs, when not nil, limits the products to these; the search index
+	// answers Search this way. Without a Sort they keep this order.
+	IDs []uuid.UUID
+	// Scores, when set, are the relevance of each of IDs. IDs are ranked by
+	// score and then by ID, so a cursor can hold the last score instead of a
+	// position that shifts when the ranking changes.
+	Scores []float64
+}
+
+// CategoryValues returns every selected category
+func (f *ProductFilters) CategoryValues() []string {
+	values := make([]string, 0, len(f.Categories)+1)
+	if f.Category != "" {
+		values = append(values, f.Category)
+	}
+	for _, c := range f.Categories {
+		if c != "" && c != f.Category {
+			values = append(values, c)
+		}
+	}
+	return values
+}
+
+// ConditionValues returns every selected condition
+func (f *ProductFilters) ConditionValues() []ProductCondition {
+	values := make([]ProductCondition, 0, len(f.Conditions)+1)
+	if f.Condition != "" {
+		values = append(values, f.Condition)
+	}
+	for _, c := range f.Conditions {
+		if c != "" && c != f.Condition {
+			values = append(values, c)
+		}
+	}
+	return values
+}
+
+// FacetRange is one bucket of a range facet; Max is zero for the last,
+// open-ended bucket
+type FacetRange struct {
+	Label string
+	Min   float64
+	Max   float64
+}
+
+var (
+	PriceFacetRanges = []FacetRange{
+		{Label: "0-999", Min: 0, Max: 1000},
+		{Label: "1000-2999", Min: 1000, Max: 3000},
+		{Label: "3000-4999", Min: 3000, Max: 5000},
+		{Label: "5000-9999", Min: 5000, Max: 10000},
+		{Label: "10000-29999", Min: 10000, Max: 30000},
+		{Label: "30000+", Min: 30000},
+	}
+	CO2FacetRanges = []FacetRange{
+		{Label: "0-1kg", Min: 0, Max: 1},
+		{Label: "1-5kg", Min: 1, Max: 5},
+		{Label: "5-20kg", Min: 5, Max: 20},
+		{Label: "20-50kg", Min: 20, Max: 50},
+		{Label: "50kg+", Min: 50},
+	}
+	// SellerRatingFacetMins are the "N stars and up" choices
+	SellerRatingFacetMins = []int{4, 3, 2, 1}
+)
+
+// ProductFacetCount is one group of the facet query
+type ProductFacetCount struct {
+	Category    string
+	Condition   ProductCondition
+	PriceBucket int // index into PriceFacetRanges
+	CO2Bucket   int // index into CO2FacetRanges
+	RatingFloor int // seller's average rating rounded down, 0 when unrated
+	PriceOK     bool
+	RatingOK    bool
+	Count       int
+}
+
+type FacetBucket struct {
+	Value    string  `json:"value"`
+	Min      float64 `json:"min,omitempty"`
+	Max      float64 `json:"max,omitempty"`
+	Count    int     `json:"count"`
+	Selected bool    `json:"selected"`
+}
+
+// ProductFacets counts products per filter choice. The counts of a facet
+// apply every other selected filter but not its own, so choices can be
+// combined.
+type ProductFacets struct {
+	Categories   []FacetBucket `json:"categories"`
+	Conditions   []FacetBucket `json:"conditions"`
+	PriceRanges  []FacetBucket `json:"price_ranges"`
+	CO2Impact    []FacetBucket `json:"co2_impact"`
+	SellerRating []FacetBucket `json:"seller_rating"`
 }
 
 type PaginationResponse struct {
-	Page       int `json:"page"`
-	Limit      int `json:"limit"`
-	Total      int `json:"total"`
-	TotalPages int `json:"total_pages"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	Total      int    `json:"total"`
+	TotalPages int    `json:"total_pages"`
+	NextCursor string `json:"next_cursor,omitempty"` // empty on the last page
+}
+
+// PageCursor is the sort key and ID of the last item of a page. Clients get
+// it encoded and pass it back as is.
+type PageCursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+func (c *PageCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodePageCursor(encoded string) (*PageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cursor PageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cursor, nil
 }
 
 type CreateProductRequest struct {
@@ -8,10 +8,75 @@ import (
 
 type PurchaseStatus string
 
+// A purchase moves through an escrow lifecycle: the buyer's payment is held
+// until they confirm receipt, and only then released to the seller. Holding,
+// releasing and refunding record that the payment provider is being called,
+// so a crash mid-call leaves the purchase where the call can be retried.
 const (
-	PurchaseStatusPending   PurchaseStatus = "pending"
-	PurchaseStatusCompleted PurchaseStatus = "completed"
-	PurchaseStatusCancelled PurchaseStatus = "cancelled"
+	PurchaseStatusAwaitingPayment PurchaseStatus = "awaiting_payment"
+	PurchaseStatusHolding         PurchaseStatus = "holding"
+	PurchaseStatusPaid            PurchaseStatus = "paid" // funds held in escrow
+	PurchaseStatusShipped         PurchaseStatus = "shipped"
+	PurchaseStatusDelivered       PurchaseStatus = "delivered"
+	PurchaseStatusBuyerConfirmed  PurchaseStatus = "buyer_confirmed"
+	PurchaseStatusReleasing       PurchaseStatus = "releasing"
+	PurchaseStatusFundsReleased   PurchaseStatus = "funds_released"
+	PurchaseStatusCancelled       PurchaseStatus = "cancelled"
+	PurchaseStatusRefunding       PurchaseStatus = "refunding"
+	PurchaseStatusRefunded        PurchaseStatus = "refunded"
+	PurchaseStatusDisputed        PurchaseStatus = "disputed"
+)
+
+var purchaseTransitions = map[PurchaseStatus][]PurchaseStatus{
+	PurchaseStatusAwaitingPayment: {PurchaseStatusHolding, PurchaseStatusCancelled},
+	PurchaseStatusHolding:         {PurchaseStatusPaid, PurchaseStatusAwaitingPayment},
+	PurchaseStatusPaid:            {PurchaseStatusShipped, PurchaseStatusRefunding, PurchaseStatusDisputed},
+	PurchaseStatusShipped:         {PurchaseStatusDelivered, PurchaseStatusDisputed},
+	PurchaseStatusDelivered:       {PurchaseStatusBuyerConfirmed, PurchaseStatusDisputed},
+	PurchaseStatusBuyerConfirmed:  {PurchaseStatusReleasing},
+	PurchaseStatusReleasing:       {PurchaseStatusFundsReleased},
+	PurchaseStatusRefunding:       {PurchaseStatusRefunded},
+	PurchaseStatusDisputed:        {PurchaseStatusRefunding, PurchaseStatusReleasing},
+}
+
+// CanTransitionTo reports whether the lifecycle allows moving from s to next
+func (s PurchaseStatus) CanTransitionTo(next PurchaseStatus) bool {
+	for _, allowed := range purchaseTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal reports whether no further transitions are possible
+func (s PurchaseStatus) IsFinal() bool {
+	return len(purchaseTransitions[s]) == 0
+}
+
+// Cancellation rules. Either party may cancel any time before shipment, but
+// a seller pays a penalty unless the buyer never paid in time.
+const (
+	PaymentDeadline         = 72 * time.Hour
+	SellerCancelPenaltyRate = 0.10
+	MinSellerCancelPenalty  = 100
+)
+
+type CancelReason string
+
+const (
+	CancelReasonChangedMind        CancelReason = "changed_mind"
+	CancelReasonPaymentNotReceived CancelReason = "payment_not_received"
+	CancelReasonOutOfStock         CancelReason = "out_of_stock"
+	CancelReasonItemDamaged        CancelReason = "item_damaged"
+	CancelReasonShippingDelay      CancelReason = "shipping_delay"
+	CancelReasonOther              CancelReason = "other"
+)
+
+const (
+	PaymentMethodCreditCard     = "credit_card"
+	PaymentMethodBankTransfer   = "bank_transfer"
+	PaymentMethodCashOnDelivery = "cash_on_delivery"
 )
 
 type Purchase struct {
@@ -24,22 +89,55 @@ type Purchase struct {
 	Seller          *User          `json:"seller,omitempty" gorm:"foreignKey:SellerID"`
 	Price           int            `json:"price" gorm:"not null"`
 	CO2SavedKg      float64        `json:"co2_saved_kg" gorm:"type:decimal(10,2);not null"`
-	Status          PurchaseStatus `json:"status" gorm:"default:pending"`
+	Status          PurchaseStatus `json:"status" gorm:"default:awaiting_payment;index"`
 	PaymentMethod   string         `json:"payment_method"`
+	PaymentRef      string         `json:"-"` // escrow reference returned by the PaymentProvider
+	ListingStatus   ProductStatus  `json:"-"` // product status before the sale, restored if it is unwound
 	ShippingAddress string         `json:"shipping_address"`
-	CompletedAt     *time.Time     `json:"completed_at"`
+	PaidAt          *time.Time     `json:"paid_at"`
+	ShippedAt       *time.Time     `json:"shipped_at"`
+	DeliveredAt     *time.Time     `json:"delivered_at"`
+	ConfirmedAt     *time.Time     `json:"confirmed_at"`
+	CompletedAt     *time.Time     `json:"completed_at"` // funds released to the seller
+	CancelledAt     *time.Time     `json:"cancelled_at"`
+	CancelledBy     *uuid.UUID     `json:"cancelled_by" gorm:"type:char(36)"`
+	CancelReason    CancelReason   `json:"cancel_reason,omitempty"`
+	CancelNote      string         `json:"cancel_note,omitempty"`
+	SellerPenalty   int            `json:"seller_penalty"`   // withheld from the seller's next payouts
+	PayoutDeduction int            `json:"payout_deduction"` // earlier penalties withheld from this payout
+	RefundedAt      *time.Time     `json:"refunded_at"`
 	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
 }
 
 type PurchaseRepository interface {
 	Create(purchase *Purchase) error
 	FindByID(id uuid.UUID) (*Purchase, error)
+	// FindByIDForUpdate locks the purchase row until the surrounding transaction ends
+	FindByIDForUpdate(id uuid.UUID) (*Purchase, error)
 	FindByUser(userID uuid.UUID, role string, page, limit int) ([]*Purchase, *PaginationResponse, error)
 	UpdateStatus(id uuid.UUID, status PurchaseStatus) error
+	Update(purchase *Purchase) error
+}
+
+// PaymentProvider moves money for a purchase. Funds are captured into escrow
+// by Hold and only reach the seller when Release is called. Hold is
+// idempotent per purchase ID and Release per ref, so either may be retried
+// after a failure without moving the money twice. Release keeps withheld back
+// from the seller's share for the platform.
+type PaymentProvider interface {
+	Hold(purchaseID uuid.UUID, amount int, method string) (ref string, err error)
+	Release(ref string, withheld int) error
+	Refund(ref string) error
 }
 
 type CreatePurchaseRequest struct {
 	ProductID       uuid.UUID `json:"product_id" binding:"required"`
 	ShippingAddress string    `json:"shipping_address" binding:"required"`
-	PaymentMethod   string    `json:"payment_method" binding:"required"`
+	PaymentMethod   string    `json:"payment_method" binding:"required,oneof=credit_card bank_transfer cash_on_delivery"`
+}
+
+type CancelPurchaseRequest struct {
+	Reason CancelReason `json:"reason" binding:"required,oneof=changed_mind payment_not_received out_of_stock item_damaged shipping_delay other"`
+	Note   string       `json:"note" binding:"max=500"`
 }
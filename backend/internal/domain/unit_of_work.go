@@ -0,0 +1,21 @@
+package domain
+
+// Repositories groups the repositories that can take part in a single
+// unit of work. Every repository in the set shares the same transaction.
+type Repositories struct {
+	Products       ProductRepository
+	Purchases      PurchaseRepository
+	Users          UserRepository
+	Sustainability SustainabilityRepository
+	Disputes       DisputeRepository
+	Auctions       AuctionRepository
+	Bids           BidRepository
+	ProxyBids      ProxyBidRepository
+	Offers         OfferRepository
+}
+
+// UnitOfWork runs fn inside one database transaction. If fn returns an
+// error, every write made through repos is rolled back.
+type UnitOfWork interface {
+	Do(fn func(repos *Repositories) error) error
+}
@@ -26,6 +26,7 @@ type User struct {
 	SustainabilityScore int       `json:"sustainability_score" gorm:"default:0"`
 	TotalCO2SavedKg    float64    `json:"total_co2_saved_kg" gorm:"type:decimal(10,2);default:0.00"`
 	Level              int        `json:"level" gorm:"default:1"`
+	PenaltyDue         int        `json:"-" gorm:"default:0"` // cancellation penalties not yet withheld from a payout
 	CreatedAt          time.Time  `json:"created_at"`
 	UpdatedAt          time.Time  `json:"updated_at"`
 	DeletedAt          *time.Time `json:"-" gorm:"index"`
@@ -50,6 +51,9 @@ type UserRepository interface {
 	FindByUsername(username string) (*User, error)
 	Update(user *User) error
 	UpdateSustainabilityStats(userID uuid.UUID, co2SavedKg float64) error
+	AddPenaltyDue(userID uuid.UUID, amount int) error
+	// TakePenaltyDue settles up to max of the user's penalties due and returns the amount taken
+	TakePenaltyDue(userID uuid.UUID, max int) (int, error)
 	GetLeaderboard(limit int, period string) ([]*LeaderboardEntry, error)
 }
 
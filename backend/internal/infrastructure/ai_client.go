@@ -2,6 +2,7 @@ package infrastructure
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
@@ -11,12 +12,20 @@ import (
 )
 
 type AIClient struct {
-	client        pb.ProductAnalysisServiceClient
-	conn          *grpc.ClientConn
-	geminiClient  *GeminiClient
+	client pb.ProductAnalysisServiceClient
+	conn   *grpc.ClientConn
+	llm    LLMProvider
 }
 
-func NewAIClient(serverURL string) (*AIClient, error) {
+// NewTextAIClient builds a client that only does text generation, without the
+// product analysis service
+func NewTextAIClient(llm LLMProvider) *AIClient {
+	return &AIClient{llm: llm}
+}
+
+// NewAIClient connects to the product analysis service. llm may be nil, in
+// which case text generation falls back to canned responses.
+func NewAIClient(serverURL string, llm LLMProvider) (*AIClient, error) {
 	conn, err := grpc.Dial(serverURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to AI service: %w", err)
@@ -25,16 +34,10 @@ func NewAIClient(serverURL string) (*AIClient, error) {
 	client := pb.NewProductAnalysisServiceClient(conn)
 	log.Printf("Connected to AI service at %s", serverURL)
 
-	// Initialize Gemini client
-	geminiClient := NewGeminiClient()
-	if geminiClient != nil {
-		log.Printf("Gemini API client initialized")
-	}
-
 	return &AIClient{
-		client:       client,
-		conn:         conn,
-		geminiClient: geminiClient,
+		client: client,
+		conn:   conn,
+		llm:    llm,
 	}, nil
 }
 
@@ -65,6 +68,10 @@ type ProductAnalysisResponse struct {
 }
 
 func (c *AIClient) AnalyzeProduct(ctx context.Context, req *ProductAnalysisRequest) (*ProductAnalysisResponse, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("AI service not connected")
+	}
+
 	resp, err := c.client.AnalyzeProduct(ctx, &pb.AnalyzeProductRequest{
 		Images:                 req.Images,
 		Title:                  req.Title,
@@ -102,6 +109,10 @@ type CO2CalculationResponse struct {
 }
 
 func (c *AIClient) CalculateCO2Impact(ctx context.Context, req *CO2CalculationRequest) (*CO2CalculationResponse, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("AI service not connected")
+	}
+
 	resp, err := c.client.CalculateCO2Impact(ctx, &pb.CalculateCO2Request{
 		Category:            req.Category,
 		WeightKg:            float32(req.WeightKg),
@@ -138,18 +149,32 @@ func (c *AIClient) AnswerProductQuestion(ctx context.Context, productTitle, prod
 // GenerateText generates text based on a prompt using AI
 // This is a general-purpose AI text generation method
 func (c *AIClient) GenerateText(prompt string) (string, error) {
-	if c == nil || c.client == nil {
-		return "", fmt.Errorf("AI client not initialized")
-	}
+	return c.generate(Prompt(prompt))
+}
 
-	// In production, this would call Gemini API for text generation
-	// For now, return a structured response based on the prompt
-	// The caller should handle parsing the response
+// GenerateJSON is GenerateText for prompts that ask for a JSON object
+func (c *AIClient) GenerateJSON(prompt string) (string, error) {
+	req := Prompt(prompt)
+	req.JSON = true
+	return c.generate(req)
+}
 
-	// Simple response that indicates AI processing
-	response := fmt.Sprintf("AI Analysis Complete: %s", prompt[:min(100, len(prompt))])
+func (c *AIClient) generate(req *LLMRequest) (string, error) {
+	return c.Complete(context.Background(), req)
+}
 
-	return response, nil
+// Complete sends a full request, system prompt and conversation included, to
+// the LLM provider
+func (c *AIClient) Complete(ctx context.Context, req *LLMRequest) (string, error) {
+	if c == nil || c.llm == nil {
+		return "", fmt.Errorf("text generation not available")
+	}
+
+	resp, err := c.llm.Generate(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
 }
 
 // ChatMessage represents a message in a conversation
@@ -166,16 +191,21 @@ type ChatRequest struct {
 
 // Chat generates a response based on conversation history
 func (c *AIClient) Chat(ctx context.Context, req *ChatRequest) (string, error) {
-	if c == nil || c.client == nil {
-		// Fallback response when AI service is not available
-		return "こんにちは！EcoMateのアシスタントです。商品探しをお手伝いします。どのような商品をお探しですか？", nil
-	}
+	return c.ChatStream(ctx, req, nil)
+}
 
-	// Build conversation context
-	conversationContext := "あなたはEcoMateのAIアシスタントです。ユーザーの質問に日本語で答え、適切な商品を提案してください。\n\n"
+// ChatStream is Chat with the reply passed to onDelta piece by piece as the
+// model produces it. The keyword fallback is delivered as a single piece. A
+// nil onDelta just collects the reply.
+func (c *AIClient) ChatStream(ctx context.Context, req *ChatRequest, onDelta func(string) error) (string, error) {
+	if onDelta == nil {
+		onDelta = func(string) error { return nil }
+	}
 
-	if req.Context != "" {
-		conversationContext += "利用可能な商品情報:\n" + req.Context + "\n\n"
+	if c == nil {
+		// Fallback response when AI service is not available
+		response := "こんにちは！EcoMateのアシスタントです。商品探しをお手伝いします。どのような商品をお探しですか？"
+		return response, onDelta(response)
 	}
 
 	// Get the last user message
@@ -184,60 +214,36 @@ func (c *AIClient) Chat(ctx context.Context, req *ChatRequest) (string, error) {
 		lastMessage = req.Messages[len(req.Messages)-1].Content
 	}
 
-	// Try Gemini API first
-	if c.geminiClient != nil {
-		// Build system prompt
-		systemPrompt := `あなたはEcoMateのAIアシスタントです。
-EcoMateは環境に優しい中古品フリーマーケットアプリです。
-
-以下のことを心がけて応答してください：
-- ユーザーの質問に日本語で丁寧に答える
-- 商品探しや購入のサポートをする
-- CO2削減や環境保護の価値を伝える
-- EcoMateの機能（3D表示、AR試着、価格予測など）を紹介する
-- 具体的で実用的なアドバイスを提供する
-
-EcoMateの主な機能：
-- 商品検索・フィルタリング（カテゴリー、価格、状態など）
-- 3Dモデル表示とAR試着機能
-- AI価格予測と交渉サポート
-- CO2削減量の可視化
-- エコポイント・レベルシステム
-- リアルタイムメッセージング
-
-`
-
-		if req.Context != "" {
-			systemPrompt += req.Context + "\n\n"
-			systemPrompt += "重要: ユーザーが商品を探している場合、上記の商品リストから適切な商品を推薦してください。商品を推薦する際は、必ず上記のフォーマット [PRODUCT:商品ID:商品名:商品画像URL] を使用してください。\n\n"
+	if c.llm != nil {
+		system, err := RenderPrompt(PromptChatSystem, req.Context)
+		if err != nil {
+			return "", err
 		}
 
-		// Build conversation history
-		var conversationText string
-		for _, msg := range req.Messages {
-			role := "ユーザー"
-			if msg.Role == "assistant" {
-				role = "アシスタント"
-			}
-			conversationText += fmt.Sprintf("%s: %s\n", role, msg.Content)
+		delivered := false
+		resp, err := StreamLLM(ctx, c.llm, &LLMRequest{System: system, Messages: req.Messages}, func(delta string) error {
+			delivered = true
+			return onDelta(delta)
+		})
+		if err == nil && resp.Text != "" {
+			return resp.Text, nil
 		}
-
-		// Build final prompt
-		prompt := systemPrompt + "\n会話履歴:\n" + conversationText + "\n\n上記の会話履歴に基づいて、ユーザーの最新の質問に日本語で答えてください。"
-
-		// Call Gemini API
-		response, err := c.geminiClient.GenerateContent(ctx, prompt)
-		if err == nil && response != "" {
-			return response, nil
+		// Once part of the reply is out, or the caller went away, there is
+		// nothing sensible to fall back to
+		if delivered || ctx.Err() != nil {
+			if err == nil {
+				err = errors.New("empty response from LLM")
+			}
+			return "", err
 		}
 
-		log.Printf("Gemini API error, falling back to keyword matching: %v", err)
+		log.Printf("LLM error, falling back to keyword matching: %v", err)
 	}
 
 	// Fallback to keyword-based responses
 	response := c.generateChatResponse(lastMessage, req.Context)
 
-	return response, nil
+	return response, onDelta(response)
 }
 
 func (c *AIClient) generateChatResponse(userMessage, context string) string {
@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/yourusername/ecomate/backend/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type auctionRepository struct {
@@ -37,15 +38,51 @@ func (r *auctionRepository) FindByProductID(productID uuid.UUID) (*domain.Auctio
 
 func (r *auctionRepository) FindActiveAuctions() ([]*domain.Auction, error) {
 	var auctions []*domain.Auction
+	now := time.Now()
 	err := r.db.Preload("Product").Preload("Product.Images").
-		Where("status = ? AND end_time > ?", domain.AuctionStatusActive, time.Now()).
+		Where("status = ? AND start_time <= ? AND end_time > ?", domain.AuctionStatusActive, now, now).
 		Order("end_time ASC").
 		Find(&auctions).Error
 	return auctions, err
 }
 
+func (r *auctionRepository) FindUpcoming(now, until time.Time) ([]*domain.Auction, error) {
+	var auctions []*domain.Auction
+	err := r.db.Preload("Product").Preload("Product.Images").
+		Where("status = ? AND start_time > ? AND start_time <= ?", domain.AuctionStatusActive, now, until).
+		Order("start_time ASC").
+		Find(&auctions).Error
+	return auctions, err
+}
+
+func (r *auctionRepository) FindExpired(now time.Time, limit int) ([]*domain.Auction, error) {
+	var auctions []*domain.Auction
+	err := r.db.
+		Where("status = ? AND end_time <= ?", domain.AuctionStatusActive, now).
+		Order("end_time ASC").
+		Limit(limit).
+		Find(&auctions).Error
+	return auctions, err
+}
+
+func (r *auctionRepository) FindByIDForUpdate(id uuid.UUID) (*domain.Auction, error) {
+	var auction domain.Auction
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&auction).Error; err != nil {
+		return nil, err
+	}
+	return &auction, nil
+}
+
+func (r *auctionRepository) RaiseBid(id uuid.UUID, amount int, now time.Time) (bool, error) {
+	result := r.db.Model(&domain.Auction{}).
+		Where("id = ? AND status = ? AND end_time > ? AND current_bid + min_bid_increment <= ?",
+			id, domain.AuctionStatusActive, now, amount).
+		Update("current_bid", amount)
+	return result.RowsAffected > 0, result.Error
+}
+
 func (r *auctionRepository) Update(auction *domain.Auction) error {
-	return r.db.Save(auction).Error
+	return r.db.Omit(clause.Associations).Save(auction).Error
 }
 
 type bidRepository struct {
@@ -92,3 +129,26 @@ func (r *bidRepository) UpdateWinningStatus(auctionID, bidID uuid.UUID) error {
 			Update("is_winning", true).Error
 	})
 }
+
+type proxyBidRepository struct {
+	db *gorm.DB
+}
+
+func NewProxyBidRepository(db *gorm.DB) domain.ProxyBidRepository {
+	return &proxyBidRepository{db: db}
+}
+
+func (r *proxyBidRepository) Upsert(proxy *domain.ProxyBid) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "auction_id"}, {Name: "bidder_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_amount", "registered_at", "updated_at"}),
+	}).Create(proxy).Error
+}
+
+func (r *proxyBidRepository) FindByAuctionID(auctionID uuid.UUID) ([]*domain.ProxyBid, error) {
+	var proxies []*domain.ProxyBid
+	err := r.db.Where("auction_id = ?", auctionID).
+		Order("registered_at ASC").
+		Find(&proxies).Error
+	return proxies, err
+}
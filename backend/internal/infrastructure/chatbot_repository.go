@@ -31,6 +31,46 @@ func (r *ChatHistoryRepository) Delete(id uuid.UUID) error {
 	return r.db.Delete(&domain.ChatHistory{}, "id = ?", id).Error
 }
 
+type ChatToolCallRepository struct {
+	db *gorm.DB
+}
+
+func NewChatToolCallRepository(db *gorm.DB) *ChatToolCallRepository {
+	return &ChatToolCallRepository{db: db}
+}
+
+func (r *ChatToolCallRepository) Create(call *domain.ChatToolCall) error {
+	return r.db.Create(call).Error
+}
+
+func (r *ChatToolCallRepository) FindByID(id uuid.UUID) (*domain.ChatToolCall, error) {
+	var call domain.ChatToolCall
+	if err := r.db.Where("id = ?", id).First(&call).Error; err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
+
+func (r *ChatToolCallRepository) FindByUserID(userID uuid.UUID, limit int) ([]*domain.ChatToolCall, error) {
+	var calls []*domain.ChatToolCall
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&calls).Error
+	return calls, err
+}
+
+func (r *ChatToolCallRepository) Update(call *domain.ChatToolCall) error {
+	return r.db.Save(call).Error
+}
+
+func (r *ChatToolCallRepository) Transition(id uuid.UUID, from, to domain.ChatToolCallStatus) (bool, error) {
+	result := r.db.Model(&domain.ChatToolCall{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	return result.RowsAffected == 1, result.Error
+}
+
 type CO2GoalRepository struct {
 	db *gorm.DB
 }
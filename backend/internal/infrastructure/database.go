@@ -74,9 +74,13 @@ func AutoMigrate(db *gorm.DB) error {
 		&domain.Product{},
 		&domain.ProductImage{},
 		&domain.Purchase{},
+		&domain.Dispute{},
+		&domain.DisputeEvidence{},
+		&domain.DisputeMessage{},
 		&domain.Conversation{},
 		&domain.ConversationParticipant{},
 		&domain.Message{},
+		&domain.MessageAttachment{},
 		&domain.Achievement{},
 		&domain.UserAchievement{},
 		&domain.SustainabilityLog{},
@@ -84,12 +88,16 @@ func AutoMigrate(db *gorm.DB) error {
 		&domain.Notification{},
 		&domain.Review{},
 		&domain.Offer{},
+		&domain.OfferRound{},
 		&domain.UserEvent{},
 		&domain.Auction{},
 		&domain.Bid{},
+		&domain.ProxyBid{},
 		&domain.BlockchainTransaction{},
 		&domain.NFTOwnership{},
 		&domain.ChatHistory{},
+		&domain.ChatToolCall{},
+		&domain.ProductEmbedding{},
 		&domain.CO2Goal{},
 		&domain.ShippingTracking{},
 		&domain.Follow{},
@@ -97,9 +105,27 @@ func AutoMigrate(db *gorm.DB) error {
 		&domain.UserFeed{},
 		&domain.LiveStream{},
 		&domain.StreamComment{},
+		&domain.Lease{},
 	)
 }
 
+// MigratePurchaseStatuses maps statuses from before the escrow lifecycle onto it
+func MigratePurchaseStatuses(db *gorm.DB) error {
+	legacy := map[string]domain.PurchaseStatus{
+		"pending":   domain.PurchaseStatusAwaitingPayment,
+		"completed": domain.PurchaseStatusFundsReleased,
+	}
+
+	for from, to := range legacy {
+		if err := db.Model(&domain.Purchase{}).
+			Where("status = ?", from).
+			Update("status", to).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func SeedAchievements(db *gorm.DB) error {
 	achievements := []domain.Achievement{
 		{
@@ -0,0 +1,133 @@
+package infrastructure
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type disputeRepository struct {
+	db *gorm.DB
+}
+
+func NewDisputeRepository(db *gorm.DB) domain.DisputeRepository {
+	return &disputeRepository{db: db}
+}
+
+func (r *disputeRepository) Create(dispute *domain.Dispute) error {
+	return r.db.Create(dispute).Error
+}
+
+func (r *disputeRepository) FindByID(id uuid.UUID) (*domain.Dispute, error) {
+	var dispute domain.Dispute
+	if err := r.db.
+		Preload("Purchase").
+		Preload("Purchase.Product").
+		Preload("Evidence", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Messages", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Messages.Sender").
+		Where("id = ?", id).
+		First(&dispute).Error; err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+func (r *disputeRepository) FindByIDForUpdate(id uuid.UUID) (*domain.Dispute, error) {
+	var dispute domain.Dispute
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&dispute).Error; err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+func (r *disputeRepository) FindByPurchaseID(purchaseID uuid.UUID) (*domain.Dispute, error) {
+	var dispute domain.Dispute
+	if err := r.db.Where("purchase_id = ?", purchaseID).First(&dispute).Error; err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+func (r *disputeRepository) FindByUser(userID uuid.UUID) ([]*domain.Dispute, error) {
+	var disputes []*domain.Dispute
+	err := r.db.Preload("Purchase").Preload("Purchase.Product").
+		Where("buyer_id = ? OR seller_id = ?", userID, userID).
+		Order("created_at DESC").
+		Find(&disputes).Error
+	return disputes, err
+}
+
+func (r *disputeRepository) FindQueue(status domain.DisputeStatus, page, limit int) ([]*domain.Dispute, *domain.PaginationResponse, error) {
+	var disputes []*domain.Dispute
+	var total int64
+
+	query := r.db.Model(&domain.Dispute{}).Preload("Purchase").Preload("Purchase.Product")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status <> ?", domain.DisputeStatusResolved)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+	if err := query.Order("resolution_due_at ASC").Offset(offset).Limit(limit).Find(&disputes).Error; err != nil {
+		return nil, nil, err
+	}
+
+	totalPages := int(total) / limit
+	if int(total)%limit > 0 {
+		totalPages++
+	}
+
+	pagination := &domain.PaginationResponse{
+		Page:       page,
+		Limit:      limit,
+		Total:      int(total),
+		TotalPages: totalPages,
+	}
+
+	return disputes, pagination, nil
+}
+
+func (r *disputeRepository) FindAwaitingResponse(before time.Time) ([]*domain.Dispute, error) {
+	var disputes []*domain.Dispute
+	err := r.db.Where("status = ? AND response_due_at < ?", domain.DisputeStatusOpen, before).
+		Find(&disputes).Error
+	return disputes, err
+}
+
+func (r *disputeRepository) Update(dispute *domain.Dispute) error {
+	return r.db.Omit(clause.Associations).Save(dispute).Error
+}
+
+func (r *disputeRepository) AddEvidence(evidence *domain.DisputeEvidence) error {
+	return r.db.Create(evidence).Error
+}
+
+func (r *disputeRepository) CountEvidence(disputeID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.DisputeEvidence{}).Where("dispute_id = ?", disputeID).Count(&count).Error
+	return count, err
+}
+
+func (r *disputeRepository) AddMessage(message *domain.DisputeMessage) error {
+	return r.db.Create(message).Error
+}
@@ -0,0 +1,223 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/yourusername/ecomate/backend/internal/config"
+)
+
+const (
+	defaultEmbeddingDimensions = 256
+	defaultOpenAIEmbedModel    = "text-embedding-3-small"
+)
+
+// Embedder turns texts into vectors whose cosine similarity reflects how
+// alike the texts are. Returned vectors are normalized to unit length.
+type Embedder interface {
+	// Name identifies the model; vectors from different models do not mix
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// NewEmbedder builds the embedder selected in cfg
+func NewEmbedder(cfg *config.AIConfig) (Embedder, error) {
+	switch cfg.EmbeddingProvider {
+	case "", "local":
+		return NewHashingEmbedder(cfg.EmbeddingDimensions), nil
+	case "openai":
+		return NewOpenAIEmbedder(cfg.EmbeddingAPIKey, cfg.EmbeddingBaseURL, cfg.EmbeddingModel), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.EmbeddingProvider)
+	}
+}
+
+// HashingEmbedder is a local model that needs no service: words, character
+// trigrams of Latin words and character bigrams of Japanese text are hashed
+// into a fixed number of dimensions
+type HashingEmbedder struct {
+	dims int
+}
+
+func NewHashingEmbedder(dims int) *HashingEmbedder {
+	if dims <= 0 {
+		dims = defaultEmbeddingDimensions
+	}
+	return &HashingEmbedder{dims: dims}
+}
+
+func (e *HashingEmbedder) Name() string {
+	return fmt.Sprintf("hashing-%d", e.dims)
+}
+
+func (e *HashingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vector := make([]float32, e.dims)
+		for feature, weight := range embeddingFeatures(text) {
+			h := fnv.New32a()
+			h.Write([]byte(feature))
+			sum := h.Sum32()
+			// The top bit picks a sign so that collisions cancel out on average
+			if sum&(1<<31) != 0 {
+				weight = -weight
+			}
+			vector[int(sum%uint32(e.dims))] += weight
+		}
+		NormalizeVector(vector)
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// embeddingFeatures splits text into weighted features
+func embeddingFeatures(text string) map[string]float32 {
+	features := make(map[string]float32)
+	var latin, cjk []rune
+
+	flushLatin := func() {
+		if len(latin) == 0 {
+			return
+		}
+		word := string(latin)
+		features["w:"+word]++
+		padded := []rune("#" + word + "#")
+		for i := 0; i+3 <= len(padded); i++ {
+			features["t:"+string(padded[i:i+3])] += 0.5
+		}
+		latin = latin[:0]
+	}
+	flushCJK := func() {
+		if len(cjk) == 0 {
+			return
+		}
+		for i, r := range cjk {
+			features["u:"+string(r)] += 0.3
+			if i+1 < len(cjk) {
+				features["b:"+string(cjk[i:i+2])]++
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || r == 'ー':
+			flushLatin()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			latin = append(latin, r)
+		default:
+			flushLatin()
+			flushCJK()
+		}
+	}
+	flushLatin()
+	flushCJK()
+	return features
+}
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint
+type OpenAIEmbedder struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+func NewOpenAIEmbedder(apiKey, baseURL, model string) *OpenAIEmbedder {
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	if model == "" {
+		model = defaultOpenAIEmbedModel
+	}
+	return &OpenAIEmbedder{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      model,
+		httpClient: &http.Client{Timeout: defaultLLMTimeout},
+	}
+}
+
+func (e *OpenAIEmbedder) Name() string {
+	return "openai:" + e.model
+}
+
+type openAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	headers := map[string]string{}
+	if e.apiKey != "" {
+		headers["Authorization"] = "Bearer " + e.apiKey
+	}
+
+	var resp openAIEmbeddingResponse
+	body := openAIEmbeddingRequest{Model: e.model, Input: texts}
+	if err := postLLMJSON(ctx, e.httpClient, "openai-embeddings", e.baseURL+"/embeddings", headers, body, &resp); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, errors.New("embedding index out of range")
+		}
+		NormalizeVector(d.Embedding)
+		vectors[d.Index] = d.Embedding
+	}
+	for _, v := range vectors {
+		if v == nil {
+			return nil, errors.New("missing embedding in response")
+		}
+	}
+	return vectors, nil
+}
+
+// NormalizeVector scales v to unit length in place
+func NormalizeVector(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+// DotProduct is the cosine similarity of two unit vectors
+func DotProduct(a, b []float32) float32 {
+	var sum float32
+	for i := 0; i < len(a) && i < len(b); i++ {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,37 @@
+package infrastructure
+
+import (
+	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type productEmbeddingRepository struct {
+	db *gorm.DB
+}
+
+func NewProductEmbeddingRepository(db *gorm.DB) domain.ProductEmbeddingRepository {
+	return &productEmbeddingRepository{db: db}
+}
+
+func (r *productEmbeddingRepository) Save(embedding *domain.ProductEmbedding) error {
+	return r.db.Save(embedding).Error
+}
+
+func (r *productEmbeddingRepository) FindByProductID(productID uuid.UUID) (*domain.ProductEmbedding, error) {
+	var embedding domain.ProductEmbedding
+	if err := r.db.Where("product_id = ?", productID).First(&embedding).Error; err != nil {
+		return nil, err
+	}
+	return &embedding, nil
+}
+
+func (r *productEmbeddingRepository) FindByModel(model string) ([]*domain.ProductEmbedding, error) {
+	var embeddings []*domain.ProductEmbedding
+	err := r.db.Where("model = ?", model).Find(&embeddings).Error
+	return embeddings, err
+}
+
+func (r *productEmbeddingRepository) Delete(productID uuid.UUID) error {
+	return r.db.Delete(&domain.ProductEmbedding{}, "product_id = ?", productID).Error
+}
@@ -0,0 +1,300 @@
+package infrastructure
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// VectorHit is one nearest-neighbour result; Score is the cosine similarity
+type VectorHit struct {
+	ID    uuid.UUID
+	Score float32
+}
+
+// VectorIndex answers approximate nearest-neighbour queries over unit vectors
+type VectorIndex interface {
+	Upsert(id uuid.UUID, vector []float32) error
+	Remove(id uuid.UUID)
+	Search(vector []float32, k int) []VectorHit
+	Len() int
+}
+
+// HNSWIndex is an in-memory Hierarchical Navigable Small World graph.
+// Removed vectors stay in the graph as waypoints but are never returned; the
+// graph is rebuilt once they make up half of it.
+type HNSWIndex struct {
+	mu sync.RWMutex
+
+	m              int // neighbours per node above layer 0
+	m0             int // neighbours per node on layer 0
+	efConstruction int
+	efSearch       int
+	levelMult      float64
+	rng            *rand.Rand
+
+	dims     int
+	nodes    []*hnswNode
+	live     map[uuid.UUID]int
+	entry    int
+	maxLevel int
+}
+
+type hnswNode struct {
+	id      uuid.UUID
+	vector  []float32
+	friends [][]int // neighbour node indexes per layer
+	deleted bool
+}
+
+func NewHNSWIndex(m, efConstruction, efSearch int) *HNSWIndex {
+	if m < 2 {
+		m = 16
+	}
+	if efConstruction < m {
+		efConstruction = 200
+	}
+	if efSearch < 1 {
+		efSearch = 64
+	}
+	return &HNSWIndex{
+		m:              m,
+		m0:             2 * m,
+		efConstruction: efConstruction,
+		efSearch:       efSearch,
+		levelMult:      1 / math.Log(float64(m)),
+		rng:            rand.New(rand.NewSource(1)),
+		live:           make(map[uuid.UUID]int),
+		entry:          -1,
+	}
+}
+
+// Upsert adds the vector for id, replacing any earlier one
+func (h *HNSWIndex) Upsert(id uuid.UUID, vector []float32) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.dims == 0 {
+		h.dims = len(vector)
+	}
+	if len(vector) != h.dims {
+		return errors.New("vector dimensions do not match the index")
+	}
+
+	h.remove(id)
+	h.insert(id, vector)
+	return nil
+}
+
+func (h *HNSWIndex) Remove(id uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(id)
+}
+
+func (h *HNSWIndex) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.live)
+}
+
+// Search returns up to k vectors most similar to vector, best first
+func (h *HNSWIndex) Search(vector []float32, k int) []VectorHit {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.entry == -1 || k <= 0 || len(vector) != h.dims {
+		return nil
+	}
+
+	ep := h.entry
+	for level := h.maxLevel; level > 0; level-- {
+		ep = h.greedy(vector, ep, level)
+	}
+
+	ef := h.efSearch
+	if ef < k {
+		ef = k
+	}
+	hits := make([]VectorHit, 0, k)
+	for _, item := range h.searchLayer(vector, ep, ef, 0) {
+		node := h.nodes[item.node]
+		if node.deleted {
+			continue
+		}
+		hits = append(hits, VectorHit{ID: node.id, Score: item.sim})
+		if len(hits) == k {
+			break
+		}
+	}
+	return hits
+}
+
+func (h *HNSWIndex) remove(id uuid.UUID) {
+	idx, ok := h.live[id]
+	if !ok {
+		return
+	}
+	h.nodes[idx].deleted = true
+	delete(h.live, id)
+
+	if len(h.nodes) >= 32 && len(h.live) < len(h.nodes)/2 {
+		h.rebuild()
+	}
+}
+
+// rebuild drops removed vectors by building a fresh graph
+func (h *HNSWIndex) rebuild() {
+	old := h.nodes
+	h.nodes = nil
+	h.live = make(map[uuid.UUID]int)
+	h.entry = -1
+	h.maxLevel = 0
+	for _, node := range old {
+		if !node.deleted {
+			h.insert(node.id, node.vector)
+		}
+	}
+}
+
+func (h *HNSWIndex) insert(id uuid.UUID, vector []float32) {
+	level := int(-math.Log(1-h.rng.Float64()) * h.levelMult)
+	idx := len(h.nodes)
+	node := &hnswNode{id: id, vector: vector, friends: make([][]int, level+1)}
+	h.nodes = append(h.nodes, node)
+	h.live[id] = idx
+
+	if h.entry == -1 {
+		h.entry = idx
+		h.maxLevel = level
+		return
+	}
+
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(vector, ep, l)
+	}
+
+	top := level
+	if top > h.maxLevel {
+		top = h.maxLevel
+	}
+	for l := top; l >= 0; l-- {
+		candidates := h.searchLayer(vector, ep, h.efConstruction, l)
+		for i := 0; i < len(candidates) && i < h.m; i++ {
+			friend := candidates[i].node
+			node.friends[l] = append(node.friends[l], friend)
+			h.link(friend, idx, l)
+		}
+		ep = candidates[0].node
+	}
+
+	if level > h.maxLevel {
+		h.maxLevel = level
+		h.entry = idx
+	}
+}
+
+// link adds to as a neighbour of from, keeping only the closest neighbours
+func (h *HNSWIndex) link(from, to, level int) {
+	node := h.nodes[from]
+	node.friends[level] = append(node.friends[level], to)
+
+	limit := h.m
+	if level == 0 {
+		limit = h.m0
+	}
+	if len(node.friends[level]) <= limit {
+		return
+	}
+
+	friends := node.friends[level]
+	sort.Slice(friends, func(i, j int) bool {
+		return DotProduct(node.vector, h.nodes[friends[i]].vector) > DotProduct(node.vector, h.nodes[friends[j]].vector)
+	})
+	node.friends[level] = friends[:limit]
+}
+
+// greedy walks towards vector on one layer until no neighbour is closer
+func (h *HNSWIndex) greedy(vector []float32, ep, level int) int {
+	best := DotProduct(vector, h.nodes[ep].vector)
+	for changed := true; changed; {
+		changed = false
+		for _, friend := range h.nodes[ep].friends[level] {
+			if sim := DotProduct(vector, h.nodes[friend].vector); sim > best {
+				best, ep, changed = sim, friend, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer is a beam search of width ef; results are sorted best first
+func (h *HNSWIndex) searchLayer(vector []float32, ep, ef, level int) []hnswItem {
+	visited := map[int]bool{ep: true}
+	first := hnswItem{node: ep, sim: DotProduct(vector, h.nodes[ep].vector)}
+	candidates := &hnswQueue{best: true, items: []hnswItem{first}}
+	results := &hnswQueue{items: []hnswItem{first}}
+
+	for candidates.Len() > 0 {
+		current := heap.Pop(candidates).(hnswItem)
+		if results.Len() >= ef && current.sim < results.items[0].sim {
+			break
+		}
+		for _, friend := range h.nodes[current.node].friends[level] {
+			if visited[friend] {
+				continue
+			}
+			visited[friend] = true
+
+			sim := DotProduct(vector, h.nodes[friend].vector)
+			if results.Len() < ef || sim > results.items[0].sim {
+				heap.Push(candidates, hnswItem{node: friend, sim: sim})
+				heap.Push(results, hnswItem{node: friend, sim: sim})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	sorted := results.items
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].sim > sorted[j].sim })
+	return sorted
+}
+
+type hnswItem struct {
+	node int
+	sim  float32
+}
+
+// hnswQueue is a heap of nodes: the most similar on top when best is set,
+// the least similar otherwise
+type hnswQueue struct {
+	items []hnswItem
+	best  bool
+}
+
+func (q *hnswQueue) Len() int { return len(q.items) }
+
+func (q *hnswQueue) Less(i, j int) bool {
+	if q.best {
+		return q.items[i].sim > q.items[j].sim
+	}
+	return q.items[i].sim < q.items[j].sim
+}
+
+func (q *hnswQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *hnswQueue) Push(x interface{}) { q.items = append(q.items, x.(hnswItem)) }
+
+func (q *hnswQueue) Pop() interface{} {
+	item := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return item
+}
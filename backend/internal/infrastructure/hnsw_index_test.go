@@ -0,0 +1,168 @@
+package infrastructure_test
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+)
+
+func randomUnitVector(rng *rand.Rand, dims int) []float32 {
+	v := make([]float32, dims)
+	for i := range v {
+		v[i] = float32(rng.NormFloat64())
+	}
+	infrastructure.NormalizeVector(v)
+	return v
+}
+
+// bruteForce returns the ids of the k vectors most similar to query
+func bruteForce(vectors map[uuid.UUID][]float32, query []float32, k int) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(vectors))
+	for id := range vectors {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return infrastructure.DotProduct(query, vectors[ids[i]]) > infrastructure.DotProduct(query, vectors[ids[j]])
+	})
+	return ids[:k]
+}
+
+func TestHNSWIndex_RecallMatchesBruteForce(t *testing.T) {
+	// Arrange
+	rng := rand.New(rand.NewSource(42))
+	index := infrastructure.NewHNSWIndex(8, 64, 64)
+	vectors := make(map[uuid.UUID][]float32)
+	for i := 0; i < 1000; i++ {
+		id := uuid.New()
+		vectors[id] = randomUnitVector(rng, 24)
+		require.NoError(t, index.Upsert(id, vectors[id]))
+	}
+
+	// Act
+	const queries, k = 50, 10
+	found := 0
+	for i := 0; i < queries; i++ {
+		query := randomUnitVector(rng, 24)
+		want := make(map[uuid.UUID]bool)
+		for _, id := range bruteForce(vectors, query, k) {
+			want[id] = true
+		}
+
+		hits := index.Search(query, k)
+		require.Len(t, hits, k)
+		for j, hit := range hits {
+			if want[hit.ID] {
+				found++
+			}
+			if j > 0 {
+				assert.GreaterOrEqual(t, hits[j-1].Score, hit.Score, "hits are best first")
+			}
+		}
+	}
+
+	// Assert
+	recall := float64(found) / float64(queries*k)
+	assert.GreaterOrEqual(t, recall, 0.9, "recall@%d", k)
+	assert.Equal(t, len(vectors), index.Len())
+}
+
+func TestHNSWIndex_UpsertReplacesAndRemoveDeletes(t *testing.T) {
+	// Arrange
+	rng := rand.New(rand.NewSource(7))
+	index := infrastructure.NewHNSWIndex(4, 32, 32)
+	ids := make([]uuid.UUID, 40)
+	for i := range ids {
+		ids[i] = uuid.New()
+		require.NoError(t, index.Upsert(ids[i], randomUnitVector(rng, 8)))
+	}
+	moved := ids[0]
+	oldVector := randomUnitVector(rng, 8)
+	require.NoError(t, index.Upsert(moved, oldVector))
+	newVector := randomUnitVector(rng, 8)
+
+	// Act
+	require.NoError(t, index.Upsert(moved, newVector))
+	afterUpdate := index.Search(newVector, 1)
+	everything := index.Search(oldVector, len(ids))
+
+	// Removing most of the vectors makes the index rebuild its graph
+	for _, id := range ids[1:30] {
+		index.Remove(id)
+	}
+	index.Remove(uuid.New())
+	remaining := index.Search(newVector, len(ids))
+
+	// Assert
+	require.Len(t, afterUpdate, 1)
+	assert.Equal(t, moved, afterUpdate[0].ID)
+	assert.InDelta(t, 1.0, afterUpdate[0].Score, 1e-5)
+
+	seen := 0
+	for _, hit := range everything {
+		if hit.ID == moved {
+			seen++
+			assert.InDelta(t, infrastructure.DotProduct(oldVector, newVector), hit.Score, 1e-5, "only the new vector is kept")
+		}
+	}
+	assert.Equal(t, 1, seen)
+
+	assert.Equal(t, 11, index.Len())
+	assert.Len(t, remaining, 11)
+	for _, hit := range remaining {
+		assert.NotContains(t, ids[1:30], hit.ID)
+	}
+	assert.Error(t, index.Upsert(uuid.New(), []float32{1, 0}), "dimensions must match")
+}
+
+func TestHNSWIndex_SearchWhileAdding(t *testing.T) {
+	// Arrange
+	index := infrastructure.NewHNSWIndex(8, 32, 32)
+	seed := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		require.NoError(t, index.Upsert(uuid.New(), randomUnitVector(seed, 16)))
+	}
+
+	// Act: writers add and replace vectors while readers search
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(100 + w)))
+			ids := make([]uuid.UUID, 0, 100)
+			for i := 0; i < 100; i++ {
+				id := uuid.New()
+				if i%4 == 3 {
+					id = ids[rng.Intn(len(ids))]
+				}
+				ids = append(ids, id)
+				assert.NoError(t, index.Upsert(id, randomUnitVector(rng, 16)))
+				if i%10 == 9 {
+					index.Remove(ids[rng.Intn(len(ids))])
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(200 + r)))
+			for i := 0; i < 200; i++ {
+				hits := index.Search(randomUnitVector(rng, 16), 5)
+				assert.LessOrEqual(t, len(hits), 5)
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	// Assert
+	assert.Greater(t, index.Len(), 50)
+	assert.Len(t, index.Search(randomUnitVector(seed, 16), 5), 5)
+}
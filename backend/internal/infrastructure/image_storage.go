@@ -1,18 +1,31 @@
 package infrastructure
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
 	"io"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/config"
+	"gorm.io/gorm"
 )
 
-// ImageStorage interface for storing images
+// ImageStorage interface for storing images. Upload returns the URL of the
+// stored file, whose last path element is the filename the other methods take.
 type ImageStorage interface {
 	Upload(ctx context.Context, file io.Reader, filename string) (string, error)
+	Open(ctx context.Context, filename string) (io.ReadCloser, error)
 	Delete(ctx context.Context, filename string) error
 	GetURL(filename string) string
 }
@@ -35,18 +48,50 @@ func (s *LocalImageStorage) Upload(ctx context.Context, file io.Reader, filename
 	ext := filepath.Ext(filename)
 	uniqueFilename := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().Unix(), ext)
 
-	// In production, save to disk or upload to GCS
-	// For now, return a mock URL
-	url := fmt.Sprintf("%s/uploads/%s", s.baseURL, uniqueFilename)
+	if err := os.MkdirAll(s.basePath, 0755); err != nil {
+		return "", err
+	}
+
+	dst, err := os.Create(filepath.Join(s.basePath, uniqueFilename))
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, file); err != nil {
+		return "", err
+	}
+
+	return s.GetURL(uniqueFilename), nil
+}
 
-	return url, nil
+func (s *LocalImageStorage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	path, err := s.path(filename)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
 }
 
 func (s *LocalImageStorage) Delete(ctx context.Context, filename string) error {
-	// In production, delete from disk or GCS
+	path, err := s.path(filename)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return nil
 }
 
+// path keeps filenames inside the storage directory
+func (s *LocalImageStorage) path(filename string) (string, error) {
+	if filename == "" || filename != filepath.Base(filename) || strings.HasPrefix(filename, ".") {
+		return "", errors.New("invalid filename")
+	}
+	return filepath.Join(s.basePath, filename), nil
+}
+
 func (s *LocalImageStorage) GetURL(filename string) string {
 	return fmt.Sprintf("%s/uploads/%s", s.baseURL, filename)
 }
@@ -74,6 +119,11 @@ func (s *GCSImageStorage) Upload(ctx context.Context, file io.Reader, filename s
 	return cdnURL, nil
 }
 
+func (s *GCSImageStorage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	// In production, read from GCS
+	return nil, errors.New("reading from GCS is not supported yet")
+}
+
 func (s *GCSImageStorage) Delete(ctx context.Context, filename string) error {
 	// In production, delete from GCS
 	return nil
@@ -83,6 +133,82 @@ func (s *GCSImageStorage) GetURL(filename string) string {
 	return fmt.Sprintf("%s/%s", s.cdnURL, filename)
 }
 
+// NewAttachmentStorage builds the storage for message attachments selected in
+// cfg. Files on local disk are only found by the replica that stored them, so
+// local storage is refused unless this is the only replica.
+func NewAttachmentStorage(cfg *config.StorageConfig, singleReplica bool, db *gorm.DB) (ImageStorage, error) {
+	switch cfg.AttachmentStorage {
+	case "", "database":
+		return NewSQLImageStorage(db)
+	case "local":
+		if !singleReplica {
+			return nil, errors.New("local attachment storage cannot be shared between replicas")
+		}
+		return NewLocalImageStorage(cfg.AttachmentDir, ""), nil
+	default:
+		return nil, fmt.Errorf("unknown attachment storage: %s", cfg.AttachmentStorage)
+	}
+}
+
+// storedFile is a file kept in the database
+type storedFile struct {
+	Name      string `gorm:"type:varchar(128);primaryKey"`
+	Data      []byte `gorm:"type:mediumblob;not null"`
+	CreatedAt time.Time
+}
+
+func (storedFile) TableName() string {
+	return "stored_files"
+}
+
+// SQLImageStorage keeps files in the database, so every replica can serve
+// what any of them stored. It suits private files of a few megabytes, such
+// as message attachments.
+type SQLImageStorage struct {
+	db *gorm.DB
+}
+
+func NewSQLImageStorage(db *gorm.DB) (*SQLImageStorage, error) {
+	if err := db.AutoMigrate(&storedFile{}); err != nil {
+		return nil, err
+	}
+	return &SQLImageStorage{db: db}, nil
+}
+
+func (s *SQLImageStorage) Upload(ctx context.Context, file io.Reader, filename string) (string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+
+	ext := filepath.Ext(filename)
+	uniqueFilename := fmt.Sprintf("%s_%d%s", uuid.New().String(), time.Now().Unix(), ext)
+	if err := s.db.WithContext(ctx).Create(&storedFile{Name: uniqueFilename, Data: data}).Error; err != nil {
+		return "", err
+	}
+
+	return s.GetURL(uniqueFilename), nil
+}
+
+func (s *SQLImageStorage) Open(ctx context.Context, filename string) (io.ReadCloser, error) {
+	var stored storedFile
+	if err := s.db.WithContext(ctx).Where("name = ?", filename).First(&stored).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, os.ErrNotExist
+		}
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(stored.Data)), nil
+}
+
+func (s *SQLImageStorage) Delete(ctx context.Context, filename string) error {
+	return s.db.WithContext(ctx).Where("name = ?", filename).Delete(&storedFile{}).Error
+}
+
+func (s *SQLImageStorage) GetURL(filename string) string {
+	return fmt.Sprintf("/uploads/%s", filename)
+}
+
 // ImageProcessor handles image optimization
 type ImageProcessor struct{}
 
@@ -98,9 +224,84 @@ func (p *ImageProcessor) OptimizeImage(data []byte, maxWidth, maxHeight uint) ([
 	return data, nil
 }
 
-// GenerateThumbnail placeholder for thumbnail generation
+// ImageSize reads the dimensions of a JPEG, PNG or GIF image
+func (p *ImageProcessor) ImageSize(data []byte) (int, int, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
+
+// maxThumbnailPixels bounds the images decoded for thumbnails
+const maxThumbnailPixels = 40_000_000
+
+// GenerateThumbnail scales a JPEG, PNG or GIF image down to fit in a square of
+// size pixels, averaging the pixels each thumbnail pixel covers, and encodes
+// it as JPEG on a white background
 func (p *ImageProcessor) GenerateThumbnail(data []byte, size uint) ([]byte, error) {
-	// In production: create thumbnail
-	// For now, return original data
-	return data, nil
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxThumbnailPixels {
+		return nil, errors.New("image is too large")
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	longest := width
+	if height > longest {
+		longest = height
+	}
+	thumbWidth, thumbHeight := width, height
+	if uint(longest) > size {
+		thumbWidth = int(uint(width) * size / uint(longest))
+		thumbHeight = int(uint(height) * size / uint(longest))
+	}
+	if thumbWidth < 1 {
+		thumbWidth = 1
+	}
+	if thumbHeight < 1 {
+		thumbHeight = 1
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		y0 := bounds.Min.Y + y*height/thumbHeight
+		y1 := bounds.Min.Y + (y+1)*height/thumbHeight
+		for x := 0; x < thumbWidth; x++ {
+			x0 := bounds.Min.X + x*width/thumbWidth
+			x1 := bounds.Min.X + (x+1)*width/thumbWidth
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			// Colors are premultiplied, so adding the missing alpha blends
+			// onto white
+			white := 0xffff*n - a
+			thumb.Set(x, y, color.RGBA64{
+				R: uint16((r + white) / n),
+				G: uint16((g + white) / n),
+				B: uint16((b + white) / n),
+				A: 0xffff,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,76 @@
+package infrastructure_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ecomate/backend/internal/config"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+)
+
+func TestSQLImageStorage_ServesFilesToEveryReplica(t *testing.T) {
+	// Arrange: two replicas on one database
+	db := newTestDB(t)
+	replicaA, err := infrastructure.NewSQLImageStorage(db)
+	require.NoError(t, err)
+	replicaB, err := infrastructure.NewSQLImageStorage(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Act
+	url, err := replicaA.Upload(ctx, bytes.NewReader([]byte("%PDF-1.4 receipt")), "attachment.pdf")
+	require.NoError(t, err)
+	key := path.Base(url)
+	file, openErr := replicaB.Open(ctx, key)
+	require.NoError(t, openErr)
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	deleteErr := replicaB.Delete(ctx, key)
+	_, afterDeleteErr := replicaA.Open(ctx, key)
+
+	// Assert
+	assert.Equal(t, "/uploads/"+key, url)
+	assert.Equal(t, ".pdf", path.Ext(key))
+	assert.Equal(t, "%PDF-1.4 receipt", string(data))
+	assert.NoError(t, deleteErr)
+	assert.ErrorIs(t, afterDeleteErr, os.ErrNotExist)
+}
+
+func TestNewAttachmentStorage(t *testing.T) {
+	db := newTestDB(t)
+
+	for _, tc := range []struct {
+		name          string
+		storage       string
+		singleReplica bool
+		expected      string
+	}{
+		{name: "database by default"},
+		{name: "local on a single replica", storage: "local", singleReplica: true},
+		{name: "local on several replicas", storage: "local", expected: "local attachment storage cannot be shared between replicas"},
+		{name: "unknown", storage: "s3", singleReplica: true, expected: "unknown attachment storage: s3"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			storage, err := infrastructure.NewAttachmentStorage(&config.StorageConfig{
+				AttachmentStorage: tc.storage,
+				AttachmentDir:     t.TempDir(),
+			}, tc.singleReplica, db)
+
+			// Assert
+			if tc.expected != "" {
+				assert.EqualError(t, err, tc.expected)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, storage)
+		})
+	}
+}
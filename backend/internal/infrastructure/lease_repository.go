@@ -0,0 +1,48 @@
+package infrastructure
+
+import (
+	"time"
+
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type leaseRepository struct {
+	db *gorm.DB
+}
+
+func NewLeaseRepository(db *gorm.DB) domain.LeaseRepository {
+	return &leaseRepository{db: db}
+}
+
+func (r *leaseRepository) TryAcquire(name, holder string, ttl time.Duration, now time.Time) (bool, error) {
+	expiresAt := now.Add(ttl)
+
+	// Renew our own lease or take over an expired one
+	result := r.db.Model(&domain.Lease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": expiresAt})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// First run: the row does not exist yet. If another replica inserts it
+	// concurrently, only one insert wins.
+	result = r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&domain.Lease{
+		Name:      name,
+		Holder:    holder,
+		ExpiresAt: expiresAt,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *leaseRepository) Release(name, holder string) error {
+	return r.db.Where("name = ? AND holder = ?", name, holder).Delete(&domain.Lease{}).Error
+}
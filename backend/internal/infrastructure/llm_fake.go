@@ -0,0 +1,69 @@
+package infrastructure
+
+import (
+	"context"
+	"strings"
+)
+
+// FakeLLMProvider answers without calling a model (for development and tests).
+// The same request always gets the same reply.
+type FakeLLMProvider struct {
+	reply func(req *LLMRequest) (string, error)
+}
+
+// NewFakeLLMProvider uses reply to answer requests. With a nil reply it echoes
+// the last message, or returns an empty JSON object for JSON requests.
+func NewFakeLLMProvider(reply func(req *LLMRequest) (string, error)) *FakeLLMProvider {
+	return &FakeLLMProvider{reply: reply}
+}
+
+func (p *FakeLLMProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeLLMProvider) Generate(ctx context.Context, req *LLMRequest) (*LLMResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var text string
+	if p.reply != nil {
+		var err error
+		if text, err = p.reply(req); err != nil {
+			return nil, err
+		}
+	} else if req.JSON {
+		text = "{}"
+	} else if len(req.Messages) > 0 {
+		text = req.Messages[len(req.Messages)-1].Content
+	}
+
+	usage := TokenUsage{
+		PromptTokens:     estimateTokens(req.text()),
+		CompletionTokens: estimateTokens(text),
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	return &LLMResponse{Text: text, Usage: usage}, nil
+}
+
+// Stream hands out the reply word by word, stopping when ctx is done
+func (p *FakeLLMProvider) Stream(ctx context.Context, req *LLMRequest, onDelta func(string) error) (*LLMResponse, error) {
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, delta := range strings.SplitAfter(resp.Text, " ") {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if delta == "" {
+			continue
+		}
+		if err := onDelta(delta); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,171 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	defaultGeminiModel   = "gemini-2.5-flash"
+)
+
+// GeminiProvider calls the Gemini generateContent API
+type GeminiProvider struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+func NewGeminiProvider(apiKey, baseURL, model string) *GeminiProvider {
+	if baseURL == "" {
+		baseURL = defaultGeminiBaseURL
+	}
+	if model == "" {
+		model = defaultGeminiModel
+	}
+	return &GeminiProvider{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      model,
+		httpClient: &http.Client{},
+	}
+}
+
+type GeminiRequest struct {
+	SystemInstruction *GeminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []GeminiContent         `json:"contents"`
+	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+type GeminiPart struct {
+	Text string `json:"text,omitempty"`
+}
+
+type GeminiGenerationConfig struct {
+	Temperature      float64 `json:"temperature,omitempty"`
+	MaxOutputTokens  int     `json:"maxOutputTokens,omitempty"`
+	ResponseMimeType string  `json:"responseMimeType,omitempty"`
+}
+
+type GeminiResponse struct {
+	Candidates    []GeminiCandidate    `json:"candidates"`
+	UsageMetadata *GeminiUsageMetadata `json:"usageMetadata,omitempty"`
+}
+
+type GeminiCandidate struct {
+	Content GeminiContent `json:"content"`
+}
+
+type GeminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+func (p *GeminiProvider) Name() string {
+	return "gemini"
+}
+
+func (p *GeminiProvider) Generate(ctx context.Context, req *LLMRequest) (*LLMResponse, error) {
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", p.baseURL, p.model, p.apiKey)
+	var resp GeminiResponse
+	if err := postLLMJSON(ctx, p.httpClient, p.Name(), url, nil, p.buildRequest(req), &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil, errors.New("no response from Gemini")
+	}
+
+	return &LLMResponse{Text: resp.text(), Usage: resp.usage()}, nil
+}
+
+// Stream uses streamGenerateContent, which sends one GeminiResponse per event
+func (p *GeminiProvider) Stream(ctx context.Context, req *LLMRequest, onDelta func(string) error) (*LLMResponse, error) {
+	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse&key=%s", p.baseURL, p.model, p.apiKey)
+	resp, err := sendLLMRequest(ctx, p.httpClient, p.Name(), url, nil, p.buildRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var text strings.Builder
+	var usage TokenUsage
+	err = readSSE(resp.Body, func(data []byte) error {
+		var chunk GeminiResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.UsageMetadata != nil {
+			usage = chunk.usage()
+		}
+		delta := chunk.text()
+		if delta == "" {
+			return nil
+		}
+		text.WriteString(delta)
+		return onDelta(delta)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LLMResponse{Text: text.String(), Usage: usage}, nil
+}
+
+func (p *GeminiProvider) buildRequest(req *LLMRequest) GeminiRequest {
+	body := GeminiRequest{}
+	if req.System != "" {
+		body.SystemInstruction = &GeminiContent{Parts: []GeminiPart{{Text: req.System}}}
+	}
+	for _, msg := range req.Messages {
+		role := "user"
+		if msg.Role == "assistant" {
+			role = "model"
+		}
+		body.Contents = append(body.Contents, GeminiContent{Role: role, Parts: []GeminiPart{{Text: msg.Content}}})
+	}
+	if req.Temperature > 0 || req.MaxTokens > 0 || req.JSON {
+		body.GenerationConfig = &GeminiGenerationConfig{
+			Temperature:     req.Temperature,
+			MaxOutputTokens: req.MaxTokens,
+		}
+		if req.JSON {
+			body.GenerationConfig.ResponseMimeType = "application/json"
+		}
+	}
+	return body
+}
+
+func (r *GeminiResponse) text() string {
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	var text strings.Builder
+	for _, part := range r.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	return text.String()
+}
+
+func (r *GeminiResponse) usage() TokenUsage {
+	if r.UsageMetadata == nil {
+		return TokenUsage{}
+	}
+	return TokenUsage{
+		PromptTokens:     r.UsageMetadata.PromptTokenCount,
+		CompletionTokens: r.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      r.UsageMetadata.TotalTokenCount,
+	}
+}
@@ -0,0 +1,171 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "gpt-4o-mini"
+)
+
+// OpenAIProvider calls an OpenAI-compatible chat completions endpoint. With a
+// base URL such as http://localhost:11434/v1 it works against local servers.
+type OpenAIProvider struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+func NewOpenAIProvider(apiKey, baseURL, model string) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	return &OpenAIProvider{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      model,
+		httpClient: &http.Client{},
+	}
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIResponseFormat struct {
+	Type string `json:"type"`
+}
+
+type openAIRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	Temperature    float64               `json:"temperature,omitempty"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Usage *TokenUsage `json:"usage,omitempty"`
+}
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta openAIMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *TokenUsage `json:"usage,omitempty"`
+}
+
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+func (p *OpenAIProvider) Generate(ctx context.Context, req *LLMRequest) (*LLMResponse, error) {
+	var resp openAIResponse
+	if err := postLLMJSON(ctx, p.httpClient, p.Name(), p.baseURL+"/chat/completions", p.headers(), p.buildRequest(req), &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("no response from OpenAI-compatible endpoint")
+	}
+
+	result := &LLMResponse{Text: resp.Choices[0].Message.Content}
+	result.Usage = usageOrEstimate(resp.Usage, req, result.Text)
+	return result, nil
+}
+
+// Stream asks for a streamed completion; each event carries a content delta
+// and, when the server supports it, the final event carries usage
+func (p *OpenAIProvider) Stream(ctx context.Context, req *LLMRequest, onDelta func(string) error) (*LLMResponse, error) {
+	body := p.buildRequest(req)
+	body.Stream = true
+	body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+
+	resp, err := sendLLMRequest(ctx, p.httpClient, p.Name(), p.baseURL+"/chat/completions", p.headers(), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var text strings.Builder
+	var usage *TokenUsage
+	err = readSSE(resp.Body, func(data []byte) error {
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			return nil
+		}
+		delta := chunk.Choices[0].Delta.Content
+		text.WriteString(delta)
+		return onDelta(delta)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LLMResponse{Text: text.String(), Usage: usageOrEstimate(usage, req, text.String())}, nil
+}
+
+func (p *OpenAIProvider) buildRequest(req *LLMRequest) openAIRequest {
+	body := openAIRequest{
+		Model:       p.model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+	if req.System != "" {
+		body.Messages = append(body.Messages, openAIMessage{Role: "system", Content: req.System})
+	}
+	for _, msg := range req.Messages {
+		body.Messages = append(body.Messages, openAIMessage{Role: msg.Role, Content: msg.Content})
+	}
+	if req.JSON {
+		body.ResponseFormat = &openAIResponseFormat{Type: "json_object"}
+	}
+	return body
+}
+
+func (p *OpenAIProvider) headers() map[string]string {
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	return headers
+}
+
+// usageOrEstimate falls back to estimates, as some local servers leave usage out
+func usageOrEstimate(reported *TokenUsage, req *LLMRequest, text string) TokenUsage {
+	if reported != nil {
+		return *reported
+	}
+	usage := TokenUsage{
+		PromptTokens:     estimateTokens(req.text()),
+		CompletionTokens: estimateTokens(text),
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
+}
@@ -0,0 +1,348 @@
+package infrastructure
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ecomate/backend/internal/config"
+)
+
+// LLMRequest is a provider-independent text generation request
+type LLMRequest struct {
+	System      string
+	Messages    []ChatMessage
+	Temperature float64 // 0 keeps the provider default
+	MaxTokens   int     // 0 keeps the provider default
+	JSON        bool    // ask the model to answer with a JSON object
+}
+
+// text joins everything sent to the model, for token estimates
+func (r *LLMRequest) text() string {
+	var b strings.Builder
+	b.WriteString(r.System)
+	for _, msg := range r.Messages {
+		b.WriteString(msg.Content)
+	}
+	return b.String()
+}
+
+// Prompt builds a single-turn request
+func Prompt(text string) *LLMRequest {
+	return &LLMRequest{Messages: []ChatMessage{{Role: "user", Content: text}}}
+}
+
+// TokenUsage counts the tokens a call consumed, as reported by the provider
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func (u *TokenUsage) add(other TokenUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+type LLMResponse struct {
+	Text  string
+	Usage TokenUsage
+}
+
+// LLMProvider generates text with a large language model
+type LLMProvider interface {
+	Name() string
+	Generate(ctx context.Context, req *LLMRequest) (*LLMResponse, error)
+}
+
+// LLMStreamer is implemented by providers that can hand over text while it is
+// still being generated. onDelta is called with each new piece of text; if it
+// returns an error the stream is abandoned and that error returned.
+type LLMStreamer interface {
+	Stream(ctx context.Context, req *LLMRequest, onDelta func(string) error) (*LLMResponse, error)
+}
+
+// StreamLLM streams from provider when it supports streaming and otherwise
+// delivers the whole reply as a single delta
+func StreamLLM(ctx context.Context, provider LLMProvider, req *LLMRequest, onDelta func(string) error) (*LLMResponse, error) {
+	if streamer, ok := provider.(LLMStreamer); ok {
+		return streamer.Stream(ctx, req, onDelta)
+	}
+
+	resp, err := provider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := onDelta(resp.Text); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LLMStatusError is returned when a provider API answers with a non-2xx status
+type LLMStatusError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *LLMStatusError) Error() string {
+	return fmt.Sprintf("%s API error (status %d): %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// Temporary reports whether the request may succeed if retried
+func (e *LLMStatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+const (
+	defaultLLMTimeout = 30 * time.Second
+	llmRetryBackoff   = 500 * time.Millisecond
+)
+
+// NewLLMProvider builds the provider selected in cfg, wrapped with timeouts,
+// retries and token accounting. It returns nil when no provider is configured.
+func NewLLMProvider(cfg *config.AIConfig) (LLMProvider, error) {
+	name := cfg.LLMProvider
+	if name == "" {
+		if cfg.LLMAPIKey == "" {
+			return nil, nil
+		}
+		name = "gemini"
+	}
+
+	var provider LLMProvider
+	switch name {
+	case "gemini":
+		if cfg.LLMAPIKey == "" {
+			return nil, errors.New("gemini provider requires an API key")
+		}
+		provider = NewGeminiProvider(cfg.LLMAPIKey, cfg.LLMBaseURL, cfg.LLMModel)
+	case "openai":
+		provider = NewOpenAIProvider(cfg.LLMAPIKey, cfg.LLMBaseURL, cfg.LLMModel)
+	case "fake":
+		provider = NewFakeLLMProvider(nil)
+	default:
+		return nil, fmt.Errorf("unknown LLM provider: %s", name)
+	}
+
+	timeout := time.Duration(cfg.LLMTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultLLMTimeout
+	}
+	return NewManagedLLMProvider(provider, timeout, cfg.LLMMaxRetries, llmRetryBackoff), nil
+}
+
+// LLMUsageStats is the running total of calls made through a ManagedLLMProvider
+type LLMUsageStats struct {
+	Calls    int        `json:"calls"`
+	Failures int        `json:"failures"`
+	Tokens   TokenUsage `json:"tokens"`
+}
+
+// ManagedLLMProvider applies a timeout to every attempt, retries temporary
+// failures with exponential backoff and keeps count of tokens used
+type ManagedLLMProvider struct {
+	provider   LLMProvider
+	timeout    time.Duration
+	maxRetries int
+	backoff    time.Duration
+
+	mu    sync.Mutex
+	stats LLMUsageStats
+}
+
+func NewManagedLLMProvider(provider LLMProvider, timeout time.Duration, maxRetries int, backoff time.Duration) *ManagedLLMProvider {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	return &ManagedLLMProvider{
+		provider:   provider,
+		timeout:    timeout,
+		maxRetries: maxRetries,
+		backoff:    backoff,
+	}
+}
+
+func (m *ManagedLLMProvider) Name() string {
+	return m.provider.Name()
+}
+
+func (m *ManagedLLMProvider) Generate(ctx context.Context, req *LLMRequest) (*LLMResponse, error) {
+	var lastErr error
+	for attempt := 0; attempt <= m.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(m.backoff << (attempt - 1)):
+			}
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, m.timeout)
+		resp, err := m.provider.Generate(callCtx, req)
+		cancel()
+		if err == nil {
+			m.record(resp.Usage, false)
+			return resp, nil
+		}
+
+		lastErr = err
+		if ctx.Err() != nil || !retryableLLMError(err) {
+			break
+		}
+	}
+
+	m.record(TokenUsage{}, true)
+	return nil, lastErr
+}
+
+// Stream relays deltas from the wrapped provider. A failed attempt is only
+// retried while nothing has been passed on to onDelta yet.
+func (m *ManagedLLMProvider) Stream(ctx context.Context, req *LLMRequest, onDelta func(string) error) (*LLMResponse, error) {
+	var lastErr error
+	for attempt := 0; attempt <= m.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(m.backoff << (attempt - 1)):
+			}
+		}
+
+		delivered := false
+		callCtx, cancel := context.WithTimeout(ctx, m.timeout)
+		resp, err := StreamLLM(callCtx, m.provider, req, func(delta string) error {
+			delivered = true
+			return onDelta(delta)
+		})
+		cancel()
+		if err == nil {
+			m.record(resp.Usage, false)
+			return resp, nil
+		}
+
+		lastErr = err
+		if delivered || ctx.Err() != nil || !retryableLLMError(err) {
+			break
+		}
+	}
+
+	m.record(TokenUsage{}, true)
+	return nil, lastErr
+}
+
+// Usage returns the calls and tokens used so far
+func (m *ManagedLLMProvider) Usage() LLMUsageStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+func (m *ManagedLLMProvider) record(usage TokenUsage, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats.Calls++
+	if failed {
+		m.stats.Failures++
+	}
+	m.stats.Tokens.add(usage)
+}
+
+func retryableLLMError(err error) bool {
+	var statusErr *LLMStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// sendLLMRequest posts body as JSON and returns the response of a successful
+// call. The caller closes the body.
+func sendLLMRequest(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body interface{}) (*http.Response, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &LLMStatusError{Provider: provider, StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return resp, nil
+}
+
+// postLLMJSON sends body as JSON and decodes a successful reply into out
+func postLLMJSON(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body, out interface{}) error {
+	resp, err := sendLLMRequest(ctx, client, provider, url, headers, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// readSSE calls onData with the payload of every "data:" line of a
+// server-sent event stream until the stream ends or sends [DONE]
+func readSSE(r io.Reader, onData func([]byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "[DONE]" {
+			return nil
+		}
+		if payload == "" {
+			continue
+		}
+		if err := onData([]byte(payload)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// estimateTokens approximates a token count for providers that do not report one
+func estimateTokens(text string) int {
+	return (len([]rune(strings.TrimSpace(text))) + 3) / 4
+}
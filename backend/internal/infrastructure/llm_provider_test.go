@@ -0,0 +1,176 @@
+package infrastructure_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+)
+
+// newOpenAIServer answers the nth chat completion request with statuses[n],
+// and with a reply once the statuses run out. It returns the request count.
+func newOpenAIServer(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&requests, 1)) - 1
+		if n < len(statuses) {
+			http.Error(w, http.StatusText(statuses[n]), statuses[n])
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"hello"}}],"usage":{"prompt_tokens":7,"completion_tokens":2,"total_tokens":9}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestManagedLLMProvider_RetriesRateLimitsAndServerErrors(t *testing.T) {
+	// Arrange
+	server, requests := newOpenAIServer(t, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	provider := infrastructure.NewManagedLLMProvider(
+		infrastructure.NewOpenAIProvider("key", server.URL, ""), time.Second, 2, time.Millisecond)
+
+	// Act
+	resp, err := provider.Generate(context.Background(), infrastructure.Prompt("hi"))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "hello", resp.Text)
+	assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+	assert.Equal(t, infrastructure.LLMUsageStats{
+		Calls:  1,
+		Tokens: infrastructure.TokenUsage{PromptTokens: 7, CompletionTokens: 2, TotalTokens: 9},
+	}, provider.Usage())
+}
+
+func TestManagedLLMProvider_DoesNotRetryClientErrors(t *testing.T) {
+	// Arrange
+	server, requests := newOpenAIServer(t, http.StatusBadRequest)
+	provider := infrastructure.NewManagedLLMProvider(
+		infrastructure.NewOpenAIProvider("key", server.URL, ""), time.Second, 3, time.Millisecond)
+
+	// Act
+	_, err := provider.Generate(context.Background(), infrastructure.Prompt("hi"))
+
+	// Assert
+	var statusErr *infrastructure.LLMStatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+	assert.Equal(t, infrastructure.LLMUsageStats{Calls: 1, Failures: 1}, provider.Usage())
+}
+
+func TestManagedLLMProvider_TimesOutEachAttempt(t *testing.T) {
+	// Arrange: the first request hangs, the second is answered
+	var requests int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"late but fine"}}]}`)
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	provider := infrastructure.NewManagedLLMProvider(
+		infrastructure.NewOpenAIProvider("", server.URL, ""), 50*time.Millisecond, 1, time.Millisecond)
+
+	// Act
+	start := time.Now()
+	resp, err := provider.Generate(context.Background(), infrastructure.Prompt("hi"))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "late but fine", resp.Text)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// flakyStreamer fails each stream with a temporary error after sending the
+// given number of deltas
+type flakyStreamer struct {
+	*infrastructure.FakeLLMProvider
+	deltasBeforeFailure int
+	attempts            int
+}
+
+func (p *flakyStreamer) Stream(ctx context.Context, req *infrastructure.LLMRequest, onDelta func(string) error) (*infrastructure.LLMResponse, error) {
+	p.attempts++
+	for i := 0; i < p.deltasBeforeFailure; i++ {
+		if err := onDelta("partial "); err != nil {
+			return nil, err
+		}
+	}
+	return nil, &infrastructure.LLMStatusError{Provider: "fake", StatusCode: http.StatusBadGateway}
+}
+
+func TestManagedLLMProvider_StreamRetriesOnlyBeforeFirstDelta(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		deltas           int
+		expectedAttempts int
+	}{
+		{name: "nothing delivered", deltas: 0, expectedAttempts: 3},
+		{name: "delta delivered", deltas: 1, expectedAttempts: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			streamer := &flakyStreamer{FakeLLMProvider: infrastructure.NewFakeLLMProvider(nil), deltasBeforeFailure: tc.deltas}
+			provider := infrastructure.NewManagedLLMProvider(streamer, time.Second, 2, time.Millisecond)
+			var received []string
+
+			// Act
+			_, err := provider.Stream(context.Background(), infrastructure.Prompt("hi"), func(delta string) error {
+				received = append(received, delta)
+				return nil
+			})
+
+			// Assert
+			var statusErr *infrastructure.LLMStatusError
+			require.ErrorAs(t, err, &statusErr)
+			assert.Equal(t, tc.expectedAttempts, streamer.attempts)
+			assert.Len(t, received, tc.deltas, "deltas are never delivered twice")
+			assert.Equal(t, infrastructure.LLMUsageStats{Calls: 1, Failures: 1}, provider.Usage())
+		})
+	}
+}
+
+func TestManagedLLMProvider_UsageTotalsCallsAndTokens(t *testing.T) {
+	// Arrange
+	fake := infrastructure.NewFakeLLMProvider(func(req *infrastructure.LLMRequest) (string, error) {
+		if req.Messages[0].Content == "fail" {
+			return "", errors.New("invalid prompt")
+		}
+		return "a fairly short reply", nil
+	})
+	provider := infrastructure.NewManagedLLMProvider(fake, time.Second, 2, time.Millisecond)
+
+	// Act
+	first, err := provider.Generate(context.Background(), infrastructure.Prompt("recommend a bike"))
+	require.NoError(t, err)
+	second, err := infrastructure.StreamLLM(context.Background(), provider, infrastructure.Prompt("and a helmet"), func(string) error { return nil })
+	require.NoError(t, err)
+	_, failErr := provider.Generate(context.Background(), infrastructure.Prompt("fail"))
+
+	// Assert
+	assert.Error(t, failErr)
+	usage := provider.Usage()
+	assert.Equal(t, 3, usage.Calls)
+	assert.Equal(t, 1, usage.Failures)
+	assert.Equal(t, first.Usage.PromptTokens+second.Usage.PromptTokens, usage.Tokens.PromptTokens)
+	assert.Equal(t, first.Usage.CompletionTokens+second.Usage.CompletionTokens, usage.Tokens.CompletionTokens)
+	assert.Equal(t, first.Usage.TotalTokens+second.Usage.TotalTokens, usage.Tokens.TotalTokens)
+	assert.NotZero(t, usage.Tokens.TotalTokens)
+}
@@ -1,6 +1,9 @@
 package infrastructure
 
 import (
+	"errors"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/yourusername/ecomate/backend/internal/domain"
 	"gorm.io/gorm"
@@ -71,9 +74,46 @@ func (r *messageRepository) GetUserConversations(userID uuid.UUID) ([]*domain.Co
 		return nil, err
 	}
 
+	if err := r.countUnread(conversations, userID); err != nil {
+		return nil, err
+	}
+
 	return conversations, nil
 }
 
+// countUnread sets how many messages of each conversation userID has not read
+func (r *messageRepository) countUnread(conversations []*domain.Conversation, userID uuid.UUID) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*domain.Conversation, len(conversations))
+	ids := make([]uuid.UUID, 0, len(conversations))
+	for _, conversation := range conversations {
+		byID[conversation.ID] = conversation
+		ids = append(ids, conversation.ID)
+	}
+
+	var counts []struct {
+		ConversationID uuid.UUID
+		Count          int64
+	}
+	if err := r.db.Model(&domain.Message{}).
+		Select("conversation_id, COUNT(*) AS count").
+		Where("conversation_id IN ? AND sender_id != ? AND is_read = ?", ids, userID, false).
+		Group("conversation_id").
+		Scan(&counts).Error; err != nil {
+		return err
+	}
+
+	for _, count := range counts {
+		if conversation := byID[count.ConversationID]; conversation != nil {
+			conversation.UnreadCount = count.Count
+		}
+	}
+	return nil
+}
+
 func (r *messageRepository) CreateMessage(message *domain.Message) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		// Create message
@@ -93,18 +133,16 @@ func (r *messageRepository) CreateMessage(message *domain.Message) error {
 	})
 }
 
-func (r *messageRepository) GetMessages(conversationID uuid.UUID, page, limit int) ([]*domain.Message, *domain.PaginationResponse, error) {
+// messageCursorSort names the only order messages are listed in
+const messageCursorSort = "created_desc"
+
+func (r *messageRepository) GetMessages(conversationID uuid.UUID, page, limit int, cursor string) ([]*domain.Message, *domain.PaginationResponse, error) {
 	var messages []*domain.Message
-	var total int64
 
 	query := r.db.Model(&domain.Message{}).
 		Where("conversation_id = ?", conversationID).
-		Preload("Sender")
-
-	// Count total
-	if err := query.Count(&total).Error; err != nil {
-		return nil, nil, err
-	}
+		Preload("Sender").
+		Preload("Attachments")
 
 	// Pagination
 	if page < 1 {
@@ -114,29 +152,91 @@ func (r *messageRepository) GetMessages(conversationID uuid.UUID, page, limit in
 		limit = 50
 	}
 
-	offset := (page - 1) * limit
-	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&messages).Error; err != nil {
-		return nil, nil, err
+	pagination := &domain.PaginationResponse{Limit: limit}
+	if cursor != "" {
+		before, err := domain.DecodePageCursor(cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		createdAt, err := time.Parse(time.RFC3339Nano, before.Value)
+		if before.Sort != messageCursorSort || err != nil {
+			return nil, nil, errors.New("invalid cursor")
+		}
+		query = query.Where("(created_at < ? OR (created_at = ? AND id < ?))", createdAt, createdAt, before.ID)
+	} else {
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			return nil, nil, err
+		}
+		pagination.Page = page
+		pagination.Total = int(total)
+		pagination.TotalPages = (int(total) + limit - 1) / limit
+		query = query.Offset((page - 1) * limit)
 	}
 
-	totalPages := int(total) / limit
-	if int(total)%limit > 0 {
-		totalPages++
+	// One extra row tells whether there are older messages
+	if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&messages).Error; err != nil {
+		return nil, nil, err
 	}
 
-	pagination := &domain.PaginationResponse{
-		Page:       page,
-		Limit:      limit,
-		Total:      int(total),
-		TotalPages: totalPages,
+	if len(messages) > limit {
+		messages = messages[:limit]
+		last := messages[limit-1]
+		next := &domain.PageCursor{Sort: messageCursorSort, Value: last.CreatedAt.Format(time.RFC3339Nano), ID: last.ID}
+		pagination.NextCursor = next.Encode()
 	}
 
 	return messages, pagination, nil
 }
 
-func (r *messageRepository) MarkAsRead(messageID, userID uuid.UUID) error {
-	return r.db.Model(&domain.Message{}).
-		Where("id = ? AND sender_id != ?", messageID, userID).
-		Update("is_read", true).
-		Error
+func (r *messageRepository) FindMessageByID(id uuid.UUID) (*domain.Message, error) {
+	var message domain.Message
+	if err := r.db.Where("id = ?", id).First(&message).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (r *messageRepository) FindAttachmentByID(id uuid.UUID) (*domain.MessageAttachment, error) {
+	var attachment domain.MessageAttachment
+	if err := r.db.Where("id = ?", id).First(&attachment).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *messageRepository) MarkAsRead(messageID, userID uuid.UUID) (bool, error) {
+	result := r.db.Model(&domain.Message{}).
+		Where("id = ? AND sender_id != ? AND is_read = ?", messageID, userID, false).
+		Update("is_read", true)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *messageRepository) MarkReadUpTo(conversationID, userID uuid.UUID, upTo time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Message{}).
+			Where("conversation_id = ? AND sender_id != ? AND is_read = ? AND created_at <= ?", conversationID, userID, false, upTo).
+			Order("created_at").
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		if len(ids) > 0 {
+			if err := tx.Model(&domain.Message{}).
+				Where("id IN ?", ids).
+				Update("is_read", true).Error; err != nil {
+				return err
+			}
+		}
+
+		// LastReadAt only moves forward
+		return tx.Model(&domain.ConversationParticipant{}).
+			Where("conversation_id = ? AND user_id = ? AND last_read_at < ?", conversationID, userID, upTo).
+			Update("last_read_at", upTo).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
 }
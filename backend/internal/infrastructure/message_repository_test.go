@@ -0,0 +1,95 @@
+package infrastructure_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+)
+
+func TestMessageRepository_GetMessages_Cursor(t *testing.T) {
+	// Arrange: messages sent in the same second are ordered by ID
+	db := newTestDB(t, &domain.User{}, &domain.Message{}, &domain.MessageAttachment{})
+	repo := infrastructure.NewMessageRepository(db)
+	conversationID := uuid.New()
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	messages := make([]*domain.Message, 8)
+	for i := range messages {
+		messages[i] = &domain.Message{
+			ID:             uuid.New(),
+			ConversationID: conversationID,
+			SenderID:       uuid.New(),
+			Content:        "hello",
+			CreatedAt:      start.Add(time.Duration(i/3) * time.Second),
+		}
+	}
+	require.NoError(t, db.Create(&messages).Error)
+	require.NoError(t, db.Create(&domain.Message{ID: uuid.New(), ConversationID: uuid.New(), SenderID: uuid.New(), Content: "elsewhere"}).Error)
+
+	newestFirst := append([]*domain.Message(nil), messages...)
+	sort.Slice(newestFirst, func(i, j int) bool {
+		a, b := newestFirst[i], newestFirst[j]
+		return a.CreatedAt.After(b.CreatedAt) || (a.CreatedAt.Equal(b.CreatedAt) && a.ID.String() > b.ID.String())
+	})
+	var expected []uuid.UUID
+	for _, m := range newestFirst {
+		expected = append(expected, m.ID)
+	}
+
+	t.Run("follows the cursor through every message", func(t *testing.T) {
+		// Act
+		var ids []uuid.UUID
+		cursor := ""
+		for pages := 0; pages < 10; pages++ {
+			page, pagination, err := repo.GetMessages(conversationID, 1, 3, cursor)
+			require.NoError(t, err)
+			for _, m := range page {
+				ids = append(ids, m.ID)
+			}
+			if cursor = pagination.NextCursor; cursor == "" {
+				break
+			}
+		}
+
+		// Assert
+		assert.Equal(t, expected, ids)
+	})
+
+	t.Run("pages and cursors agree", func(t *testing.T) {
+		// Act
+		_, first, err := repo.GetMessages(conversationID, 1, 3, "")
+		require.NoError(t, err)
+		second, _, err := repo.GetMessages(conversationID, 2, 3, "")
+		require.NoError(t, err)
+		afterFirst, pagination, err := repo.GetMessages(conversationID, 1, 3, first.NextCursor)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, 8, first.Total)
+		assert.Equal(t, 3, first.TotalPages)
+		assert.Equal(t, second, afterFirst)
+		assert.Zero(t, pagination.Total, "cursors skip counting")
+	})
+
+	for _, tc := range []struct {
+		name   string
+		cursor string
+	}{
+		{name: "rejects a cursor of another sort order", cursor: (&domain.PageCursor{Sort: "price_asc", Value: "1000", ID: uuid.New()}).Encode()},
+		{name: "rejects a cursor without a time", cursor: (&domain.PageCursor{Sort: "created_desc", Value: "1000", ID: uuid.New()}).Encode()},
+		{name: "rejects a cursor that does not decode", cursor: "%%%"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			_, _, err := repo.GetMessages(conversationID, 1, 3, tc.cursor)
+
+			// Assert
+			assert.EqualError(t, err, "invalid cursor")
+		})
+	}
+}
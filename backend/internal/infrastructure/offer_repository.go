@@ -1,9 +1,12 @@
 package infrastructure
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/yourusername/ecomate/backend/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type offerRepository struct {
@@ -21,10 +24,22 @@ func (r *offerRepository) Create(offer *domain.Offer) error {
 func (r *offerRepository) FindByID(id uuid.UUID) (*domain.Offer, error) {
 	var offer domain.Offer
 	err := r.db.Preload("Product").Preload("Product.Seller").Preload("Buyer").
+		Preload("Rounds", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
 		First(&offer, "id = ?", id).Error
 	return &offer, err
 }
 
+func (r *offerRepository) FindByIDForUpdate(id uuid.UUID) (*domain.Offer, error) {
+	var offer domain.Offer
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&offer, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
 func (r *offerRepository) FindByProductID(productID uuid.UUID) ([]*domain.Offer, error) {
 	var offers []*domain.Offer
 	err := r.db.Preload("Buyer").
@@ -53,6 +68,52 @@ func (r *offerRepository) FindBySellerID(sellerID uuid.UUID) ([]*domain.Offer, e
 	return offers, err
 }
 
+// FindPendingByProductID locks every pending offer on the product
+func (r *offerRepository) FindPendingByProductID(productID uuid.UUID) ([]*domain.Offer, error) {
+	var offers []*domain.Offer
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND status = ?", productID, domain.OfferStatusPending).
+		Order("created_at ASC").
+		Find(&offers).Error
+	return offers, err
+}
+
+func (r *offerRepository) FindExpired(now time.Time, limit int) ([]*domain.Offer, error) {
+	var offers []*domain.Offer
+	err := r.db.
+		Where("status = ? AND expires_at <= ?", domain.OfferStatusPending, now).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&offers).Error
+	return offers, err
+}
+
+// FindLapsedReservations lists accepted offers whose reservation window has passed
+func (r *offerRepository) FindLapsedReservations(now time.Time, limit int) ([]*domain.Offer, error) {
+	var offers []*domain.Offer
+	err := r.db.
+		Where("status = ? AND reserved_until <= ?", domain.OfferStatusAccepted, now).
+		Order("reserved_until ASC").
+		Limit(limit).
+		Find(&offers).Error
+	return offers, err
+}
+
+func (r *offerRepository) FindAcceptedPriceRatios(category string, limit int) ([]float64, error) {
+	var ratios []float64
+	err := r.db.Model(&domain.Offer{}).
+		Joins("JOIN products ON products.id = offers.product_id").
+		Where("products.category = ? AND offers.status = ? AND products.price > 0", category, domain.OfferStatusAccepted).
+		Order("offers.responded_at DESC").
+		Limit(limit).
+		Pluck("offers.offer_price / products.price", &ratios).Error
+	return ratios, err
+}
+
+func (r *offerRepository) AddRound(round *domain.OfferRound) error {
+	return r.db.Create(round).Error
+}
+
 func (r *offerRepository) Update(offer *domain.Offer) error {
-	return r.db.Save(offer).Error
+	return r.db.Omit(clause.Associations).Save(offer).Error
 }
@@ -0,0 +1,241 @@
+package infrastructure
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/config"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type escrowState string
+
+const (
+	escrowHeld     escrowState = "held"
+	escrowReleased escrowState = "released"
+	escrowRefunded escrowState = "refunded"
+)
+
+// NewPaymentProvider builds the escrow provider selected in cfg. Escrow kept
+// in memory is lost on restart and invisible to other replicas, so it is
+// refused outside development.
+func NewPaymentProvider(cfg *config.PaymentConfig, env string, db *gorm.DB) (domain.PaymentProvider, error) {
+	switch cfg.Provider {
+	case "", "database":
+		return NewSQLPaymentProvider(db)
+	case "memory":
+		if env != "development" {
+			return nil, fmt.Errorf("the memory payment provider is for development only, not %s", env)
+		}
+		return NewLocalPaymentProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider: %s", cfg.Provider)
+	}
+}
+
+type escrowEntry struct {
+	purchaseID uuid.UUID
+	amount     int
+	withheld   int
+	state      escrowState
+}
+
+// LocalPaymentProvider keeps escrow balances in memory (for development and tests)
+type LocalPaymentProvider struct {
+	mu         sync.Mutex
+	entries    map[string]*escrowEntry
+	byPurchase map[uuid.UUID]string
+}
+
+func NewLocalPaymentProvider() *LocalPaymentProvider {
+	return &LocalPaymentProvider{
+		entries:    make(map[string]*escrowEntry),
+		byPurchase: make(map[uuid.UUID]string),
+	}
+}
+
+var _ domain.PaymentProvider = (*LocalPaymentProvider)(nil)
+
+func (p *LocalPaymentProvider) Hold(purchaseID uuid.UUID, amount int, method string) (string, error) {
+	if amount <= 0 {
+		return "", fmt.Errorf("invalid amount: %d", amount)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// A retried hold returns the escrow already taken for the purchase
+	if ref, ok := p.byPurchase[purchaseID]; ok {
+		if p.entries[ref].amount != amount {
+			return "", fmt.Errorf("purchase %s is already held for a different amount", purchaseID)
+		}
+		return ref, nil
+	}
+
+	ref := "local_" + uuid.New().String()
+	p.byPurchase[purchaseID] = ref
+	p.entries[ref] = &escrowEntry{
+		purchaseID: purchaseID,
+		amount:     amount,
+		state:      escrowHeld,
+	}
+	return ref, nil
+}
+
+func (p *LocalPaymentProvider) Release(ref string, withheld int) error {
+	return p.settle(ref, escrowReleased, withheld)
+}
+
+func (p *LocalPaymentProvider) Refund(ref string) error {
+	return p.settle(ref, escrowRefunded, 0)
+}
+
+// State returns the escrow state for ref, or "" if it is unknown
+func (p *LocalPaymentProvider) State(ref string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if entry, ok := p.entries[ref]; ok {
+		return string(entry.state)
+	}
+	return ""
+}
+
+// Withheld returns how much of a released escrow was kept from the seller
+func (p *LocalPaymentProvider) Withheld(ref string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if entry, ok := p.entries[ref]; ok {
+		return entry.withheld
+	}
+	return 0
+}
+
+func (p *LocalPaymentProvider) settle(ref string, to escrowState, withheld int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.entries[ref]
+	if !ok {
+		return fmt.Errorf("unknown payment reference: %s", ref)
+	}
+	if entry.state == to {
+		return nil
+	}
+	if entry.state != escrowHeld {
+		return fmt.Errorf("payment %s already %s", ref, entry.state)
+	}
+	if withheld < 0 || withheld > entry.amount {
+		return fmt.Errorf("invalid withheld amount: %d", withheld)
+	}
+	entry.state = to
+	entry.withheld = withheld
+	return nil
+}
+
+// escrowPayment is the ledger row of one purchase's escrow
+type escrowPayment struct {
+	Reference  string    `gorm:"type:varchar(64);primaryKey"`
+	PurchaseID uuid.UUID `gorm:"type:char(36);uniqueIndex;not null"`
+	Amount     int       `gorm:"not null"`
+	Method     string    `gorm:"type:varchar(32)"`
+	Withheld   int       `gorm:"default:0"`
+	State      string    `gorm:"type:varchar(16);not null"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (escrowPayment) TableName() string {
+	return "escrow_payments"
+}
+
+// SQLPaymentProvider keeps the escrow ledger in the database, so a payment
+// held by one replica can be released or refunded by any other and survives
+// restarts
+type SQLPaymentProvider struct {
+	db *gorm.DB
+}
+
+func NewSQLPaymentProvider(db *gorm.DB) (*SQLPaymentProvider, error) {
+	if err := db.AutoMigrate(&escrowPayment{}); err != nil {
+		return nil, err
+	}
+	return &SQLPaymentProvider{db: db}, nil
+}
+
+var _ domain.PaymentProvider = (*SQLPaymentProvider)(nil)
+
+func (p *SQLPaymentProvider) Hold(purchaseID uuid.UUID, amount int, method string) (string, error) {
+	if amount <= 0 {
+		return "", fmt.Errorf("invalid amount: %d", amount)
+	}
+
+	// The unique purchase ID makes a retried hold find the escrow already
+	// taken, even when another replica took it
+	err := p.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&escrowPayment{
+		Reference:  "escrow_" + uuid.New().String(),
+		PurchaseID: purchaseID,
+		Amount:     amount,
+		Method:     method,
+		State:      string(escrowHeld),
+	}).Error
+	if err != nil {
+		return "", err
+	}
+
+	var payment escrowPayment
+	if err := p.db.Where("purchase_id = ?", purchaseID).First(&payment).Error; err != nil {
+		return "", err
+	}
+	if payment.Amount != amount {
+		return "", fmt.Errorf("purchase %s is already held for a different amount", purchaseID)
+	}
+	return payment.Reference, nil
+}
+
+func (p *SQLPaymentProvider) Release(ref string, withheld int) error {
+	return p.settle(ref, escrowReleased, withheld)
+}
+
+func (p *SQLPaymentProvider) Refund(ref string) error {
+	return p.settle(ref, escrowRefunded, 0)
+}
+
+func (p *SQLPaymentProvider) settle(ref string, to escrowState, withheld int) error {
+	if withheld < 0 {
+		return fmt.Errorf("invalid withheld amount: %d", withheld)
+	}
+
+	// Only held escrow moves, so concurrent settlements cannot both succeed
+	result := p.db.Model(&escrowPayment{}).
+		Where("reference = ? AND state = ? AND amount >= ?", ref, escrowHeld, withheld).
+		Updates(map[string]interface{}{"state": string(to), "withheld": withheld})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	var payment escrowPayment
+	if err := p.db.Where("reference = ?", ref).First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("unknown payment reference: %s", ref)
+		}
+		return err
+	}
+	switch escrowState(payment.State) {
+	case to:
+		return nil
+	case escrowHeld:
+		return fmt.Errorf("invalid withheld amount: %d", withheld)
+	default:
+		return fmt.Errorf("payment %s already %s", ref, payment.State)
+	}
+}
@@ -0,0 +1,83 @@
+package infrastructure_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ecomate/backend/internal/config"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+)
+
+func TestSQLPaymentProvider_SharesEscrowAcrossReplicas(t *testing.T) {
+	// Arrange: two replicas on one database
+	db := newTestDB(t)
+	replicaA, err := infrastructure.NewSQLPaymentProvider(db)
+	require.NoError(t, err)
+	replicaB, err := infrastructure.NewSQLPaymentProvider(db)
+	require.NoError(t, err)
+	purchaseID := uuid.New()
+
+	// Act
+	ref, err := replicaA.Hold(purchaseID, 5000, "card")
+	require.NoError(t, err)
+	retried, retryErr := replicaB.Hold(purchaseID, 5000, "card")
+	_, differentErr := replicaB.Hold(purchaseID, 4000, "card")
+	releaseErr := replicaB.Release(ref, 500)
+	releaseAgainErr := replicaA.Release(ref, 500)
+	refundErr := replicaA.Refund(ref)
+
+	// Assert
+	require.NoError(t, retryErr)
+	assert.Equal(t, ref, retried, "a retried hold returns the same escrow")
+	assert.EqualError(t, differentErr, "purchase "+purchaseID.String()+" is already held for a different amount")
+	assert.NoError(t, releaseErr, "escrow held by one replica is released by another")
+	assert.NoError(t, releaseAgainErr, "a retried release is a no-op")
+	assert.EqualError(t, refundErr, "payment "+ref+" already released")
+}
+
+func TestSQLPaymentProvider_RejectsBadSettlements(t *testing.T) {
+	// Arrange
+	provider, err := infrastructure.NewSQLPaymentProvider(newTestDB(t))
+	require.NoError(t, err)
+	ref, err := provider.Hold(uuid.New(), 1000, "card")
+	require.NoError(t, err)
+
+	// Act & Assert
+	assert.EqualError(t, provider.Release("escrow_missing", 0), "unknown payment reference: escrow_missing")
+	assert.EqualError(t, provider.Release(ref, 2000), "invalid withheld amount: 2000")
+	assert.EqualError(t, provider.Release(ref, -1), "invalid withheld amount: -1")
+	assert.NoError(t, provider.Refund(ref))
+	assert.NoError(t, provider.Refund(ref))
+	assert.EqualError(t, provider.Release(ref, 0), "payment "+ref+" already refunded")
+}
+
+func TestNewPaymentProvider(t *testing.T) {
+	db := newTestDB(t)
+
+	for _, tc := range []struct {
+		name     string
+		provider string
+		env      string
+		expected string
+	}{
+		{name: "database by default", env: "production"},
+		{name: "memory in development", provider: "memory", env: "development"},
+		{name: "memory outside development", provider: "memory", env: "production", expected: "the memory payment provider is for development only, not production"},
+		{name: "unknown", provider: "stripe", env: "development", expected: "unknown payment provider: stripe"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			provider, err := infrastructure.NewPaymentProvider(&config.PaymentConfig{Provider: tc.provider}, tc.env, db)
+
+			// Assert
+			if tc.expected != "" {
+				assert.EqualError(t, err, tc.expected)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, provider)
+		})
+	}
+}
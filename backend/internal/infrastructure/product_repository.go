@@ -1,9 +1,16 @@
 package infrastructure
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/yourusername/ecomate/backend/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type productRepository struct {
@@ -26,27 +33,216 @@ func (r *productRepository) FindByID(id uuid.UUID) (*domain.Product, error) {
 	return &product, nil
 }
 
+func (r *productRepository) FindByIDs(ids []uuid.UUID) ([]*domain.Product, error) {
+	var products []*domain.Product
+	if len(ids) == 0 {
+		return products, nil
+	}
+	if err := r.db.Preload("Seller").Preload("Images").Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *productRepository) FindByIDForUpdate(id uuid.UUID) (*domain.Product, error) {
+	var product domain.Product
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&product).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// productSortKey is a sort order of listings and how to keep a cursor in it
+type productSortKey struct {
+	column string
+	desc   bool
+	value  func(p *domain.Product) string
+	parse  func(value string) (interface{}, error)
+}
+
+var productSortKeys = map[string]productSortKey{
+	"price_asc": {
+		column: "products.price",
+		value:  func(p *domain.Product) string { return strconv.Itoa(p.Price) },
+		parse:  func(v string) (interface{}, error) { return strconv.Atoi(v) },
+	},
+	"price_desc": {
+		column: "products.price",
+		desc:   true,
+		value:  func(p *domain.Product) string { return strconv.Itoa(p.Price) },
+		parse:  func(v string) (interface{}, error) { return strconv.Atoi(v) },
+	},
+	"eco_impact_desc": {
+		column: "products.co2_impact_kg",
+		desc:   true,
+		value:  func(p *domain.Product) string { return strconv.FormatFloat(p.CO2ImpactKg, 'f', -1, 64) },
+		parse:  func(v string) (interface{}, error) { return strconv.ParseFloat(v, 64) },
+	},
+	"created_desc": {
+		column: "products.created_at",
+		desc:   true,
+		value:  func(p *domain.Product) string { return p.CreatedAt.Format(time.RFC3339Nano) },
+		parse:  func(v string) (interface{}, error) { return time.Parse(time.RFC3339Nano, v) },
+	},
+}
+
+// relevanceSort orders search results as filters.IDs lists them
+const relevanceSort = "relevance"
+
 func (r *productRepository) List(filters *domain.ProductFilters) ([]*domain.Product, *domain.PaginationResponse, error) {
 	var products []*domain.Product
-	var total int64
+
+	page := filters.Page
+	if page < 1 {
+		page = 1
+	}
+	limit := filters.Limit
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	sort := filters.Sort
+	if sort == "" && len(filters.IDs) > 0 {
+		sort = relevanceSort
+	} else if _, ok := productSortKeys[sort]; !ok {
+		sort = "created_desc"
+	}
+
+	var cursor *domain.PageCursor
+	if filters.Cursor != "" {
+		var err error
+		if cursor, err = domain.DecodePageCursor(filters.Cursor); err != nil {
+			return nil, nil, err
+		}
+		if cursor.Sort != sort {
+			return nil, nil, errors.New("cursor does not match the sort order")
+		}
+	}
+
+	scoped := *filters
+	if sort == relevanceSort && cursor != nil {
+		// The cursor is the score and ID of the last product; the products
+		// ranked after it are left
+		score, err := strconv.ParseFloat(cursor.Value, 64)
+		if err != nil {
+			return nil, nil, errors.New("invalid cursor")
+		}
+		scoped.IDs = make([]uuid.UUID, 0, len(filters.IDs))
+		for i, id := range filters.IDs {
+			s := relevanceScore(filters, i)
+			if s < score || (s == score && id.String() > cursor.ID.String()) {
+				scoped.IDs = append(scoped.IDs, id)
+			}
+		}
+	}
 
 	query := r.db.Model(&domain.Product{}).
 		Preload("Seller").
 		Preload("Images").
 		Where("status = ?", domain.StatusActive)
 
-	// Apply filters
-	if filters.Category != "" {
-		query = query.Where("category = ?", filters.Category)
+	query = filterProducts(query, &scoped)
+
+	pagination := &domain.PaginationResponse{Limit: limit}
+	if cursor == nil {
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			return nil, nil, err
+		}
+		pagination.Page = page
+		pagination.Total = int(total)
+		pagination.TotalPages = (int(total) + limit - 1) / limit
+		query = query.Offset((page - 1) * limit)
+	}
+
+	key := productSortKeys[sort]
+	if sort == relevanceSort {
+		if len(scoped.IDs) > 0 {
+			query = query.Clauses(clause.OrderBy{
+				Expression: clause.Expr{SQL: "FIELD(products.id, ?)", Vars: []interface{}{scoped.IDs}, WithoutParentheses: true},
+			})
+		}
+	} else {
+		dir, op := "ASC", ">"
+		if key.desc {
+			dir, op = "DESC", "<"
+		}
+		if cursor != nil {
+			value, err := key.parse(cursor.Value)
+			if err != nil {
+				return nil, nil, errors.New("invalid cursor")
+			}
+			query = query.Where(
+				fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND products.id %[2]s ?))", key.column, op),
+				value, value, cursor.ID,
+			)
+		}
+		query = query.Order(key.column + " " + dir + ", products.id " + dir)
+	}
+
+	// One extra row tells whether there is another page
+	if err := query.Limit(limit + 1).Find(&products).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if len(products) > limit {
+		products = products[:limit]
+		last := products[limit-1]
+		next := &domain.PageCursor{Sort: sort, ID: last.ID}
+		if sort == relevanceSort {
+			for i, id := range filters.IDs {
+				if id == last.ID {
+					next.Value = strconv.FormatFloat(relevanceScore(filters, i), 'g', -1, 64)
+					break
+				}
+			}
+		} else {
+			next.Value = key.value(last)
+		}
+		pagination.NextCursor = next.Encode()
+	}
+
+	return products, pagination, nil
+}
+
+// relevanceScore is the score of the ith of filters.IDs; without scores the
+// IDs rank by position
+func relevanceScore(filters *domain.ProductFilters, i int) float64 {
+	if i < len(filters.Scores) {
+		return filters.Scores[i]
+	}
+	return -float64(i)
+}
+
+// sellerRatingsJoin adds each seller's average review rating as seller_ratings.rating
+const sellerRatingsJoin = "LEFT JOIN (SELECT p.seller_id, AVG(rv.rating) AS rating FROM reviews rv " +
+	"JOIN products p ON p.id = rv.product_id GROUP BY p.seller_id) seller_ratings ON seller_ratings.seller_id = products.seller_id"
+
+func filterProducts(query *gorm.DB, filters *domain.ProductFilters) *gorm.DB {
+	if filters.IDs != nil {
+		if len(filters.IDs) == 0 {
+			return query.Where("1 = 0")
+		}
+		query = query.Where("products.id IN ?", filters.IDs)
+	}
+	if categories := filters.CategoryValues(); len(categories) > 0 {
+		query = query.Where("products.category IN ?", categories)
 	}
 	if filters.MinPrice > 0 {
-		query = query.Where("price >= ?", filters.MinPrice)
+		query = query.Where("products.price >= ?", filters.MinPrice)
 	}
 	if filters.MaxPrice > 0 {
-		query = query.Where("price <= ?", filters.MaxPrice)
+		query = query.Where("products.price <= ?", filters.MaxPrice)
+	}
+	if conditions := filters.ConditionValues(); len(conditions) > 0 {
+		query = query.Where("products.condition IN ?", conditions)
 	}
-	if filters.Condition != "" {
-		query = query.Where("condition = ?", filters.Condition)
+	if filters.MinSellerRating > 0 {
+		query = query.Where("products.seller_id IN (SELECT p.seller_id FROM reviews rv JOIN products p ON p.id = rv.product_id "+
+			"GROUP BY p.seller_id HAVING AVG(rv.rating) >= ?)", filters.MinSellerRating)
 	}
 	if filters.Search != "" {
 		query = query.Where(
@@ -54,55 +250,68 @@ func (r *productRepository) List(filters *domain.ProductFilters) ([]*domain.Prod
 			filters.Search,
 		)
 	}
+	return query
+}
 
-	// Count total
-	if err := query.Count(&total).Error; err != nil {
-		return nil, nil, err
+func (r *productRepository) CountFacets(filters *domain.ProductFilters) ([]*domain.ProductFacetCount, error) {
+	priceOK, priceArgs := "TRUE", []interface{}{}
+	switch {
+	case filters.MinPrice > 0 && filters.MaxPrice > 0:
+		priceOK, priceArgs = "products.price BETWEEN ? AND ?", []interface{}{filters.MinPrice, filters.MaxPrice}
+	case filters.MinPrice > 0:
+		priceOK, priceArgs = "products.price >= ?", []interface{}{filters.MinPrice}
+	case filters.MaxPrice > 0:
+		priceOK, priceArgs = "products.price <= ?", []interface{}{filters.MaxPrice}
 	}
-
-	// Apply sorting
-	switch filters.Sort {
-	case "price_asc":
-		query = query.Order("price ASC")
-	case "price_desc":
-		query = query.Order("price DESC")
-	case "eco_impact_desc":
-		query = query.Order("co2_impact_kg DESC")
-	default:
-		query = query.Order("created_at DESC")
+	ratingOK, ratingArgs := "TRUE", []interface{}{}
+	if filters.MinSellerRating > 0 {
+		ratingOK, ratingArgs = "COALESCE(seller_ratings.rating, 0) >= ?", []interface{}{filters.MinSellerRating}
 	}
 
-	// Apply pagination
-	page := filters.Page
-	if page < 1 {
-		page = 1
-	}
-	limit := filters.Limit
-	if limit < 1 {
-		limit = 20
-	}
-	if limit > 100 {
-		limit = 100
-	}
+	columns := strings.Join([]string{
+		"products.category AS category",
+		"products.condition AS `condition`",
+		facetBucketSQL("products.price", domain.PriceFacetRanges) + " AS price_bucket",
+		facetBucketSQL("products.co2_impact_kg", domain.CO2FacetRanges) + " AS co2_bucket",
+		"FLOOR(COALESCE(seller_ratings.rating, 0)) AS rating_floor",
+		"(" + priceOK + ") AS price_ok",
+		"(" + ratingOK + ") AS rating_ok",
+		"COUNT(*) AS count",
+	}, ", ")
 
-	offset := (page - 1) * limit
-	if err := query.Offset(offset).Limit(limit).Find(&products).Error; err != nil {
-		return nil, nil, err
+	query := r.db.Model(&domain.Product{}).
+		Select(columns, append(priceArgs, ratingArgs...)...).
+		Joins(sellerRatingsJoin).
+		Where("products.status = ?", domain.StatusActive)
+	if filters.IDs != nil {
+		if len(filters.IDs) == 0 {
+			return nil, nil
+		}
+		query = query.Where("products.id IN ?", filters.IDs)
 	}
-
-	totalPages := int(total) / limit
-	if int(total)%limit > 0 {
-		totalPages++
+	if filters.Search != "" {
+		query = query.Where(
+			"MATCH(products.title, products.description) AGAINST (? IN NATURAL LANGUAGE MODE)",
+			filters.Search,
+		)
 	}
 
-	pagination := &domain.PaginationResponse{
-		Page:       page,
-		Limit:      limit,
-		Total:      int(total),
-		TotalPages: totalPages,
-	}
+	var counts []*domain.ProductFacetCount
+	err := query.
+		Group("category, `condition`, price_bucket, co2_bucket, rating_floor, price_ok, rating_ok").
+		Scan(&counts).Error
+	return counts, err
+}
 
-	return products, pagination, nil
+// facetBucketSQL numbers the range each value of column falls into
+func facetBucketSQL(column string, ranges []domain.FacetRange) string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for i, rg := range ranges[:len(ranges)-1] {
+		fmt.Fprintf(&b, " WHEN %s < %g THEN %d", column, rg.Max, i)
+	}
+	fmt.Fprintf(&b, " ELSE %d END", len(ranges)-1)
+	return b.String()
 }
 
 func (r *productRepository) Update(product *domain.Product) error {
@@ -122,3 +331,15 @@ func (r *productRepository) IncrementViewCount(id uuid.UUID) error {
 		Update("view_count", gorm.Expr("view_count + 1")).
 		Error
 }
+
+func (r *productRepository) FindRecentSoldPrices(category string, limit int) ([]int, error) {
+	var prices []int
+	err := r.db.Model(&domain.Purchase{}).
+		Joins("JOIN products ON products.id = purchases.product_id").
+		Where("products.category = ?", category).
+		Where("purchases.status NOT IN ?", []domain.PurchaseStatus{domain.PurchaseStatusCancelled, domain.PurchaseStatusRefunding, domain.PurchaseStatusRefunded}).
+		Order("purchases.created_at DESC").
+		Limit(limit).
+		Pluck("purchases.price", &prices).Error
+	return prices, err
+}
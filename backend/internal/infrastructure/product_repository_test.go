@@ -0,0 +1,249 @@
+package infrastructure_test
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var registerSQLite sync.Once
+
+// newTestDB opens an empty SQLite database with the tables of models. It
+// has MySQL's FIELD, which orders search results.
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	registerSQLite.Do(func() {
+		sql.Register("sqlite3_mysql", &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				return conn.RegisterFunc("field", func(value string, list ...string) int {
+					for i, item := range list {
+						if item == value {
+							return i + 1
+						}
+					}
+					return 0
+				}, true)
+			},
+		})
+	})
+
+	db, err := gorm.Open(sqlite.Dialector{
+		DriverName: "sqlite3_mysql",
+		DSN:        filepath.Join(t.TempDir(), "test.db"),
+	}, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(models...))
+	return db
+}
+
+// seedProducts stores n active products whose prices, CO2 savings and
+// creation times repeat, so every sort order has ties
+func seedProducts(t *testing.T, db *gorm.DB, n int) []*domain.Product {
+	seller := &domain.User{ID: uuid.New(), Email: "seller@example.com", Username: "seller", PasswordHash: "x"}
+	require.NoError(t, db.Create(seller).Error)
+
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	products := make([]*domain.Product, n)
+	for i := range products {
+		products[i] = &domain.Product{
+			ID:          uuid.New(),
+			SellerID:    seller.ID,
+			Title:       fmt.Sprintf("Item %d", i),
+			Price:       1000 * (i % 3),
+			Category:    "furniture",
+			Condition:   domain.ConditionGood,
+			Status:      domain.StatusActive,
+			CO2ImpactKg: float64(i%2) + 0.5,
+			CreatedAt:   start.Add(time.Duration(i%4) * time.Hour),
+		}
+	}
+	require.NoError(t, db.Create(&products).Error)
+	return products
+}
+
+func productIDs(products []*domain.Product) []uuid.UUID {
+	ids := make([]uuid.UUID, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+// listAll follows NextCursor from the first page to the last
+func listAll(t *testing.T, repo domain.ProductRepository, filters domain.ProductFilters) []uuid.UUID {
+	var ids []uuid.UUID
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 100, "pagination does not end")
+		products, pagination, err := repo.List(&filters)
+		require.NoError(t, err)
+		ids = append(ids, productIDs(products)...)
+		if pagination.NextCursor == "" {
+			return ids
+		}
+		filters.Cursor = pagination.NextCursor
+	}
+}
+
+func TestProductRepository_List_CursorFollowsEverySortOrder(t *testing.T) {
+	db := newTestDB(t, &domain.User{}, &domain.Product{}, &domain.ProductImage{})
+	products := seedProducts(t, db, 11)
+	repo := infrastructure.NewProductRepository(db)
+
+	for _, tc := range []struct {
+		sort string
+		less func(a, b *domain.Product) bool
+	}{
+		{sort: "price_asc", less: func(a, b *domain.Product) bool {
+			return a.Price < b.Price || (a.Price == b.Price && a.ID.String() < b.ID.String())
+		}},
+		{sort: "price_desc", less: func(a, b *domain.Product) bool {
+			return a.Price > b.Price || (a.Price == b.Price && a.ID.String() > b.ID.String())
+		}},
+		{sort: "eco_impact_desc", less: func(a, b *domain.Product) bool {
+			return a.CO2ImpactKg > b.CO2ImpactKg || (a.CO2ImpactKg == b.CO2ImpactKg && a.ID.String() > b.ID.String())
+		}},
+		{sort: "created_desc", less: func(a, b *domain.Product) bool {
+			return a.CreatedAt.After(b.CreatedAt) || (a.CreatedAt.Equal(b.CreatedAt) && a.ID.String() > b.ID.String())
+		}},
+	} {
+		t.Run(tc.sort, func(t *testing.T) {
+			// Arrange: ties in the sort column are broken by ID
+			expected := append([]*domain.Product(nil), products...)
+			sort.Slice(expected, func(i, j int) bool { return tc.less(expected[i], expected[j]) })
+
+			// Act
+			ids := listAll(t, repo, domain.ProductFilters{Sort: tc.sort, Limit: 3})
+
+			// Assert
+			assert.Equal(t, productIDs(expected), ids)
+		})
+	}
+}
+
+func TestProductRepository_List_PagesAndCursorsAgree(t *testing.T) {
+	// Arrange
+	db := newTestDB(t, &domain.User{}, &domain.Product{}, &domain.ProductImage{})
+	seedProducts(t, db, 7)
+	repo := infrastructure.NewProductRepository(db)
+
+	// Act
+	first, firstPagination, err := repo.List(&domain.ProductFilters{Sort: "price_asc", Page: 1, Limit: 3})
+	require.NoError(t, err)
+	second, secondPagination, err := repo.List(&domain.ProductFilters{Sort: "price_asc", Page: 2, Limit: 3})
+	require.NoError(t, err)
+	afterFirst, cursorPagination, err := repo.List(&domain.ProductFilters{Sort: "price_asc", Limit: 3, Cursor: firstPagination.NextCursor})
+	require.NoError(t, err)
+
+	// Assert
+	assert.Len(t, first, 3)
+	assert.Equal(t, 7, firstPagination.Total)
+	assert.Equal(t, 3, firstPagination.TotalPages)
+	assert.NotEmpty(t, firstPagination.NextCursor, "pages hand out cursors too")
+	assert.Equal(t, productIDs(second), productIDs(afterFirst))
+	assert.Equal(t, secondPagination.NextCursor, cursorPagination.NextCursor)
+	assert.Zero(t, cursorPagination.Total, "cursors skip counting")
+	assert.Zero(t, cursorPagination.Page)
+}
+
+func TestProductRepository_List_RejectsBadCursors(t *testing.T) {
+	db := newTestDB(t, &domain.User{}, &domain.Product{}, &domain.ProductImage{})
+	seedProducts(t, db, 4)
+	repo := infrastructure.NewProductRepository(db)
+	_, pagination, err := repo.List(&domain.ProductFilters{Sort: "price_asc", Limit: 2})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		filters  domain.ProductFilters
+		expected string
+	}{
+		{
+			name:     "another sort order",
+			filters:  domain.ProductFilters{Sort: "price_desc", Cursor: pagination.NextCursor},
+			expected: "cursor does not match the sort order",
+		},
+		{
+			name:     "relevance for a sort order",
+			filters:  domain.ProductFilters{IDs: []uuid.UUID{uuid.New()}, Cursor: pagination.NextCursor},
+			expected: "cursor does not match the sort order",
+		},
+		{
+			name:     "not base64",
+			filters:  domain.ProductFilters{Sort: "price_asc", Cursor: "not a cursor!"},
+			expected: "invalid cursor",
+		},
+		{
+			name:     "no ID",
+			filters:  domain.ProductFilters{Sort: "price_asc", Cursor: (&domain.PageCursor{Sort: "price_asc", Value: "1000"}).Encode()},
+			expected: "invalid cursor",
+		},
+		{
+			name:     "value of the wrong type",
+			filters:  domain.ProductFilters{Sort: "price_asc", Cursor: (&domain.PageCursor{Sort: "price_asc", Value: "cheap", ID: uuid.New()}).Encode()},
+			expected: "invalid cursor",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			_, _, err := repo.List(&tc.filters)
+
+			// Assert
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestPageCursor_EncodeDecode(t *testing.T) {
+	// Arrange
+	cursor := &domain.PageCursor{Sort: "created_desc", Value: "2024-05-01T09:00:00.123456789Z", ID: uuid.New()}
+
+	// Act
+	decoded, err := domain.DecodePageCursor(cursor.Encode())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, cursor, decoded)
+	assert.NotContains(t, cursor.Encode(), "=", "cursors are safe in URLs")
+}
+
+func TestProductRepository_List_RelevanceCursorSurvivesRankingChanges(t *testing.T) {
+	// Arrange: the search index ranks the products by score, ties by ID
+	db := newTestDB(t, &domain.User{}, &domain.Product{}, &domain.ProductImage{})
+	products := seedProducts(t, db, 8)
+	repo := infrastructure.NewProductRepository(db)
+	scores := []float64{5, 4, 4, 4, 3, 2, 1.5, 1}
+	ranked := append([]*domain.Product(nil), products...)
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].ID.String() < ranked[j].ID.String() })
+	filters := domain.ProductFilters{IDs: productIDs(ranked), Scores: scores, Limit: 3}
+
+	// Act
+	first, pagination, err := repo.List(&filters)
+	require.NoError(t, err)
+
+	// Before the next page a new listing ranks first and the last one
+	// drops out of the results
+	newcomer := &domain.Product{ID: uuid.New(), SellerID: products[0].SellerID, Title: "New", Category: "furniture",
+		Condition: domain.ConditionGood, Status: domain.StatusActive}
+	require.NoError(t, db.Create(newcomer).Error)
+	filters.IDs = append([]uuid.UUID{newcomer.ID}, productIDs(ranked[:7])...)
+	filters.Scores = append([]float64{9}, scores[:7]...)
+	filters.Cursor = pagination.NextCursor
+	rest := listAll(t, repo, filters)
+
+	// Assert
+	assert.Equal(t, productIDs(ranked[:3]), productIDs(first))
+	assert.Equal(t, productIDs(ranked[3:7]), rest, "no product is skipped or repeated")
+}
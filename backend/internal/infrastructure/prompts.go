@@ -0,0 +1,160 @@
+package infrastructure
+
+import (
+	"strings"
+	"text/template"
+)
+
+// Prompt template names. Every prompt sent to an LLMProvider is defined here.
+const (
+	PromptChatSystem      = "chat_system"
+	PromptNegotiation     = "negotiation"
+	PromptTranslateSearch = "translate_search"
+	PromptChatRetrieval   = "chat_retrieval"
+	PromptChatAgent       = "chat_agent"
+)
+
+// NegotiationPromptData fills the negotiation template
+type NegotiationPromptData struct {
+	Role            string
+	Title           string
+	Price           int
+	Condition       string
+	Category        string
+	Description     string
+	ProductOffers   int
+	AcceptedOffers  int
+	AcceptedPercent float64
+	RecentSales     int
+	MedianSoldPrice int
+}
+
+// ChatRetrievalPromptData fills the chat retrieval template
+type ChatRetrievalPromptData struct {
+	Question   string
+	Categories []string
+	Conditions []string
+}
+
+// ChatAgentTool describes one action the chat agent may call
+type ChatAgentTool struct {
+	Name        string
+	Description string
+	Arguments   string // example arguments object
+	Confirm     bool   // the user confirms before it runs
+}
+
+var promptTemplates = template.Must(template.New("prompts").Parse(`
+{{define "chat_system"}}あなたはEcoMateのAIアシスタントです。
+EcoMateは環境に優しい中古品フリーマーケットアプリです。
+
+以下のことを心がけて応答してください：
+- ユーザーの質問に日本語で丁寧に答える
+- 商品探しや購入のサポートをする
+- CO2削減や環境保護の価値を伝える
+- EcoMateの機能（3D表示、AR試着、価格予測など）を紹介する
+- 具体的で実用的なアドバイスを提供する
+
+EcoMateの主な機能：
+- 商品検索・フィルタリング（カテゴリー、価格、状態など）
+- 3Dモデル表示とAR試着機能
+- AI価格予測と交渉サポート
+- CO2削減量の可視化
+- エコポイント・レベルシステム
+- リアルタイムメッセージング
+{{if .}}
+{{.}}
+
+重要: ユーザーが商品を探している場合、上記の商品リストから適切な商品を推薦してください。商品を推薦する際は、必ず上記のフォーマット [PRODUCT:商品ID:商品名:商品画像URL] を使用してください。
+リストにない商品や、リストにない情報（価格、状態、レビュー、CO2削減量）を作らないでください。
+{{end}}{{end}}
+
+{{define "chat_agent"}}あなたはEcoMateのAIアシスタントです。ログイン中のユーザーの代わりに、以下のツールを使って操作できます。
+
+ツール:
+{{range .}}- {{.Name}}: {{.Description}}{{if .Confirm}}（ユーザーの確認後に実行されます）{{end}}
+  引数の例: {{.Arguments}}
+{{end}}
+毎回、次のどちらかのJSONだけで応答してください:
+{"tool": "<ツール名>", "arguments": {<引数>}}
+{"reply": "<ユーザーへの日本語の返答>"}
+
+ツールの結果は "TOOL_RESULT <ツール名>: <JSON>" という形で届きます。結果に含まれる商品IDや購入IDだけを使い、IDを作らないでください。
+商品を紹介する時は [PRODUCT:商品ID:商品名:] のフォーマットを使ってください。{{end}}
+
+{{define "chat_retrieval"}}You turn questions asked to the chatbot of a second-hand marketplace into a catalog search.
+
+Question: "{{.Question}}"
+
+Categories: {{range $i, $c := .Categories}}{{if $i}}, {{end}}{{$c}}{{end}}
+Conditions: {{range $i, $c := .Conditions}}{{if $i}}, {{end}}{{$c}}{{end}}
+
+Respond with JSON only, in this format:
+{
+  "wants_products": <true if the user is looking for or asking about items for sale>,
+  "search": "<a few keywords to match product titles and descriptions, in the language of the catalog, or empty>",
+  "category": "<one of the categories, or empty>",
+  "condition": "<one of the conditions, or empty>",
+  "min_price": <yen, 0 for none>,
+  "max_price": <yen, 0 for none>,
+  "sort": "<price_asc, price_desc, eco_impact_desc or empty>"
+}{{end}}
+
+{{define "negotiation"}}You are an AI negotiation assistant for a flea market app. Analyze the following product and provide negotiation advice for the {{.Role}}.
+
+Product Details:
+- Title: {{.Title}}
+- Current Price: ¥{{.Price}}
+- Condition: {{.Condition}}
+- Category: {{.Category}}
+- Description: {{.Description}}
+
+Historical Offers: {{.ProductOffers}} previous offers on this product
+
+Market History:
+{{if .AcceptedOffers}}- {{.AcceptedOffers}} accepted offers in this category, agreed at {{printf "%.0f" .AcceptedPercent}}% of the listing price on average
+{{else}}- No accepted offers in this category yet
+{{end}}{{if .RecentSales}}- {{.RecentSales}} recent sales in this category, median sold price ¥{{.MedianSoldPrice}}
+{{else}}- No recent sales in this category
+{{end}}
+As a {{.Role}}, provide:
+1. A recommended price for negotiation (specific number, no higher than the current price)
+2. Estimated acceptance rate (0-1)
+3. Negotiation strategy (concise, 2-3 sentences)
+4. Reasoning (1-2 sentences)
+
+Respond with JSON only, in this format:
+{
+  "recommended_price": <number>,
+  "acceptance_rate": <0.0-1.0>,
+  "strategy": "<strategy text>",
+  "reasoning": "<reasoning text>"
+}{{end}}
+
+{{define "translate_search"}}Translate the following search query to help with multilingual product search.
+Original query: "{{.}}"
+
+Provide translations and search-relevant keywords in JSON format:
+{
+    "japanese": "Japanese translation/keywords",
+    "english": "English translation/keywords",
+    "romanized": "Romanized version if applicable",
+    "keywords": ["keyword1", "keyword2", "keyword3"],
+    "detected_language": "original language code (ja/en/etc)",
+    "search_intent": "brief description of what user is looking for"
+}
+
+For example:
+- If query is "スマホ", return english "smartphone", keywords ["phone", "mobile", "iPhone", "Android"]
+- If query is "laptop", return japanese "ノートパソコン", keywords ["PC", "MacBook", "computer"]
+- Be creative with synonyms and related terms for better search results{{end}}
+`))
+
+// RenderPrompt fills the named prompt template with data
+func RenderPrompt(name string, data interface{}) (string, error) {
+	var b strings.Builder
+	if err := promptTemplates.ExecuteTemplate(&b, name, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/yourusername/ecomate/backend/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type purchaseRepository struct {
@@ -32,6 +33,14 @@ func (r *purchaseRepository) FindByID(id uuid.UUID) (*domain.Purchase, error) {
 	return &purchase, nil
 }
 
+func (r *purchaseRepository) FindByIDForUpdate(id uuid.UUID) (*domain.Purchase, error) {
+	var purchase domain.Purchase
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&purchase).Error; err != nil {
+		return nil, err
+	}
+	return &purchase, nil
+}
+
 func (r *purchaseRepository) FindByUser(userID uuid.UUID, role string, page, limit int) ([]*domain.Purchase, *domain.PaginationResponse, error) {
 	var purchases []*domain.Purchase
 	var total int64
@@ -89,3 +98,7 @@ func (r *purchaseRepository) UpdateStatus(id uuid.UUID, status domain.PurchaseSt
 		Update("status", status).
 		Error
 }
+
+func (r *purchaseRepository) Update(purchase *domain.Purchase) error {
+	return r.db.Omit(clause.Associations).Save(purchase).Error
+}
@@ -0,0 +1,273 @@
+package infrastructure
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// Weights of the ways a query word can match, relative to the word itself
+	synonymMatchWeight = 0.9
+	romajiMatchWeight  = 0.8
+	typoMatchWeight    = 0.7
+)
+
+// SearchDocument is the text of one item; title words count double
+type SearchDocument struct {
+	ID    uuid.UUID
+	Title string
+	Body  string
+}
+
+type SearchHit struct {
+	ID    uuid.UUID
+	Score float64
+}
+
+// SearchIndex is a full-text index over Japanese and English documents
+type SearchIndex interface {
+	// Index adds the document, replacing any earlier version
+	Index(doc SearchDocument)
+	Remove(id uuid.UUID)
+	// Search returns up to limit documents matching query, best first
+	Search(query string, limit int) []SearchHit
+	Len() int
+}
+
+// InvertedIndex is an in-memory SearchIndex ranked by BM25. Japanese text is
+// indexed as character bigrams, so it needs no dictionary to split words.
+// A query matches documents that match every one of its words, where a word
+// may also match through a synonym, its romaji reading or a close spelling.
+// If no document has them all, documents matching any word are returned.
+type InvertedIndex struct {
+	mu       sync.RWMutex
+	synonyms *SearchSynonyms
+	postings map[string]map[uuid.UUID]int // term -> document -> term frequency
+	docTerms map[uuid.UUID]map[string]int
+	docLen   map[uuid.UUID]int
+	totalLen int
+}
+
+func NewInvertedIndex(synonyms *SearchSynonyms) *InvertedIndex {
+	if synonyms == nil {
+		synonyms = NewSearchSynonyms(nil)
+	}
+	return &InvertedIndex{
+		synonyms: synonyms,
+		postings: make(map[string]map[uuid.UUID]int),
+		docTerms: make(map[uuid.UUID]map[string]int),
+		docLen:   make(map[uuid.UUID]int),
+	}
+}
+
+func (x *InvertedIndex) Index(doc SearchDocument) {
+	terms := make(map[string]int)
+	length := 0
+	for _, field := range []struct {
+		text  string
+		boost int
+	}{{doc.Title, 2}, {doc.Body, 1}} {
+		for _, word := range splitSearchWords(NormalizeSearchText(field.text)) {
+			for _, term := range searchTerms(word, true) {
+				terms[term] += field.boost
+				length += field.boost
+			}
+		}
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(doc.ID)
+	for term, tf := range terms {
+		if x.postings[term] == nil {
+			x.postings[term] = make(map[uuid.UUID]int)
+		}
+		x.postings[term][doc.ID] = tf
+	}
+	x.docTerms[doc.ID] = terms
+	x.docLen[doc.ID] = length
+	x.totalLen += length
+}
+
+func (x *InvertedIndex) Remove(id uuid.UUID) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(id)
+}
+
+func (x *InvertedIndex) remove(id uuid.UUID) {
+	terms, ok := x.docTerms[id]
+	if !ok {
+		return
+	}
+	for term := range terms {
+		delete(x.postings[term], id)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+		}
+	}
+	x.totalLen -= x.docLen[id]
+	delete(x.docTerms, id)
+	delete(x.docLen, id)
+}
+
+func (x *InvertedIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docLen)
+}
+
+// searchAlternative is one way to match a query word
+type searchAlternative struct {
+	terms  []string
+	weight float64
+}
+
+func (x *InvertedIndex) Search(query string, limit int) []SearchHit {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	words := splitSearchWords(NormalizeSearchText(query))
+	if len(words) == 0 || len(x.docLen) == 0 || limit <= 0 {
+		return nil
+	}
+
+	scores := make(map[uuid.UUID]float64)
+	matched := make(map[uuid.UUID]int)
+	for _, word := range words {
+		best := make(map[uuid.UUID]float64)
+		for _, alt := range x.alternatives(word) {
+			for id, score := range x.score(alt) {
+				if score > best[id] {
+					best[id] = score
+				}
+			}
+		}
+		for id, score := range best {
+			scores[id] += score
+			matched[id]++
+		}
+	}
+
+	hits := make([]SearchHit, 0, len(scores))
+	for id, score := range scores {
+		if matched[id] == len(words) {
+			hits = append(hits, SearchHit{ID: id, Score: score})
+		}
+	}
+	if len(hits) == 0 {
+		for id, score := range scores {
+			hits = append(hits, SearchHit{ID: id, Score: score})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID.String() < hits[j].ID.String()
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// alternatives lists the ways word can match: itself, its synonyms, its
+// reading when it is romaji and, for Latin words the index does not know,
+// known words one or two edits away
+func (x *InvertedIndex) alternatives(word searchWord) []searchAlternative {
+	alts := []searchAlternative{{terms: searchTerms(word, false), weight: 1}}
+	add := func(text string, weight float64) {
+		var terms []string
+		for _, w := range splitSearchWords(text) {
+			terms = append(terms, searchTerms(w, false)...)
+		}
+		if len(terms) > 0 {
+			alts = append(alts, searchAlternative{terms: terms, weight: weight})
+		}
+	}
+
+	for _, synonym := range x.synonyms.expand(word) {
+		add(synonym, synonymMatchWeight)
+	}
+	if word.japanese {
+		return alts
+	}
+
+	if kana, ok := romajiToHiragana(word.text); ok {
+		add(kana, romajiMatchWeight)
+		for _, synonym := range x.synonyms.expand(searchWord{text: kana, japanese: true}) {
+			add(synonym, romajiMatchWeight*synonymMatchWeight)
+		}
+	}
+
+	term := stemLatin(word.text)
+	if _, known := x.postings[term]; known || len(term) < 4 {
+		return alts
+	}
+	maxEdits := 1
+	if len(term) >= 8 {
+		maxEdits = 2
+	}
+	for candidate := range x.postings {
+		if candidate[0] >= 0x80 || abs(len(candidate)-len(term)) > maxEdits {
+			continue
+		}
+		if editDistance(candidate, term) <= maxEdits {
+			alts = append(alts, searchAlternative{terms: []string{candidate}, weight: typoMatchWeight})
+		}
+	}
+	return alts
+}
+
+// score is the BM25 score of the documents containing at least three
+// quarters of the terms, so a bigram or two may differ
+func (x *InvertedIndex) score(alt searchAlternative) map[uuid.UUID]float64 {
+	unique := make(map[string]bool, len(alt.terms))
+	for _, term := range alt.terms {
+		unique[term] = true
+	}
+	required := int(math.Ceil(0.75 * float64(len(unique))))
+
+	n := float64(len(x.docLen))
+	avgLen := float64(x.totalLen) / n
+	scores := make(map[uuid.UUID]float64)
+	found := make(map[uuid.UUID]int)
+	for term := range unique {
+		postings := x.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range postings {
+			f := float64(tf)
+			lengthNorm := bm25K1 * (1 - bm25B + bm25B*float64(x.docLen[id])/avgLen)
+			scores[id] += idf * f * (bm25K1 + 1) / (f + lengthNorm)
+			found[id]++
+		}
+	}
+
+	for id := range scores {
+		if found[id] < required {
+			delete(scores, id)
+		} else {
+			scores[id] *= alt.weight
+		}
+	}
+	return scores
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,91 @@
+package infrastructure_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+)
+
+func hitIDs(hits []infrastructure.SearchHit) []uuid.UUID {
+	ids := make([]uuid.UUID, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	return ids
+}
+
+func TestInvertedIndex_RanksByRelevance(t *testing.T) {
+	// Arrange
+	index := infrastructure.NewInvertedIndex(nil)
+	inTitle := infrastructure.SearchDocument{ID: uuid.New(), Title: "ロードバイク", Body: "軽量 カーボン"}
+	inBody := infrastructure.SearchDocument{ID: uuid.New(), Title: "ヘルメット", Body: "ロードバイク 用"}
+	exact := infrastructure.SearchDocument{ID: uuid.New(), Title: "Road bike", Body: "carbon frame"}
+	synonym := infrastructure.SearchDocument{ID: uuid.New(), Title: "Road bicycle", Body: "carbon frame"}
+	for _, doc := range []infrastructure.SearchDocument{inTitle, inBody, exact, synonym} {
+		index.Index(doc)
+	}
+
+	// Act
+	byTitle := index.Search("ロードバイク", 10)
+	bike := index.Search("bike", 10)
+
+	// Assert
+	assert.Equal(t, []uuid.UUID{inTitle.ID, inBody.ID}, hitIDs(byTitle), "title words count double")
+	require.Len(t, bike, 2)
+	assert.Equal(t, exact.ID, bike[0].ID, "the word itself beats a synonym")
+	assert.Equal(t, synonym.ID, bike[1].ID)
+	assert.Greater(t, bike[0].Score, bike[1].Score)
+}
+
+func TestInvertedIndex_PrefersDocumentsMatchingEveryWord(t *testing.T) {
+	// Arrange
+	index := infrastructure.NewInvertedIndex(nil)
+	both := infrastructure.SearchDocument{ID: uuid.New(), Title: "iPhone ケース 手帳型"}
+	caseOnly := infrastructure.SearchDocument{ID: uuid.New(), Title: "カメラ ケース"}
+	index.Index(both)
+	index.Index(caseOnly)
+
+	// Act & Assert
+	assert.Equal(t, []uuid.UUID{both.ID}, hitIDs(index.Search("iphone ケース", 10)))
+	// With no document holding both words, any word is enough
+	assert.Equal(t, []uuid.UUID{caseOnly.ID}, hitIDs(index.Search("カメラ 三脚", 10)))
+}
+
+func TestInvertedIndex_ReplacesAndRemovesDocuments(t *testing.T) {
+	// Arrange
+	index := infrastructure.NewInvertedIndex(nil)
+	id := uuid.New()
+	index.Index(infrastructure.SearchDocument{ID: id, Title: "ソファ 2人掛け"})
+	other := infrastructure.SearchDocument{ID: uuid.New(), Title: "ソファ カバー"}
+	index.Index(other)
+
+	// Act
+	index.Index(infrastructure.SearchDocument{ID: id, Title: "ダイニングテーブル"})
+	afterReplace := index.Search("ソファ", 10)
+	replaced := index.Search("テーブル", 10)
+	index.Remove(id)
+	index.Remove(uuid.New())
+
+	// Assert
+	assert.Equal(t, []uuid.UUID{other.ID}, hitIDs(afterReplace), "the old text is forgotten")
+	assert.Equal(t, []uuid.UUID{id}, hitIDs(replaced))
+	assert.Empty(t, index.Search("テーブル", 10))
+	assert.Equal(t, 1, index.Len())
+}
+
+func TestInvertedIndex_SearchLimits(t *testing.T) {
+	// Arrange
+	index := infrastructure.NewInvertedIndex(nil)
+	for i := 0; i < 5; i++ {
+		index.Index(infrastructure.SearchDocument{ID: uuid.New(), Title: "Guitar strings"})
+	}
+
+	// Act & Assert
+	assert.Len(t, index.Search("guitar", 3), 3)
+	assert.Empty(t, index.Search("guitar", 0))
+	assert.Empty(t, index.Search("  ！？ ", 10), "a query without words matches nothing")
+	assert.Empty(t, infrastructure.NewInvertedIndex(nil).Search("guitar", 10))
+}
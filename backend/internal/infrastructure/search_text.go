@@ -0,0 +1,317 @@
+package infrastructure
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeSearchText folds the ways the same Japanese or English word can be
+// written: full-width letters and half-width kana (NFKC), case, and katakana,
+// which is turned into hiragana
+func NormalizeSearchText(text string) string {
+	text = strings.ToLower(norm.NFKC.String(text))
+	return strings.Map(func(r rune) rune {
+		if r >= 'ァ' && r <= 'ヶ' {
+			return r - ('ァ' - 'ぁ')
+		}
+		return r
+	}, text)
+}
+
+// searchWord is a run of Latin letters and digits or of Japanese characters
+type searchWord struct {
+	text     string
+	japanese bool
+}
+
+func isJapaneseRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || r == 'ー'
+}
+
+// splitSearchWords splits normalized text into words; Japanese has no spaces
+// so a Japanese run may hold several words
+func splitSearchWords(text string) []searchWord {
+	var words []searchWord
+	var current []rune
+	japanese := false
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, searchWord{text: string(current), japanese: japanese})
+			current = current[:0]
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case isJapaneseRune(r):
+			if !japanese {
+				flush()
+			}
+			japanese = true
+			current = append(current, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if japanese {
+				flush()
+			}
+			japanese = false
+			current = append(current, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// searchTerms turns a word into index terms: Latin words stay whole (with a
+// plural "s" dropped) and Japanese runs become character bigrams. With
+// kanjiUnigrams every kanji is also a term of its own so that one-kanji
+// queries such as 本 can match.
+func searchTerms(word searchWord, kanjiUnigrams bool) []string {
+	if !word.japanese {
+		return []string{stemLatin(word.text)}
+	}
+
+	runes := []rune(word.text)
+	// コンピューター and コンピュータ are the same word
+	for len(runes) > 1 && runes[len(runes)-1] == 'ー' {
+		runes = runes[:len(runes)-1]
+	}
+	if len(runes) == 1 {
+		return []string{string(runes)}
+	}
+
+	terms := make([]string, 0, 2*len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		terms = append(terms, string(runes[i:i+2]))
+	}
+	if kanjiUnigrams {
+		for _, r := range runes {
+			if unicode.Is(unicode.Han, r) {
+				terms = append(terms, string(r))
+			}
+		}
+	}
+	return terms
+}
+
+func stemLatin(word string) string {
+	if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// romajiSyllables maps romaji, Hepburn and kunrei-shiki, to hiragana
+var romajiSyllables = map[string]string{
+	"a": "あ", "i": "い", "u": "う", "e": "え", "o": "お",
+	"ka": "か", "ki": "き", "ku": "く", "ke": "け", "ko": "こ",
+	"ga": "が", "gi": "ぎ", "gu": "ぐ", "ge": "げ", "go": "ご",
+	"sa": "さ", "shi": "し", "si": "し", "su": "す", "se": "せ", "so": "そ",
+	"za": "ざ", "ji": "じ", "zi": "じ", "zu": "ず", "ze": "ぜ", "zo": "ぞ",
+	"ta": "た", "chi": "ち", "ti": "ち", "tsu": "つ", "tu": "つ", "te": "て", "to": "と",
+	"da": "だ", "di": "ぢ", "du": "づ", "de": "で", "do": "ど",
+	"na": "な", "ni": "に", "nu": "ぬ", "ne": "ね", "no": "の",
+	"ha": "は", "hi": "ひ", "fu": "ふ", "hu": "ふ", "he": "へ", "ho": "ほ",
+	"ba": "ば", "bi": "び", "bu": "ぶ", "be": "べ", "bo": "ぼ",
+	"pa": "ぱ", "pi": "ぴ", "pu": "ぷ", "pe": "ぺ", "po": "ぽ",
+	"ma": "ま", "mi": "み", "mu": "む", "me": "め", "mo": "も",
+	"ya": "や", "yu": "ゆ", "yo": "よ",
+	"ra": "ら", "ri": "り", "ru": "る", "re": "れ", "ro": "ろ",
+	"wa": "わ", "wo": "を",
+	"kya": "きゃ", "kyu": "きゅ", "kyo": "きょ", "gya": "ぎゃ", "gyu": "ぎゅ", "gyo": "ぎょ",
+	"sha": "しゃ", "shu": "しゅ", "sho": "しょ", "she": "しぇ", "sya": "しゃ", "syu": "しゅ", "syo": "しょ",
+	"ja": "じゃ", "ju": "じゅ", "jo": "じょ", "je": "じぇ", "zya": "じゃ", "zyu": "じゅ", "zyo": "じょ",
+	"cha": "ちゃ", "chu": "ちゅ", "cho": "ちょ", "che": "ちぇ", "tya": "ちゃ", "tyu": "ちゅ", "tyo": "ちょ",
+	"nya": "にゃ", "nyu": "にゅ", "nyo": "にょ", "hya": "ひゃ", "hyu": "ひゅ", "hyo": "ひょ",
+	"bya": "びゃ", "byu": "びゅ", "byo": "びょ", "pya": "ぴゃ", "pyu": "ぴゅ", "pyo": "ぴょ",
+	"mya": "みゃ", "myu": "みゅ", "myo": "みょ", "rya": "りゃ", "ryu": "りゅ", "ryo": "りょ",
+	"fa": "ふぁ", "fi": "ふぃ", "fe": "ふぇ", "fo": "ふぉ",
+}
+
+// romajiToHiragana reads a Latin word as romaji; ok is false unless the whole
+// word is romaji
+func romajiToHiragana(word string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(word); {
+		c := word[i]
+		if c < 'a' || c > 'z' {
+			return "", false
+		}
+		// A doubled consonant is a small tsu: kitte → きって
+		if i+1 < len(word) && word[i+1] == c && c != 'n' && !strings.ContainsRune("aiueo", rune(c)) {
+			b.WriteString("っ")
+			i++
+			continue
+		}
+		// n is ん unless it starts a syllable: konnichiwa → こんにちわ
+		if c == 'n' && (i+1 == len(word) || !strings.ContainsRune("aiueoy", rune(word[i+1]))) {
+			b.WriteString("ん")
+			i++
+			continue
+		}
+		matched := false
+		for size := 3; size >= 1; size-- {
+			if i+size > len(word) {
+				continue
+			}
+			if kana, ok := romajiSyllables[word[i:i+size]]; ok {
+				b.WriteString(kana)
+				i += size
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// defaultSearchSynonyms are groups of words buyers use for the same thing
+var defaultSearchSynonyms = [][]string{
+	{"スマホ", "スマートフォン", "smartphone", "携帯", "ケータイ"},
+	{"iphone", "アイフォン", "アイフォーン"},
+	{"パソコン", "pc", "コンピューター", "computer"},
+	{"ノートパソコン", "laptop", "notebook"},
+	{"タブレット", "tablet"},
+	{"テレビ", "tv", "television"},
+	{"カメラ", "camera"},
+	{"デジカメ", "デジタルカメラ"},
+	{"ヘッドホン", "ヘッドフォン", "headphone"},
+	{"イヤホン", "イヤフォン", "earphone", "earbud"},
+	{"スピーカー", "speaker"},
+	{"キーボード", "keyboard"},
+	{"マウス", "mouse"},
+	{"モニター", "ディスプレイ", "monitor", "display"},
+	{"プリンター", "printer"},
+	{"充電器", "charger"},
+	{"ゲーム", "game"},
+	{"自転車", "チャリ", "bicycle", "bike"},
+	{"ソファ", "sofa", "couch"},
+	{"机", "デスク", "desk"},
+	{"椅子", "イス", "チェア", "chair"},
+	{"ベッド", "bed"},
+	{"棚", "ラック", "シェルフ", "shelf", "rack"},
+	{"冷蔵庫", "fridge", "refrigerator"},
+	{"洗濯機", "washer"},
+	{"掃除機", "vacuum"},
+	{"電子レンジ", "microwave"},
+	{"本", "書籍", "book"},
+	{"漫画", "マンガ", "comic", "manga"},
+	{"服", "洋服", "衣類", "clothe", "clothing"},
+	{"シャツ", "shirt"},
+	{"ジャケット", "jacket"},
+	{"コート", "coat"},
+	{"スニーカー", "sneaker"},
+	{"靴", "シューズ", "shoe"},
+	{"バッグ", "鞄", "カバン", "bag"},
+	{"財布", "ウォレット", "wallet"},
+	{"時計", "ウォッチ", "watch"},
+	{"おもちゃ", "玩具", "toy"},
+	{"ぬいぐるみ", "plush"},
+	{"ギター", "guitar"},
+	{"ピアノ", "piano"},
+	{"テント", "tent"},
+	{"キャンプ", "camping", "camp"},
+	{"ゴルフ", "golf"},
+	{"ベビーカー", "stroller"},
+	{"食器", "dish"},
+}
+
+// SearchSynonyms looks up the other words of a synonym group
+type SearchSynonyms struct {
+	peers map[string][]string
+}
+
+// NewSearchSynonyms builds the dictionary from groups of synonyms; nil
+// groups use the built-in dictionary
+func NewSearchSynonyms(groups [][]string) *SearchSynonyms {
+	if groups == nil {
+		groups = defaultSearchSynonyms
+	}
+	s := &SearchSynonyms{peers: make(map[string][]string)}
+	for _, group := range groups {
+		normalized := make([]string, len(group))
+		for i, word := range group {
+			normalized[i] = NormalizeSearchText(word)
+		}
+		for _, word := range normalized {
+			for _, peer := range normalized {
+				if peer != word {
+					s.peers[word] = append(s.peers[word], peer)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// expand rewrites a normalized word with each synonym. Japanese words are
+// matched by substring as they are not split: すまほけーす → すまーとふぉんけーす.
+func (s *SearchSynonyms) expand(word searchWord) []string {
+	if !word.japanese {
+		return s.peers[stemLatin(word.text)]
+	}
+
+	var expanded []string
+	for term, peers := range s.peers {
+		if !isJapaneseWord(term) || !strings.Contains(word.text, term) {
+			continue
+		}
+		for _, peer := range peers {
+			// An English synonym can only stand in for the whole word
+			if word.text != term && !isJapaneseWord(peer) {
+				continue
+			}
+			expanded = append(expanded, strings.Replace(word.text, term, peer, 1))
+		}
+	}
+	return expanded
+}
+
+func isJapaneseWord(text string) bool {
+	for _, r := range text {
+		if !isJapaneseRune(r) {
+			return false
+		}
+	}
+	return text != ""
+}
+
+// editDistance is the optimal string alignment distance: insertions,
+// deletions, substitutions and swaps of neighbours each cost one
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j] + 1
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+			if prev[j-1]+cost < cur[j] {
+				cur[j] = prev[j-1] + cost
+			}
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] && prev2[j-2]+1 < cur[j] {
+				cur[j] = prev2[j-2] + 1
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,82 @@
+package infrastructure_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+)
+
+func TestNormalizeSearchText(t *testing.T) {
+	for _, tc := range []struct {
+		text     string
+		expected string
+	}{
+		{text: "スマートフォン", expected: "すまーとふぉん"},
+		{text: "ｽﾏﾎ", expected: "すまほ"},
+		{text: "ＩＰＨＯＮＥ　１２", expected: "iphone 12"},
+		{text: "Road Bike", expected: "road bike"},
+		{text: "本棚", expected: "本棚"},
+		{text: "ヴィンテージ", expected: "ゔぃんてーじ"},
+	} {
+		t.Run(tc.text, func(t *testing.T) {
+			assert.Equal(t, tc.expected, infrastructure.NormalizeSearchText(tc.text))
+		})
+	}
+}
+
+func TestInvertedIndex_MatchesWordVariants(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		title    string
+		query    string
+		expected bool
+	}{
+		{name: "synonym", title: "スマートフォン 128GB", query: "スマホ", expected: true},
+		{name: "synonym inside a longer word", title: "スマートフォンケース", query: "スマホケース", expected: true},
+		{name: "English synonym", title: "自転車 26インチ", query: "bicycle", expected: true},
+		{name: "half-width kana", title: "ｽﾏﾎ スタンド", query: "スマホ", expected: true},
+		{name: "hiragana for katakana", title: "カメラ", query: "かめら", expected: true},
+		{name: "trailing long vowel", title: "コンピューター", query: "コンピュータ", expected: true},
+		{name: "romaji", title: "ぬいぐるみ", query: "nuigurumi", expected: true},
+		{name: "romaji with a doubled consonant", title: "ヨガマット", query: "matto", expected: true},
+		{name: "plural", title: "Running shoes", query: "shoe", expected: true},
+		{name: "one typo", title: "Nintendo Switch", query: "nitnendo", expected: true},
+		{name: "two typos in a long word", title: "Refrigerator 400L", query: "refridgerater", expected: true},
+		{name: "one kanji", title: "木製 本棚", query: "本", expected: true},
+		{name: "unrelated word", title: "スマートフォン", query: "冷蔵庫", expected: false},
+		{name: "short words allow no typo", title: "Golf bag", query: "bad", expected: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			index := infrastructure.NewInvertedIndex(nil)
+			doc := infrastructure.SearchDocument{ID: uuid.New(), Title: tc.title}
+			index.Index(doc)
+			index.Index(infrastructure.SearchDocument{ID: uuid.New(), Title: "木製 デスク", Body: "unrelated filler"})
+
+			// Act
+			hits := index.Search(tc.query, 10)
+
+			// Assert
+			matched := false
+			for _, hit := range hits {
+				matched = matched || hit.ID == doc.ID
+			}
+			assert.Equal(t, tc.expected, matched, "hits: %v", hits)
+		})
+	}
+}
+
+func TestNewSearchSynonyms_UsesCustomGroups(t *testing.T) {
+	// Arrange
+	index := infrastructure.NewInvertedIndex(infrastructure.NewSearchSynonyms([][]string{{"ガチャ", "capsule toy"}}))
+	toy := infrastructure.SearchDocument{ID: uuid.New(), Title: "ガチャ まとめ売り"}
+	phone := infrastructure.SearchDocument{ID: uuid.New(), Title: "スマートフォン"}
+	index.Index(toy)
+	index.Index(phone)
+
+	// Act & Assert
+	assert.Equal(t, toy.ID, index.Search("がちゃ", 1)[0].ID)
+	assert.Empty(t, index.Search("スマホ", 10), "the built-in groups are not used")
+}
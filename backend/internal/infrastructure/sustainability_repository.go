@@ -41,7 +41,7 @@ func (r *sustainabilityRepository) CheckAndAwardAchievements(userID uuid.UUID) e
 		var transactionCount int64
 		if err := tx.Model(&domain.Purchase{}).
 			Where("buyer_id = ? OR seller_id = ?", userID, userID).
-			Where("status = ?", domain.PurchaseStatusCompleted).
+			Where("status = ?", domain.PurchaseStatusFundsReleased).
 			Count(&transactionCount).Error; err != nil {
 			return err
 		}
@@ -136,7 +136,7 @@ func (r *sustainabilityRepository) GetMonthlyStats(userID uuid.UUID) (*domain.Mo
 	// Get transaction count this month
 	if err := r.db.Model(&domain.Purchase{}).
 		Where("(buyer_id = ? OR seller_id = ?) AND created_at >= ?", userID, userID, startOfMonth).
-		Where("status = ?", domain.PurchaseStatusCompleted).
+		Where("status = ?", domain.PurchaseStatusFundsReleased).
 		Count(&transactionCount).Error; err != nil {
 		return nil, err
 	}
@@ -0,0 +1,35 @@
+package infrastructure
+
+import (
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) domain.UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+func (u *unitOfWork) Do(fn func(repos *domain.Repositories) error) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		return fn(newRepositories(tx))
+	})
+}
+
+// newRepositories binds every repository to the given connection or transaction
+func newRepositories(db *gorm.DB) *domain.Repositories {
+	return &domain.Repositories{
+		Products:       NewProductRepository(db),
+		Purchases:      NewPurchaseRepository(db),
+		Users:          NewUserRepository(db),
+		Sustainability: NewSustainabilityRepository(db),
+		Disputes:       NewDisputeRepository(db),
+		Auctions:       NewAuctionRepository(db),
+		Bids:           NewBidRepository(db),
+		ProxyBids:      NewProxyBidRepository(db),
+		Offers:         NewOfferRepository(db),
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/yourusername/ecomate/backend/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type userRepository struct {
@@ -45,7 +46,42 @@ func (r *userRepository) FindByUsername(username string) (*domain.User, error) {
 }
 
 func (r *userRepository) Update(user *domain.User) error {
-	return r.db.Save(user).Error
+	// Penalties are only changed through AddPenaltyDue and TakePenaltyDue
+	return r.db.Omit("penalty_due").Save(user).Error
+}
+
+func (r *userRepository) AddPenaltyDue(userID uuid.UUID, amount int) error {
+	return r.db.Model(&domain.User{}).
+		Where("id = ?", userID).
+		Update("penalty_due", gorm.Expr("penalty_due + ?", amount)).
+		Error
+}
+
+func (r *userRepository) TakePenaltyDue(userID uuid.UUID, max int) (int, error) {
+	var taken int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "penalty_due").
+			Where("id = ?", userID).
+			First(&user).Error; err != nil {
+			return err
+		}
+
+		taken = user.PenaltyDue
+		if taken > max {
+			taken = max
+		}
+		if taken <= 0 {
+			taken = 0
+			return nil
+		}
+		return tx.Model(&domain.User{}).
+			Where("id = ?", userID).
+			Update("penalty_due", gorm.Expr("penalty_due - ?", taken)).
+			Error
+	})
+	return taken, err
 }
 
 func (r *userRepository) UpdateSustainabilityStats(userID uuid.UUID, co2SavedKg float64) error {
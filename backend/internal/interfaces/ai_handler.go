@@ -2,21 +2,22 @@ package interfaces
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"io"
+	"errors"
 	"net/http"
-	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
 )
 
 type AIHandler struct {
-	geminiAPIKey string
+	llm infrastructure.LLMProvider
 }
 
-func NewAIHandler() *AIHandler {
+func NewAIHandler(llm infrastructure.LLMProvider) *AIHandler {
 	return &AIHandler{
-		geminiAPIKey: os.Getenv("GOOGLE_API_KEY"),
+		llm: llm,
 	}
 }
 
@@ -41,26 +42,7 @@ func (h *AIHandler) TranslateSearch(c *gin.Context) {
 		return
 	}
 
-	prompt := `Translate the following search query to help with multilingual product search.
-Original query: "` + req.Query + `"
-
-Provide translations and search-relevant keywords in JSON format:
-{
-    "japanese": "Japanese translation/keywords",
-    "english": "English translation/keywords",
-    "romanized": "Romanized version if applicable",
-    "keywords": ["keyword1", "keyword2", "keyword3"],
-    "detected_language": "original language code (ja/en/etc)",
-    "search_intent": "brief description of what user is looking for"
-}
-
-For example:
-- If query is "スマホ", return english "smartphone", keywords ["phone", "mobile", "iPhone", "Android"]
-- If query is "laptop", return japanese "ノートパソコン", keywords ["PC", "MacBook", "computer"]
-- Be creative with synonyms and related terms for better search results`
-
-	// Call Gemini API
-	result, err := h.callGeminiAPI(prompt)
+	result, err := h.translate(c.Request.Context(), req.Query)
 	if err != nil {
 		// Fallback response
 		c.JSON(http.StatusOK, TranslateSearchResponse{
@@ -77,62 +59,26 @@ For example:
 	c.JSON(http.StatusOK, result)
 }
 
-func (h *AIHandler) callGeminiAPI(prompt string) (TranslateSearchResponse, error) {
-	url := "https://generativelanguage.googleapis.com/v1beta/models/gemini-1.5-flash:generateContent?key=" + h.geminiAPIKey
-
-	requestBody := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]string{
-					{"text": prompt},
-				},
-			},
-		},
-		"generationConfig": map[string]interface{}{
-			"temperature": 0.7,
-		},
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return TranslateSearchResponse{}, err
+func (h *AIHandler) translate(ctx context.Context, query string) (TranslateSearchResponse, error) {
+	if h.llm == nil {
+		return TranslateSearchResponse{}, errors.New("LLM provider not configured")
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	prompt, err := infrastructure.RenderPrompt(infrastructure.PromptTranslateSearch, query)
 	if err != nil {
 		return TranslateSearchResponse{}, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	llmReq := infrastructure.Prompt(prompt)
+	llmReq.Temperature = 0.7
+	llmReq.JSON = true
+	resp, err := h.llm.Generate(ctx, llmReq)
 	if err != nil {
 		return TranslateSearchResponse{}, err
 	}
 
-	// Parse Gemini response
-	var geminiResp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
-	}
-
-	if err := json.Unmarshal(body, &geminiResp); err != nil {
-		return TranslateSearchResponse{}, err
-	}
-
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return TranslateSearchResponse{}, err
-	}
-
-	// Extract JSON from response
-	text := geminiResp.Candidates[0].Content.Parts[0].Text
-
 	// Remove markdown code blocks if present
-	text = cleanJSONResponse(text)
+	text := cleanJSONResponse(resp.Text)
 
 	var result TranslateSearchResponse
 	if err := json.Unmarshal([]byte(text), &result); err != nil {
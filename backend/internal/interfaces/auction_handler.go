@@ -1,32 +1,30 @@
 package interfaces
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
-	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/realtime"
 	"github.com/yourusername/ecomate/backend/internal/usecase"
 )
 
 type AuctionHandler struct {
 	auctionUseCase usecase.AuctionUseCase
-	connections    map[uuid.UUID]map[*websocket.Conn]bool
-	mu             sync.RWMutex
+	hub            *realtime.Hub
 	upgrader       websocket.Upgrader
 }
 
-func NewAuctionHandler(auctionUseCase usecase.AuctionUseCase) *AuctionHandler {
+func NewAuctionHandler(auctionUseCase usecase.AuctionUseCase, hub *realtime.Hub, allowedOrigins []string) *AuctionHandler {
 	return &AuctionHandler{
 		auctionUseCase: auctionUseCase,
-		connections:    make(map[uuid.UUID]map[*websocket.Conn]bool),
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
-		},
+		hub:            hub,
+		upgrader:       newUpgrader(allowedOrigins),
 	}
 }
 
@@ -50,13 +48,7 @@ func (h *AuctionHandler) CreateAuction(c *gin.Context) {
 		return
 	}
 
-	auction, err := h.auctionUseCase.CreateAuction(
-		userID.(uuid.UUID),
-		productID,
-		req.StartPrice,
-		req.MinBidIncrement,
-		req.DurationMinutes,
-	)
+	auction, err := h.auctionUseCase.CreateAuction(userID.(uuid.UUID), productID, &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -85,16 +77,83 @@ func (h *AuctionHandler) PlaceBid(c *gin.Context) {
 		return
 	}
 
-	bid, err := h.auctionUseCase.PlaceBid(auctionID, userID.(uuid.UUID), req.Amount)
+	outcome, err := h.auctionUseCase.PlaceBid(auctionID, userID.(uuid.UUID), req.Amount)
+	var outbid *domain.OutbidError
+	if errors.As(err, &outbid) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "min_bid": outbid.MinBid})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Broadcast bid to all connected clients
-	h.broadcastBid(auctionID, bid)
+	h.broadcastBid(auctionID, outcome.Bid)
+	h.broadcastOutcome(auctionID, outcome)
+
+	c.JSON(http.StatusCreated, outcome.Bid)
+}
+
+// SetProxyBid handles POST /auctions/:id/proxy-bid
+func (h *AuctionHandler) SetProxyBid(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	auctionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid auction ID"})
+		return
+	}
+
+	var req domain.ProxyBidRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	proxy, outcome, err := h.auctionUseCase.SetProxyBid(auctionID, userID.(uuid.UUID), req.MaxAmount)
+	var outbid *domain.OutbidError
+	if errors.As(err, &outbid) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "min_bid": outbid.MinBid})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.broadcastOutcome(auctionID, outcome)
 
-	c.JSON(http.StatusCreated, bid)
+	c.JSON(http.StatusOK, proxy)
+}
+
+// BuyNow handles POST /auctions/:id/buy-now
+func (h *AuctionHandler) BuyNow(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	auctionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid auction ID"})
+		return
+	}
+
+	auction, err := h.auctionUseCase.BuyNow(auctionID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.BroadcastAuctionEnded(auction)
+
+	c.JSON(http.StatusOK, auction)
 }
 
 // GetAuction handles GET /auctions/:id
@@ -125,6 +184,42 @@ func (h *AuctionHandler) GetActiveAuctions(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"auctions": auctions})
 }
 
+// GetUpcomingAuctions handles GET /auctions/upcoming
+func (h *AuctionHandler) GetUpcomingAuctions(c *gin.Context) {
+	auctions, err := h.auctionUseCase.GetUpcomingAuctions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"auctions": auctions})
+}
+
+// AcceptDutchPrice handles POST /auctions/:id/accept
+func (h *AuctionHandler) AcceptDutchPrice(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	auctionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid auction ID"})
+		return
+	}
+
+	auction, err := h.auctionUseCase.AcceptDutchPrice(auctionID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.BroadcastAuctionEnded(auction)
+
+	c.JSON(http.StatusOK, auction)
+}
+
 // GetAuctionBids handles GET /auctions/:id/bids
 func (h *AuctionHandler) GetAuctionBids(c *gin.Context) {
 	auctionID, err := uuid.Parse(c.Param("id"))
@@ -154,24 +249,17 @@ func (h *AuctionHandler) WebSocketHandler(c *gin.Context) {
 	if err != nil {
 		return
 	}
-	defer conn.Close()
+	client := h.hub.Connect(conn)
+	defer client.Close()
 
-	// Add connection
-	h.mu.Lock()
-	if h.connections[auctionID] == nil {
-		h.connections[auctionID] = make(map[*websocket.Conn]bool)
+	unsubscribe, err := h.hub.Subscribe(realtime.AuctionTopic(auctionID), client)
+	if err != nil {
+		log.Printf("Failed to subscribe to auction %s: %v", auctionID, err)
+		return
 	}
-	h.connections[auctionID][conn] = true
-	h.mu.Unlock()
-
-	// Remove connection on close
-	defer func() {
-		h.mu.Lock()
-		delete(h.connections[auctionID], conn)
-		h.mu.Unlock()
-	}()
+	defer unsubscribe()
 
-	// Keep connection alive
+	// Reading answers pings and notices the disconnect or an eviction
 	for {
 		_, _, err := conn.ReadMessage()
 		if err != nil {
@@ -181,18 +269,34 @@ func (h *AuctionHandler) WebSocketHandler(c *gin.Context) {
 }
 
 func (h *AuctionHandler) broadcastBid(auctionID uuid.UUID, bid *domain.Bid) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	h.broadcast(auctionID, "new_bid", bid)
+}
 
-	connections, exists := h.connections[auctionID]
-	if !exists {
-		return
+// broadcastOutcome sends proxy bids and any soft-close extension
+func (h *AuctionHandler) broadcastOutcome(auctionID uuid.UUID, outcome *domain.BidOutcome) {
+	for _, autoBid := range outcome.AutoBids {
+		h.broadcastBid(auctionID, autoBid)
 	}
 
-	for conn := range connections {
-		conn.WriteJSON(map[string]interface{}{
-			"type": "new_bid",
-			"data": bid,
+	if outcome.Extended {
+		h.broadcast(auctionID, "auction_extended", gin.H{
+			"auction_id": auctionID,
+			"end_time":   outcome.EndTime,
 		})
 	}
 }
+
+// BroadcastAuctionEnded tells watchers of an auction that it has closed
+func (h *AuctionHandler) BroadcastAuctionEnded(auction *domain.Auction) {
+	h.broadcast(auction.ID, "auction_ended", auction)
+}
+
+func (h *AuctionHandler) broadcast(auctionID uuid.UUID, msgType string, data interface{}) {
+	err := h.hub.Publish(context.Background(), realtime.AuctionTopic(auctionID), map[string]interface{}{
+		"type": msgType,
+		"data": data,
+	})
+	if err != nil {
+		log.Printf("Failed to publish %s event: %v", msgType, err)
+	}
+}
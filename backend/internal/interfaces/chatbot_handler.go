@@ -1,25 +1,23 @@
 package interfaces
 
 import (
-	"context"
-	"encoding/json"
-	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/google/uuid"
 	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+	"github.com/yourusername/ecomate/backend/internal/usecase"
 )
 
 type ChatbotHandler struct {
-	aiClient      *infrastructure.AIClient
-	productRepo   domain.ProductRepository
+	assistant usecase.ChatAssistantUseCase
+	agent     usecase.ChatAgentUseCase
 }
 
-func NewChatbotHandler(aiClient *infrastructure.AIClient, productRepo domain.ProductRepository) *ChatbotHandler {
+func NewChatbotHandler(assistant usecase.ChatAssistantUseCase, agent usecase.ChatAgentUseCase) *ChatbotHandler {
 	return &ChatbotHandler{
-		aiClient:    aiClient,
-		productRepo: productRepo,
+		assistant: assistant,
+		agent:     agent,
 	}
 }
 
@@ -34,7 +32,8 @@ type ChatMessage struct {
 }
 
 type ChatResponse struct {
-	Message string `json:"message"`
+	Message   string      `json:"message"`
+	Citations []uuid.UUID `json:"citations"` // IDs of the products the reply is based on
 }
 
 // Chat handles AI chatbot requests
@@ -45,113 +44,160 @@ func (h *ChatbotHandler) Chat(c *gin.Context) {
 		return
 	}
 
-	// Get products data from database
-	ctx := context.Background()
-	products, _, err := h.productRepo.List(&domain.ProductFilters{
-		Limit: 20,
-	})
+	// Get AI response
+	reply, err := h.assistant.Reply(c.Request.Context(), GetUserIDFromContext(c), req.toMessages(), req.Context)
 	if err != nil {
-		// Continue without product data
-		products = []*domain.Product{}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate response"})
+		return
 	}
 
-	// Build product context for AI
-	productContext := h.buildProductContext(products)
+	c.JSON(http.StatusOK, newChatResponse(reply))
+}
 
-	// Convert to infrastructure types
-	messages := make([]infrastructure.ChatMessage, len(req.Messages))
-	for i, msg := range req.Messages {
-		messages[i] = infrastructure.ChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
+// ChatStream relays the reply as server-sent events while it is generated:
+// "token" events carry text as it arrives, then "done" carries the full
+// message and cited products, or "error" reports a failure. Closing the
+// connection stops generation.
+func (h *ChatbotHandler) ChatStream(c *gin.Context) {
+	var req ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	ctx := c.Request.Context()
+	reply, err := h.assistant.StreamReply(ctx, GetUserIDFromContext(c), req.toMessages(), req.Context, func(token string) error {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
+		c.SSEvent("token", gin.H{"text": token})
+		c.Writer.Flush()
+		return nil
+	})
+	if ctx.Err() != nil {
+		// The client has gone; nobody is left to tell
+		return
 	}
+	if err != nil {
+		c.SSEvent("error", gin.H{"error": "Failed to generate response"})
+		c.Writer.Flush()
+		return
+	}
+
+	c.SSEvent("done", newChatResponse(reply))
+	c.Writer.Flush()
+}
 
-	chatReq := &infrastructure.ChatRequest{
-		Messages: messages,
-		Context:  productContext,
+// Agent lets the chatbot search, favorite, make offers and check shipping for
+// the signed-in user. Favorites and offers come back as a pending action.
+func (h *ChatbotHandler) Agent(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
 	}
 
-	// Get AI response
-	response, err := h.aiClient.Chat(ctx, chatReq)
+	var req ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reply, err := h.agent.Run(c.Request.Context(), userID.(uuid.UUID), req.toMessages())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate response"})
 		return
 	}
 
-	c.JSON(http.StatusOK, ChatResponse{
-		Message: response,
-	})
+	c.JSON(http.StatusOK, reply)
 }
 
-func (h *ChatbotHandler) buildProductContext(products []*domain.Product) string {
-	if len(products) == 0 {
-		return ""
-	}
-
-	type ProductInfo struct {
-		ID          string `json:"id"`
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Price       int    `json:"price"`
-		Category    string `json:"category"`
-		Condition   string `json:"condition"`
-		CO2Impact   float64 `json:"co2_impact_kg"`
-		ImageURL    string `json:"image_url"`
-	}
-
-	productList := make([]ProductInfo, 0, len(products))
-	for _, p := range products {
-		imageURL := ""
-		if len(p.Images) > 0 {
-			for _, img := range p.Images {
-				if img.IsPrimary {
-					if img.CDNURL != "" {
-						imageURL = img.CDNURL
-					} else {
-						imageURL = img.ImageURL
-					}
-					break
-				}
-			}
-			if imageURL == "" && len(p.Images) > 0 {
-				if p.Images[0].CDNURL != "" {
-					imageURL = p.Images[0].CDNURL
-				} else {
-					imageURL = p.Images[0].ImageURL
-				}
-			}
-		}
+// ConfirmAction runs an action the chatbot proposed
+func (h *ChatbotHandler) ConfirmAction(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
 
-		productList = append(productList, ProductInfo{
-			ID:          p.ID.String(),
-			Title:       p.Title,
-			Description: p.Description,
-			Price:       p.Price,
-			Category:    p.Category,
-			Condition:   string(p.Condition),
-			CO2Impact:   p.CO2ImpactKg,
-			ImageURL:    imageURL,
-		})
+	callID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid action ID"})
+		return
 	}
 
-	jsonData, err := json.Marshal(productList)
+	call, err := h.agent.ConfirmAction(userID.(uuid.UUID), callID)
 	if err != nil {
-		return ""
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, call)
+}
+
+// RejectAction discards an action the chatbot proposed
+func (h *ChatbotHandler) RejectAction(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	callID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid action ID"})
+		return
+	}
+
+	call, err := h.agent.RejectAction(userID.(uuid.UUID), callID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	context := fmt.Sprintf(`
-利用可能な商品リスト（JSON形式）:
-%s
+	c.JSON(http.StatusOK, call)
+}
 
-商品を推薦する時は、以下のフォーマットを使用してください：
-[PRODUCT:商品ID:商品名:商品画像URL]
+// GetActions lists the tools the chatbot invoked for the user, newest first
+func (h *ChatbotHandler) GetActions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
 
-例: [PRODUCT:123e4567-e89b-12d3-a456-426614174000:ハイトップスニーカー:https://example.com/image.jpg]
+	calls, err := h.agent.GetToolCalls(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-このフォーマットを使うと、フロントエンドで商品カードとして表示されます。
-複数の商品を推薦する場合は、改行して複数の[PRODUCT]タグを含めてください。
-`, string(jsonData))
+	c.JSON(http.StatusOK, gin.H{"actions": calls})
+}
 
-	return context
+func newChatResponse(reply *usecase.ChatReply) ChatResponse {
+	citations := reply.Citations
+	if citations == nil {
+		citations = []uuid.UUID{}
+	}
+	return ChatResponse{
+		Message:   reply.Message,
+		Citations: citations,
+	}
+}
+
+func (r *ChatRequest) toMessages() []infrastructure.ChatMessage {
+	messages := make([]infrastructure.ChatMessage, len(r.Messages))
+	for i, msg := range r.Messages {
+		messages[i] = infrastructure.ChatMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		}
+	}
+	return messages
 }
@@ -0,0 +1,236 @@
+package interfaces
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/usecase"
+)
+
+type DisputeHandler struct {
+	disputeUseCase usecase.DisputeUseCase
+	uploadHandler  *UploadHandler
+}
+
+func NewDisputeHandler(disputeUseCase usecase.DisputeUseCase, uploadHandler *UploadHandler) *DisputeHandler {
+	return &DisputeHandler{
+		disputeUseCase: disputeUseCase,
+		uploadHandler:  uploadHandler,
+	}
+}
+
+func (h *DisputeHandler) Open(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req domain.OpenDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dispute, err := h.disputeUseCase.Open(userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dispute)
+}
+
+func (h *DisputeHandler) List(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	disputes, err := h.disputeUseCase.ListByUser(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"disputes": disputes})
+}
+
+func (h *DisputeHandler) GetByID(c *gin.Context) {
+	h.get(c, false)
+}
+
+// AddEvidence accepts an image in the "image" form field, stored the same way
+// as listing images, with an optional "caption". The stored image is removed
+// again if the evidence is rejected.
+func (h *DisputeHandler) AddEvidence(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	// Check access and limits before anything is written to disk
+	dispute, err := h.disputeUseCase.GetByID(id, userID.(uuid.UUID), false)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if dispute.Status == domain.DisputeStatusResolved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dispute is already resolved"})
+		return
+	}
+	if len(dispute.Evidence) >= domain.MaxDisputeEvidence {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("a dispute can have at most %d evidence images", domain.MaxDisputeEvidence)})
+		return
+	}
+
+	if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File too large"})
+		return
+	}
+
+	_, header, err := c.Request.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+
+	upload, err := h.uploadHandler.saveImage(header)
+	if err == errNotImage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only image files are allowed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		return
+	}
+
+	evidence, err := h.disputeUseCase.AddEvidence(id, userID.(uuid.UUID), upload.URL, c.PostForm("caption"))
+	if err != nil {
+		// The dispute may have changed since it was checked
+		_ = h.uploadHandler.deleteImage(upload.Filename)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, evidence)
+}
+
+func (h *DisputeHandler) PostMessage(c *gin.Context) {
+	h.postMessage(c, false)
+}
+
+// Moderator endpoints
+
+func (h *DisputeHandler) Queue(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	status := domain.DisputeStatus(c.Query("status"))
+
+	disputes, pagination, err := h.disputeUseCase.Queue(status, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"disputes":   disputes,
+		"pagination": pagination,
+	})
+}
+
+func (h *DisputeHandler) ModeratorGetByID(c *gin.Context) {
+	h.get(c, true)
+}
+
+func (h *DisputeHandler) ModeratorPostMessage(c *gin.Context) {
+	h.postMessage(c, true)
+}
+
+func (h *DisputeHandler) Assign(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	dispute, err := h.disputeUseCase.Assign(id, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dispute)
+}
+
+func (h *DisputeHandler) Resolve(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req domain.ResolveDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dispute, err := h.disputeUseCase.Resolve(id, userID.(uuid.UUID), &req)
+	if err != nil {
+		if err.Error() == "unauthorized: dispute is assigned to another moderator" {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dispute)
+}
+
+func (h *DisputeHandler) get(c *gin.Context, moderator bool) {
+	userID, _ := c.Get("user_id")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	dispute, err := h.disputeUseCase.GetByID(id, userID.(uuid.UUID), moderator)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dispute)
+}
+
+func (h *DisputeHandler) postMessage(c *gin.Context, moderator bool) {
+	userID, _ := c.Get("user_id")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req domain.DisputeMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := h.disputeUseCase.PostMessage(id, userID.(uuid.UUID), &req, moderator)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
@@ -1,37 +1,36 @@
 package interfaces
 
 import (
-	"encoding/json"
+	"context"
+	"io"
 	"log"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"strconv"
-	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/realtime"
 	"github.com/yourusername/ecomate/backend/internal/usecase"
 )
 
 type MessageHandler struct {
 	messageUseCase usecase.MessageUseCase
 	authUseCase    *usecase.AuthUseCase
+	hub            *realtime.Hub
 	upgrader       websocket.Upgrader
-	connections    map[uuid.UUID]map[*websocket.Conn]bool // conversationID -> connections
-	mu             sync.RWMutex
 }
 
-func NewMessageHandler(messageUseCase usecase.MessageUseCase, authUseCase *usecase.AuthUseCase) *MessageHandler {
+func NewMessageHandler(messageUseCase usecase.MessageUseCase, authUseCase *usecase.AuthUseCase, hub *realtime.Hub, allowedOrigins []string) *MessageHandler {
 	return &MessageHandler{
 		messageUseCase: messageUseCase,
 		authUseCase:    authUseCase,
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // In production, check origin properly
-			},
-		},
-		connections: make(map[uuid.UUID]map[*websocket.Conn]bool),
+		hub:            hub,
+		upgrader:       newUpgrader(allowedOrigins),
 	}
 }
 
@@ -70,7 +69,11 @@ func (h *MessageHandler) GetOrCreateConversation(c *gin.Context) {
 
 	conversation, err := h.messageUseCase.GetOrCreateConversation(productID, userID.(uuid.UUID), sellerID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		status := http.StatusBadRequest
+		if err.Error() == "product not found" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -90,6 +93,8 @@ func (h *MessageHandler) ListConversations(c *gin.Context) {
 }
 
 func (h *MessageHandler) GetMessages(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
 	conversationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid conversation id"})
@@ -98,16 +103,24 @@ func (h *MessageHandler) GetMessages(c *gin.Context) {
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	cursor := c.Query("cursor")
+	if cursor != "" {
+		if _, err := domain.DecodePageCursor(cursor); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	messages, pagination, err := h.messageUseCase.GetMessages(conversationID, page, limit)
+	messages, pagination, err := h.messageUseCase.GetMessages(conversationID, userID.(uuid.UUID), page, limit, cursor)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(conversationErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"messages":   messages,
-		"pagination": pagination,
+		"messages":    messages,
+		"pagination":  pagination,
+		"next_cursor": pagination.NextCursor,
 	})
 }
 
@@ -128,15 +141,11 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 
 	message, err := h.messageUseCase.SendMessage(conversationID, userID.(uuid.UUID), req.Content)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(conversationErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
 		return
 	}
 
-	// Broadcast to WebSocket connections
-	h.broadcast(conversationID, domain.WSMessage{
-		Type: domain.WSMessageTypeMessage,
-		Data: message,
-	})
+	h.publishMessage(conversationID, message)
 
 	c.JSON(http.StatusCreated, message)
 }
@@ -148,62 +157,58 @@ func (h *MessageHandler) WebSocketHandler(c *gin.Context) {
 		return
 	}
 
+	// A token sent with the handshake lets non-participants be refused before
+	// upgrading; otherwise the first message on the socket must carry it
+	var userID uuid.UUID
+	if token := handshakeToken(c.Request); token != "" {
+		userID, err = h.authUseCase.ValidateToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			return
+		}
+		if _, err := h.messageUseCase.GetConversation(conversationID, userID); err != nil {
+			c.JSON(conversationErrorStatus(err, http.StatusForbidden), gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("Failed to upgrade connection: %v", err)
 		return
 	}
+	client := h.hub.Connect(conn)
+	defer client.Close()
 
-	var userID uuid.UUID
-	authenticated := false
-
-	// Wait for auth message
-	var authMsg domain.WSMessage
-	if err := conn.ReadJSON(&authMsg); err != nil {
-		conn.Close()
-		return
-	}
-
-	if authMsg.Type == domain.WSMessageTypeAuth && authMsg.Token != "" {
-		validatedUserID, err := h.authUseCase.ValidateToken(authMsg.Token)
-		if err == nil {
-			userID = validatedUserID
-			authenticated = true
-
-			// Register connection
-			h.mu.Lock()
-			if h.connections[conversationID] == nil {
-				h.connections[conversationID] = make(map[*websocket.Conn]bool)
-			}
-			h.connections[conversationID][conn] = true
-			h.mu.Unlock()
+	if userID == uuid.Nil {
+		var ok bool
+		if userID, ok = h.authenticate(conn, client); !ok {
+			return
+		}
 
-			// Send success
-			conn.WriteJSON(domain.WSMessage{
+		// Only participants may listen to a conversation
+		if _, err := h.messageUseCase.GetConversation(conversationID, userID); err != nil {
+			client.WriteJSON(domain.WSMessage{
 				Type: domain.WSMessageTypeAuth,
-				Data: gin.H{"status": "authenticated"},
+				Data: gin.H{"error": err.Error()},
 			})
+			return
 		}
 	}
 
-	if !authenticated {
-		conn.WriteJSON(domain.WSMessage{
-			Type: domain.WSMessageTypeAuth,
-			Data: gin.H{"error": "authentication failed"},
-		})
-		conn.Close()
+	unsubscribe, err := h.hub.SubscribeAs(realtime.ConversationTopic(conversationID), userID.String(), client)
+	if err != nil {
+		log.Printf("Failed to subscribe to conversation %s: %v", conversationID, err)
 		return
 	}
+	defer unsubscribe()
 
-	// Clean up on disconnect
+	typing := &typingThrottle{}
 	defer func() {
-		h.mu.Lock()
-		delete(h.connections[conversationID], conn)
-		if len(h.connections[conversationID]) == 0 {
-			delete(h.connections, conversationID)
+		// Leaving mid-sentence stops the indicator
+		if typing.update(false, time.Now()) {
+			h.publishTyping(conversationID, userID, false)
 		}
-		h.mu.Unlock()
-		conn.Close()
 	}()
 
 	// Handle incoming messages
@@ -218,30 +223,351 @@ func (h *MessageHandler) WebSocketHandler(c *gin.Context) {
 			if msg.Content != "" {
 				message, err := h.messageUseCase.SendMessage(conversationID, userID, msg.Content)
 				if err == nil {
-					h.broadcast(conversationID, domain.WSMessage{
-						Type: domain.WSMessageTypeMessage,
-						Data: message,
-					})
+					if typing.update(false, time.Now()) {
+						h.publishTyping(conversationID, userID, false)
+					}
+					h.publishMessage(conversationID, message)
 				}
 			}
 		case domain.WSMessageTypeTyping:
-			h.broadcast(conversationID, domain.WSMessage{
-				Type: domain.WSMessageTypeTyping,
-				Data: gin.H{"user_id": userID},
+			isTyping := typingState(msg.Data)
+			if typing.update(isTyping, time.Now()) {
+				h.publishTyping(conversationID, userID, isTyping)
+			}
+		case domain.WSMessageTypeMarkRead:
+			var receipt *domain.ReadReceipt
+			if msg.MessageID != nil {
+				receipt, err = h.messageUseCase.MarkMessageRead(conversationID, *msg.MessageID, userID)
+			} else {
+				receipt, err = h.messageUseCase.MarkConversationRead(conversationID, userID, msg.ReadUpTo)
+			}
+			if err == nil {
+				h.publishReceipt(receipt)
+			}
+		}
+	}
+}
+
+// MarkRead handles POST /conversations/:id/read, the REST form of a "read up
+// to" receipt
+func (h *MessageHandler) MarkRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid conversation id"})
+		return
+	}
+
+	// The body is optional: without it everything is read
+	var req domain.MarkReadRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	receipt, err := h.messageUseCase.MarkConversationRead(conversationID, userID.(uuid.UUID), req.ReadUpTo)
+	if err != nil {
+		c.JSON(conversationErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.publishReceipt(receipt)
+
+	c.JSON(http.StatusOK, receipt)
+}
+
+// MarkMessageRead handles POST /conversations/:id/messages/:messageId/read
+func (h *MessageHandler) MarkMessageRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid conversation id"})
+		return
+	}
+
+	messageID, err := uuid.Parse(c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		return
+	}
+
+	receipt, err := h.messageUseCase.MarkMessageRead(conversationID, messageID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(conversationErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.publishReceipt(receipt)
+
+	c.JSON(http.StatusOK, receipt)
+}
+
+// UserWebSocketHandler streams the events of the signed-in user, such as new
+// messages in any of their conversations
+func (h *MessageHandler) UserWebSocketHandler(c *gin.Context) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade connection: %v", err)
+		return
+	}
+	client := h.hub.Connect(conn)
+	defer client.Close()
+
+	userID, ok := h.authenticate(conn, client)
+	if !ok {
+		return
+	}
+
+	unsubscribe, err := h.hub.Subscribe(realtime.UserTopic(userID), client)
+	if err != nil {
+		log.Printf("Failed to subscribe to user %s: %v", userID, err)
+		return
+	}
+	defer unsubscribe()
+
+	// Nothing is expected from the client; reading notices the disconnect
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+}
+
+// authenticate waits for the auth message that must open every socket
+func (h *MessageHandler) authenticate(conn *websocket.Conn, client *realtime.ConnClient) (uuid.UUID, bool) {
+	var authMsg domain.WSMessage
+	if err := conn.ReadJSON(&authMsg); err != nil {
+		return uuid.Nil, false
+	}
+
+	if authMsg.Type == domain.WSMessageTypeAuth && authMsg.Token != "" {
+		if userID, err := h.authUseCase.ValidateToken(authMsg.Token); err == nil {
+			client.WriteJSON(domain.WSMessage{
+				Type: domain.WSMessageTypeAuth,
+				Data: gin.H{"status": "authenticated"},
 			})
+			return userID, true
+		}
+	}
+
+	client.WriteJSON(domain.WSMessage{
+		Type: domain.WSMessageTypeAuth,
+		Data: gin.H{"error": "authentication failed"},
+	})
+	return uuid.Nil, false
+}
+
+// publishMessage sends a new message to the open conversation and tells the
+// other participants wherever they are
+func (h *MessageHandler) publishMessage(conversationID uuid.UUID, message *domain.Message) {
+	event := domain.WSMessage{
+		Type: domain.WSMessageTypeMessage,
+		Data: message,
+	}
+	h.publish(realtime.ConversationTopic(conversationID), event)
+
+	conversation, err := h.messageUseCase.GetConversation(conversationID, message.SenderID)
+	if err != nil {
+		return
+	}
+	for _, participant := range conversation.Participants {
+		if participant.UserID != message.SenderID {
+			h.publish(realtime.UserTopic(participant.UserID), event)
+		}
+	}
+}
+
+// SendAttachments handles POST /conversations/:id/attachments: up to
+// usecase.MaxAttachmentsPerMessage images or PDFs in the "files" form field,
+// with optional "content" text, sent as one message
+func (h *MessageHandler) SendAttachments(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid conversation id"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, usecase.MaxAttachmentsPerMessage*usecase.MaxAttachmentSize+1<<20)
+	if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Files too large"})
+		return
+	}
+
+	files := c.Request.MultipartForm.File["files"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No files uploaded"})
+		return
+	}
+	if len(files) > usecase.MaxAttachmentsPerMessage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many attachments"})
+		return
+	}
+
+	uploads := make([]domain.AttachmentUpload, 0, len(files))
+	for _, header := range files {
+		if header.Size > usecase.MaxAttachmentSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "attachment is too large"})
+			return
+		}
+		data, err := readUpload(header)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+			return
 		}
+		uploads = append(uploads, domain.AttachmentUpload{Filename: header.Filename, Data: data})
+	}
+
+	message, err := h.messageUseCase.SendAttachments(conversationID, userID.(uuid.UUID), c.Request.FormValue("content"), uploads)
+	if err != nil {
+		c.JSON(conversationErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.publishMessage(conversationID, message)
+
+	c.JSON(http.StatusCreated, message)
+}
+
+// GetAttachment handles GET /conversations/:id/attachments/:attachmentId
+func (h *MessageHandler) GetAttachment(c *gin.Context) {
+	h.serveAttachment(c, false)
+}
+
+// GetAttachmentThumbnail handles GET
+// /conversations/:id/attachments/:attachmentId/thumbnail
+func (h *MessageHandler) GetAttachmentThumbnail(c *gin.Context) {
+	h.serveAttachment(c, true)
+}
+
+func (h *MessageHandler) serveAttachment(c *gin.Context, thumbnail bool) {
+	userID, _ := c.Get("user_id")
+
+	conversationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid conversation id"})
+		return
+	}
+
+	attachmentID, err := uuid.Parse(c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment id"})
+		return
+	}
+
+	attachment, file, err := h.messageUseCase.OpenAttachment(conversationID, attachmentID, userID.(uuid.UUID), thumbnail)
+	if err != nil {
+		c.JSON(conversationErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	contentType, size := attachment.ContentType, attachment.Size
+	if thumbnail {
+		contentType, size = "image/jpeg", -1
+	}
+
+	// Participants only, so shared caches must not keep it
+	c.DataFromReader(http.StatusOK, size, contentType, file, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("inline", map[string]string{"filename": attachment.Filename}),
+		"Cache-Control":          "private, max-age=3600",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+func readUpload(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// publishReceipt tells the open conversation and every participant's other
+// screens, such as unread badges, which messages were read
+func (h *MessageHandler) publishReceipt(receipt *domain.ReadReceipt) {
+	if len(receipt.MessageIDs) == 0 {
+		return
+	}
+
+	event := domain.WSMessage{
+		Type: domain.WSMessageTypeRead,
+		Data: receipt,
+	}
+	h.publish(realtime.ConversationTopic(receipt.ConversationID), event)
+
+	conversation, err := h.messageUseCase.GetConversation(receipt.ConversationID, receipt.UserID)
+	if err != nil {
+		return
+	}
+	for _, participant := range conversation.Participants {
+		h.publish(realtime.UserTopic(participant.UserID), event)
+	}
+}
+
+// publishTyping tells the other participants in the conversation; the
+// typist's own sockets are left out
+func (h *MessageHandler) publishTyping(conversationID, userID uuid.UUID, isTyping bool) {
+	msg := domain.WSMessage{
+		Type: domain.WSMessageTypeTyping,
+		Data: gin.H{"user_id": userID, "typing": isTyping},
+	}
+	if err := h.hub.PublishExcept(context.Background(), realtime.ConversationTopic(conversationID), userID.String(), msg); err != nil {
+		log.Printf("Failed to publish %s event: %v", msg.Type, err)
 	}
 }
 
-func (h *MessageHandler) broadcast(conversationID uuid.UUID, msg domain.WSMessage) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+// typingRefresh is how often a client that keeps typing is announced again
+const typingRefresh = 3 * time.Second
+
+// typingThrottle passes on changes of a socket's typing state, and repeats
+// of "typing" no more than every typingRefresh
+type typingThrottle struct {
+	typing   bool
+	lastSent time.Time
+}
 
-	msgBytes, _ := json.Marshal(msg)
+func (t *typingThrottle) update(typing bool, now time.Time) bool {
+	if typing == t.typing && (!typing || now.Sub(t.lastSent) < typingRefresh) {
+		return false
+	}
+	t.typing = typing
+	t.lastSent = now
+	return true
+}
 
-	for conn := range h.connections[conversationID] {
-		if err := conn.WriteMessage(websocket.TextMessage, msgBytes); err != nil {
-			log.Printf("Error broadcasting: %v", err)
+// typingState reads {"typing": false} from a typing event; clients that send
+// no data are typing
+func typingState(data interface{}) bool {
+	if fields, ok := data.(map[string]interface{}); ok {
+		if typing, ok := fields["typing"].(bool); ok {
+			return typing
 		}
 	}
+	return true
+}
+
+// conversationErrorStatus maps the access errors of the message use case to
+// their HTTP status
+func conversationErrorStatus(err error, fallback int) int {
+	switch err.Error() {
+	case "conversation not found", "message not found", "attachment not found":
+		return http.StatusNotFound
+	case "user is not a participant of this conversation":
+		return http.StatusForbidden
+	default:
+		return fallback
+	}
+}
+
+func (h *MessageHandler) publish(topic string, msg domain.WSMessage) {
+	if err := h.hub.Publish(context.Background(), topic, msg); err != nil {
+		log.Printf("Failed to publish %s event: %v", msg.Type, err)
+	}
 }
@@ -0,0 +1,506 @@
+package interfaces_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+	"github.com/yourusername/ecomate/backend/internal/interfaces"
+	"github.com/yourusername/ecomate/backend/internal/realtime"
+	"github.com/yourusername/ecomate/backend/internal/usecase"
+)
+
+const (
+	testJWTSecret = "test-secret"
+	allowedOrigin = "http://localhost:3000"
+)
+
+// memMessageRepository keeps conversations and messages in memory
+type memMessageRepository struct {
+	domain.MessageRepository
+	mu            sync.Mutex
+	conversations map[uuid.UUID]*domain.Conversation
+	messages      []*domain.Message
+}
+
+func (r *memMessageRepository) FindConversationByID(id uuid.UUID) (*domain.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if conversation, ok := r.conversations[id]; ok {
+		return conversation, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memMessageRepository) CreateMessage(message *domain.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	message.ID = uuid.New()
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func (r *memMessageRepository) GetMessages(conversationID uuid.UUID, page, limit int, cursor string) ([]*domain.Message, *domain.PaginationResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var messages []*domain.Message
+	for _, message := range r.messages {
+		if message.ConversationID == conversationID {
+			messages = append(messages, message)
+		}
+	}
+	return messages, &domain.PaginationResponse{Page: page, Limit: limit, Total: len(messages)}, nil
+}
+
+func (r *memMessageRepository) FindMessageByID(id uuid.UUID) (*domain.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, message := range r.messages {
+		if message.ID == id {
+			return message, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memMessageRepository) MarkAsRead(messageID, userID uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, message := range r.messages {
+		if message.ID == messageID && message.SenderID != userID && !message.IsRead {
+			message.IsRead = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memMessageRepository) MarkReadUpTo(conversationID, userID uuid.UUID, upTo time.Time) ([]uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := []uuid.UUID{}
+	for _, message := range r.messages {
+		if message.ConversationID == conversationID && message.SenderID != userID && !message.IsRead && !message.CreatedAt.After(upTo) {
+			message.IsRead = true
+			ids = append(ids, message.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *memMessageRepository) FindAttachmentByID(id uuid.UUID) (*domain.MessageAttachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, message := range r.messages {
+		for _, attachment := range message.Attachments {
+			if attachment.ID == id {
+				return &attachment, nil
+			}
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memMessageRepository) FindConversationByParticipants(productID uuid.UUID, userID1, userID2 uuid.UUID) (*domain.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, conversation := range r.conversations {
+		if conversation.ProductID == nil || *conversation.ProductID != productID {
+			continue
+		}
+		found := 0
+		for _, p := range conversation.Participants {
+			if p.UserID == userID1 || p.UserID == userID2 {
+				found++
+			}
+		}
+		if found == 2 {
+			return conversation, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memMessageRepository) CreateConversation(conversation *domain.Conversation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conversation.ID = uuid.New()
+	r.conversations[conversation.ID] = conversation
+	return nil
+}
+
+// memProductRepository serves the products conversations are about
+type memProductRepository struct {
+	domain.ProductRepository
+	products map[uuid.UUID]*domain.Product
+}
+
+func (r *memProductRepository) FindByID(id uuid.UUID) (*domain.Product, error) {
+	if product, ok := r.products[id]; ok {
+		return product, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+type messageFixture struct {
+	server       *httptest.Server
+	conversation uuid.UUID
+	product      uuid.UUID
+	buyer        uuid.UUID
+	seller       uuid.UUID
+	stranger     uuid.UUID
+}
+
+func newMessageFixture(t *testing.T) *messageFixture {
+	gin.SetMode(gin.TestMode)
+
+	buyer, seller := uuid.New(), uuid.New()
+	conversation := &domain.Conversation{
+		ID: uuid.New(),
+		Participants: []domain.ConversationParticipant{
+			{UserID: buyer},
+			{UserID: seller},
+		},
+	}
+	repo := &memMessageRepository{conversations: map[uuid.UUID]*domain.Conversation{conversation.ID: conversation}}
+	product := &domain.Product{ID: uuid.New(), SellerID: seller}
+	products := &memProductRepository{products: map[uuid.UUID]*domain.Product{product.ID: product}}
+
+	authUseCase := usecase.NewAuthUseCase(nil, testJWTSecret, 1)
+	hub := realtime.NewHub(realtime.NewMemoryBroker(), realtime.ConnOptions{})
+	handler := interfaces.NewMessageHandler(usecase.NewMessageUseCase(repo, products, infrastructure.NewLocalImageStorage(t.TempDir(), "")), authUseCase, hub, []string{allowedOrigin})
+
+	router := gin.New()
+	conversations := router.Group("/v1/conversations", interfaces.AuthMiddleware(authUseCase))
+	conversations.GET("/product/:productId/seller/:sellerId", handler.GetOrCreateConversation)
+	conversations.GET("/:id/messages", handler.GetMessages)
+	conversations.POST("/:id/messages", handler.SendMessage)
+	conversations.POST("/:id/read", handler.MarkRead)
+	conversations.POST("/:id/attachments", handler.SendAttachments)
+	conversations.GET("/:id/attachments/:attachmentId", handler.GetAttachment)
+	conversations.GET("/:id/attachments/:attachmentId/thumbnail", handler.GetAttachmentThumbnail)
+	router.GET("/v1/ws/conversations/:id", handler.WebSocketHandler)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return &messageFixture{server: server, conversation: conversation.ID, product: product.ID, buyer: buyer, seller: seller, stranger: uuid.New()}
+}
+
+func token(t *testing.T, userID uuid.UUID) string {
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID.String(),
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testJWTSecret))
+	require.NoError(t, err)
+	return signed
+}
+
+func (f *messageFixture) request(t *testing.T, method string, userID uuid.UUID, body string) int {
+	req, err := http.NewRequest(method, f.server.URL+"/v1/conversations/"+f.conversation.String()+"/messages", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token(t, userID))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// dial opens the conversation's socket, offering accessToken with the
+// handshake as a browser does
+func (f *messageFixture) dial(accessToken string, origin string) (*websocket.Conn, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(f.server.URL, "http") + "/v1/ws/conversations/" + f.conversation.String()
+	header := http.Header{}
+	if origin != "" {
+		header.Set("Origin", origin)
+	}
+	dialer := *websocket.DefaultDialer
+	if accessToken != "" {
+		dialer.Subprotocols = []string{"bearer", accessToken}
+	}
+	return dialer.Dial(url, header)
+}
+
+func TestMessageHandler_NonParticipantsAreForbidden(t *testing.T) {
+	f := newMessageFixture(t)
+
+	// Participants read and write
+	assert.Equal(t, http.StatusCreated, f.request(t, http.MethodPost, f.buyer, `{"content": "Is this still available?"}`))
+	assert.Equal(t, http.StatusOK, f.request(t, http.MethodGet, f.buyer, ""))
+
+	// Anyone else is refused
+	assert.Equal(t, http.StatusForbidden, f.request(t, http.MethodPost, f.stranger, `{"content": "hello"}`))
+	assert.Equal(t, http.StatusForbidden, f.request(t, http.MethodGet, f.stranger, ""))
+
+	_, resp, err := f.dial(token(t, f.stranger), allowedOrigin)
+	require.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	conn, _, err := f.dial(token(t, f.buyer), allowedOrigin)
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestMessageHandler_GetOrCreateConversationChecksTheSeller(t *testing.T) {
+	f := newMessageFixture(t)
+	open := func(productID, sellerID uuid.UUID) (int, *domain.Conversation) {
+		req, err := http.NewRequest(http.MethodGet, f.server.URL+"/v1/conversations/product/"+productID.String()+"/seller/"+sellerID.String(), nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token(t, f.stranger))
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var conversation domain.Conversation
+		json.NewDecoder(resp.Body).Decode(&conversation)
+		return resp.StatusCode, &conversation
+	}
+
+	// A buyer may only open a conversation with the product's seller
+	status, _ := open(f.product, f.buyer)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = open(uuid.New(), f.seller)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, conversation := open(f.product, f.seller)
+	require.Equal(t, http.StatusOK, status)
+	_, again := open(f.product, f.seller)
+	assert.Equal(t, conversation.ID, again.ID, "the conversation is reused")
+}
+
+func TestMessageHandler_WebSocketTakesTokensOutsideTheURL(t *testing.T) {
+	f := newMessageFixture(t)
+	url := "ws" + strings.TrimPrefix(f.server.URL, "http") + "/v1/ws/conversations/" + f.conversation.String()
+
+	// Browsers offer the token as a subprotocol; only "bearer" is echoed
+	conn, resp, err := f.dial(token(t, f.buyer), allowedOrigin)
+	require.NoError(t, err)
+	conn.Close()
+	assert.Equal(t, "bearer", resp.Header.Get("Sec-WebSocket-Protocol"))
+
+	// Other clients may send an Authorization header
+	_, resp, err = websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer " + token(t, f.stranger)}})
+	require.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, resp, err = f.dial("not-a-token", allowedOrigin)
+	require.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// A token in the query string is ignored, so the socket waits for the
+	// auth message
+	conn, _, err = websocket.DefaultDialer.Dial(url+"?token="+token(t, f.stranger), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.WriteJSON(domain.WSMessage{Type: domain.WSMessageTypeAuth, Token: token(t, f.buyer)}))
+	var authenticated domain.WSMessage
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	require.NoError(t, conn.ReadJSON(&authenticated))
+	assert.Equal(t, map[string]interface{}{"status": "authenticated"}, authenticated.Data)
+}
+
+func TestMessageHandler_WebSocketRefusesStrangersAfterAuthMessage(t *testing.T) {
+	f := newMessageFixture(t)
+
+	conn, _, err := f.dial("", allowedOrigin)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.WriteJSON(domain.WSMessage{Type: domain.WSMessageTypeAuth, Token: token(t, f.stranger)}))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var authenticated, refused domain.WSMessage
+	require.NoError(t, conn.ReadJSON(&authenticated))
+	require.NoError(t, conn.ReadJSON(&refused))
+	assert.Equal(t, map[string]interface{}{"error": "user is not a participant of this conversation"}, refused.Data)
+
+	_, _, err = conn.ReadMessage()
+	assert.Error(t, err, "the socket is closed")
+}
+
+func TestMessageHandler_WebSocketChecksOrigin(t *testing.T) {
+	f := newMessageFixture(t)
+
+	_, resp, err := f.dial(token(t, f.buyer), "http://evil.example")
+	require.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Clients that are not browsers send no origin
+	conn, _, err := f.dial(token(t, f.buyer), "")
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestMessageHandler_TypingAndReadReceipts(t *testing.T) {
+	f := newMessageFixture(t)
+	connect := func(userID uuid.UUID) *websocket.Conn {
+		conn, _, err := f.dial(token(t, userID), allowedOrigin)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	next := func(conn *websocket.Conn) domain.WSMessage {
+		var msg domain.WSMessage
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		require.NoError(t, conn.ReadJSON(&msg))
+		return msg
+	}
+
+	buyer := connect(f.buyer)
+	seller := connect(f.seller)
+
+	// The buyer's typing reaches the seller once, and not the buyer
+	for i := 0; i < 3; i++ {
+		require.NoError(t, buyer.WriteJSON(gin.H{"type": "typing", "data": gin.H{"typing": true}}))
+	}
+	typing := next(seller)
+	assert.Equal(t, domain.WSMessageTypeTyping, typing.Type)
+	assert.Equal(t, map[string]interface{}{"user_id": f.buyer.String(), "typing": true}, typing.Data)
+
+	// Sending ends the indicator
+	require.NoError(t, buyer.WriteJSON(domain.WSMessage{Type: domain.WSMessageTypeSend, Content: "Would you take 800?"}))
+	assert.Equal(t, map[string]interface{}{"user_id": f.buyer.String(), "typing": false}, next(seller).Data)
+	sent := next(seller)
+	assert.Equal(t, domain.WSMessageTypeMessage, sent.Type)
+	assert.Equal(t, domain.WSMessageTypeMessage, next(buyer).Type, "the buyer sees their own message but no typing")
+
+	// The seller reads it; both sides get the receipt
+	require.NoError(t, seller.WriteJSON(domain.WSMessage{Type: domain.WSMessageTypeMarkRead}))
+	for _, conn := range []*websocket.Conn{buyer, seller} {
+		receipt := next(conn)
+		assert.Equal(t, domain.WSMessageTypeRead, receipt.Type)
+		data := receipt.Data.(map[string]interface{})
+		assert.Equal(t, f.seller.String(), data["user_id"])
+		assert.Equal(t, []interface{}{sent.Data.(map[string]interface{})["id"]}, data["message_ids"])
+	}
+
+	// Reading again is not news; the REST form answers with an empty receipt
+	req, err := http.NewRequest(http.MethodPost, f.server.URL+"/v1/conversations/"+f.conversation.String()+"/read", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token(t, f.seller))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var receipt domain.ReadReceipt
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&receipt))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, receipt.MessageIDs)
+	assert.NotNil(t, receipt.LastReadAt)
+}
+
+func TestMessageHandler_AttachmentsAreForParticipantsOnly(t *testing.T) {
+	f := newMessageFixture(t)
+	seller, _, err := f.dial(token(t, f.seller), allowedOrigin)
+	require.NoError(t, err)
+	defer seller.Close()
+
+	photo := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	for x := 0; x < 800; x++ {
+		photo.Set(x, 300, color.RGBA{R: 255, A: 255})
+	}
+	var photoData bytes.Buffer
+	require.NoError(t, png.Encode(&photoData, photo))
+	receiptData := []byte("%PDF-1.4\n1 0 obj\n<<>>\nendobj\n")
+
+	upload := func(userID uuid.UUID, files map[string][]byte) *http.Response {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		require.NoError(t, form.WriteField("content", "The scratch is here"))
+		for name, data := range files {
+			part, err := form.CreateFormFile("files", name)
+			require.NoError(t, err)
+			part.Write(data)
+		}
+		require.NoError(t, form.Close())
+
+		req, err := http.NewRequest(http.MethodPost, f.server.URL+"/v1/conversations/"+f.conversation.String()+"/attachments", &body)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token(t, userID))
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	fetch := func(userID uuid.UUID, url string) (*http.Response, []byte) {
+		req, err := http.NewRequest(http.MethodGet, f.server.URL+url, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token(t, userID))
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, body
+	}
+
+	// Only images and PDFs are accepted, whatever the file is called
+	resp := upload(f.buyer, map[string][]byte{"photo.png": []byte("just some text")})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, http.StatusForbidden, upload(f.stranger, map[string][]byte{"photo.png": photoData.Bytes()}).StatusCode)
+
+	// The buyer sends a photo and a PDF
+	resp = upload(f.buyer, map[string][]byte{"defect.png": photoData.Bytes(), "receipt": receiptData})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var message domain.Message
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+	require.Len(t, message.Attachments, 2)
+
+	attachments := map[string]domain.MessageAttachment{}
+	for _, attachment := range message.Attachments {
+		attachments[attachment.ContentType] = attachment
+	}
+	photoAttachment, pdfAttachment := attachments["image/png"], attachments["application/pdf"]
+	assert.Equal(t, "defect.png", photoAttachment.Filename)
+	assert.Equal(t, 800, photoAttachment.Width)
+	assert.NotEmpty(t, photoAttachment.ThumbnailURL)
+	assert.Equal(t, "receipt.pdf", pdfAttachment.Filename)
+	assert.Empty(t, pdfAttachment.ThumbnailURL)
+
+	// The seller's socket gets the message with its attachments
+	var event struct {
+		Type domain.WSMessageType `json:"type"`
+		Data domain.Message       `json:"data"`
+	}
+	seller.SetReadDeadline(time.Now().Add(time.Second))
+	require.NoError(t, seller.ReadJSON(&event))
+	assert.Equal(t, domain.WSMessageTypeMessage, event.Type)
+	assert.ElementsMatch(t, message.Attachments, event.Data.Attachments)
+
+	// Participants fetch the files; nobody else can
+	resp, body := fetch(f.seller, pdfAttachment.URL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+	assert.Equal(t, receiptData, body)
+
+	resp, body = fetch(f.buyer, photoAttachment.ThumbnailURL)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	thumbnail, format, err := image.DecodeConfig(bytes.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 320, thumbnail.Width)
+	assert.Equal(t, 240, thumbnail.Height)
+
+	resp, _ = fetch(f.stranger, photoAttachment.URL)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp, _ = fetch(f.buyer, pdfAttachment.URL+"/thumbnail")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
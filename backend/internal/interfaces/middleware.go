@@ -41,6 +41,20 @@ func AuthMiddleware(authUseCase *usecase.AuthUseCase) gin.HandlerFunc {
 	}
 }
 
+// OptionalAuthMiddleware sets the user ID when a valid bearer token is sent
+// and lets anonymous requests through otherwise
+func OptionalAuthMiddleware(authUseCase *usecase.AuthUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if userID, err := authUseCase.ValidateToken(parts[1]); err == nil {
+				c.Set(UserIDKey, userID)
+			}
+		}
+		c.Next()
+	}
+}
+
 func GetUserIDFromContext(c *gin.Context) uuid.UUID {
 	userID, exists := c.Get(UserIDKey)
 	if !exists {
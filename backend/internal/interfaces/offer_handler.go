@@ -87,6 +87,58 @@ func (h *OfferHandler) RespondOffer(c *gin.Context) {
 	c.JSON(http.StatusOK, offer)
 }
 
+// CounterOffer handles POST /offers/:id/counter
+func (h *OfferHandler) CounterOffer(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	offerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offer ID"})
+		return
+	}
+
+	var req domain.CounterOfferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	offer, err := h.offerUseCase.CounterOffer(offerID, userID.(uuid.UUID), req.Price, req.Message)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, offer)
+}
+
+// GetOffer handles GET /offers/:id
+func (h *OfferHandler) GetOffer(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	offerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offer ID"})
+		return
+	}
+
+	offer, err := h.offerUseCase.GetOffer(offerID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, offer)
+}
+
 // GetMyOffers handles GET /offers/my
 func (h *OfferHandler) GetMyOffers(c *gin.Context) {
 	userID, exists := c.Get("user_id")
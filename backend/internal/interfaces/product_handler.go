@@ -3,6 +3,7 @@ package interfaces
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,51 +15,134 @@ type ProductHandler struct {
 	productUseCase         *usecase.ProductUseCase
 	authUseCase            *usecase.AuthUseCase
 	sustainabilityRepo     domain.SustainabilityRepository
+	semanticSearch         usecase.SemanticSearchUseCase
 }
 
-func NewProductHandler(productUseCase *usecase.ProductUseCase, authUseCase *usecase.AuthUseCase, sustainabilityRepo domain.SustainabilityRepository) *ProductHandler {
+func NewProductHandler(productUseCase *usecase.ProductUseCase, authUseCase *usecase.AuthUseCase, sustainabilityRepo domain.SustainabilityRepository, semanticSearch usecase.SemanticSearchUseCase) *ProductHandler {
 	return &ProductHandler{
 		productUseCase:     productUseCase,
 		authUseCase:        authUseCase,
 		sustainabilityRepo: sustainabilityRepo,
+		semanticSearch:     semanticSearch,
 	}
 }
 
-// List handles GET /products
+// List handles GET /products. category and condition accept several values.
+// Pages are picked with page, or with cursor set to the next_cursor of the
+// previous response, which skips counting the total.
+// The response carries facet counts, except with mode=semantic where the
+// search text is matched by meaning instead of by words.
 func (h *ProductHandler) List(c *gin.Context) {
 	// Parse query parameters
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	category := c.Query("category")
-	condition := c.Query("condition")
 	minPrice, _ := strconv.Atoi(c.Query("min_price"))
 	maxPrice, _ := strconv.Atoi(c.Query("max_price"))
-	sort := c.DefaultQuery("sort", "created_desc")
+	minSellerRating, _ := strconv.ParseFloat(c.Query("min_seller_rating"), 64)
+	// Without a sort, search results come best match first
+	sort := c.Query("sort")
 	search := c.Query("search")
 
+	var conditions []domain.ProductCondition
+	for _, condition := range queryValues(c, "condition") {
+		conditions = append(conditions, domain.ProductCondition(condition))
+	}
+
 	filters := domain.ProductFilters{
-		Page:      page,
-		Limit:     limit,
-		Category:  category,
-		Condition: domain.ProductCondition(condition),
-		MinPrice:  minPrice,
-		MaxPrice:  maxPrice,
-		Sort:      sort,
-		Search:    search,
+		Page:            page,
+		Limit:           limit,
+		Categories:      queryValues(c, "category"),
+		Conditions:      conditions,
+		MinPrice:        minPrice,
+		MaxPrice:        maxPrice,
+		MinSellerRating: minSellerRating,
+		Sort:            sort,
+		Search:          search,
+		Cursor:          c.Query("cursor"),
+	}
+
+	if filters.Cursor != "" {
+		if _, err := domain.DecodePageCursor(filters.Cursor); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var products []*domain.Product
+	var total int
+	var nextCursor string
+	var facets *domain.ProductFacets
+	var err error
+	if c.Query("mode") == "semantic" && search != "" && h.semanticSearch != nil {
+		products, total, err = h.semanticSearch.Search(c.Request.Context(), &filters)
+	} else {
+		var pagination *domain.PaginationResponse
+		products, pagination, err = h.productUseCase.ListProducts(&filters)
+		if err == nil {
+			total, nextCursor = pagination.Total, pagination.NextCursor
+		}
+		// Later pages reuse the facets of the first
+		if err == nil && filters.Cursor == "" {
+			facets, err = h.productUseCase.Facets(filters)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"products":    products,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"next_cursor": nextCursor,
+	}
+	if facets != nil {
+		response["facets"] = facets
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// queryValues reads a multi-select parameter given either repeated
+// (?category=a&category=b) or comma separated (?category=a,b)
+func queryValues(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// Similar handles GET /products/:id/similar
+func (h *ProductHandler) Similar(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if h.semanticSearch == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Similar items are not available"})
+		return
 	}
 
-	products, total, err := h.productUseCase.List(filters)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	products, err := h.semanticSearch.Similar(c.Request.Context(), productID, limit)
 	if err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"products": products,
-		"total":    total,
-		"page":     page,
-		"limit":    limit,
-	})
+	c.JSON(http.StatusOK, gin.H{"products": products})
 }
 
 // GetByID handles GET /products/:id
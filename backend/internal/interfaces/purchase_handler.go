@@ -73,6 +73,85 @@ func (h *PurchaseHandler) List(c *gin.Context) {
 	})
 }
 
+func (h *PurchaseHandler) Pay(c *gin.Context) {
+	h.transition(c, h.purchaseUseCase.Pay)
+}
+
+func (h *PurchaseHandler) Ship(c *gin.Context) {
+	h.transition(c, h.purchaseUseCase.MarkShipped)
+}
+
+func (h *PurchaseHandler) Deliver(c *gin.Context) {
+	h.transition(c, h.purchaseUseCase.MarkDelivered)
+}
+
+func (h *PurchaseHandler) Cancel(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req domain.CancelPurchaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	purchase, err := h.purchaseUseCase.Cancel(id, userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, purchase)
+}
+
+func (h *PurchaseHandler) CheckoutOffer(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	offerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req domain.CheckoutOfferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	purchase, err := h.purchaseUseCase.CheckoutOffer(offerID, userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, purchase)
+}
+
+// transition runs a lifecycle step on the purchase in the :id param
+func (h *PurchaseHandler) transition(c *gin.Context, step func(id, userID uuid.UUID) (*domain.Purchase, error)) {
+	userID, _ := c.Get("user_id")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	purchase, err := step(id, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, purchase)
+}
+
 func (h *PurchaseHandler) Complete(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
@@ -1,7 +1,9 @@
 package interfaces
 
 import (
+	"errors"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -32,6 +34,8 @@ func NewUploadHandler() *UploadHandler {
 	}
 }
 
+var errNotImage = errors.New("only image files are allowed")
+
 type UploadResponse struct {
 	URL      string `json:"url"`
 	Filename string `json:"filename"`
@@ -45,49 +49,23 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 		return
 	}
 
-	file, header, err := c.Request.FormFile("image")
+	_, header, err := c.Request.FormFile("image")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
 		return
 	}
-	defer file.Close()
 
-	// Validate file type
-	contentType := header.Header.Get("Content-Type")
-	if !strings.HasPrefix(contentType, "image/") {
+	upload, err := h.saveImage(header)
+	if err == errNotImage {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Only image files are allowed"})
 		return
 	}
-
-	// Generate unique filename
-	ext := filepath.Ext(header.Filename)
-	filename := uuid.New().String() + ext
-	filepath := filepath.Join(h.uploadDir, filename)
-
-	// Create file
-	dst, err := os.Create(filepath)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
 		return
 	}
-	defer dst.Close()
-
-	// Copy uploaded file to destination
-	if _, err := io.Copy(dst, file); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
-		return
-	}
-
-	// Return URL (using public endpoint)
-	baseURL := os.Getenv("BASE_URL")
-	if baseURL == "" {
-		baseURL = "http://localhost:8080"
-	}
 
-	c.JSON(http.StatusOK, UploadResponse{
-		URL:      baseURL + "/uploads/" + filename,
-		Filename: filename,
-	})
+	c.JSON(http.StatusOK, upload)
 }
 
 // UploadMultipleImages handles multiple image uploads
@@ -114,53 +92,70 @@ func (h *UploadHandler) UploadMultipleImages(c *gin.Context) {
 	var responses []UploadResponse
 
 	for _, fileHeader := range files {
-		file, err := fileHeader.Open()
+		upload, err := h.saveImage(fileHeader)
 		if err != nil {
 			continue
 		}
-		defer file.Close()
+		responses = append(responses, *upload)
+	}
 
-		// Validate file type
-		contentType := fileHeader.Header.Get("Content-Type")
-		if !strings.HasPrefix(contentType, "image/") {
-			continue
-		}
+	if len(responses) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload any files"})
+		return
+	}
 
-		// Generate unique filename
-		ext := filepath.Ext(fileHeader.Filename)
-		filename := uuid.New().String() + ext
-		filepath := filepath.Join(h.uploadDir, filename)
+	c.JSON(http.StatusOK, gin.H{"images": responses})
+}
 
-		// Create file
-		dst, err := os.Create(filepath)
-		if err != nil {
-			continue
-		}
-		defer dst.Close()
+// saveImage stores an uploaded image under a fresh name and returns its public URL
+func (h *UploadHandler) saveImage(header *multipart.FileHeader) (*UploadResponse, error) {
+	// Validate file type
+	contentType := header.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, errNotImage
+	}
 
-		// Copy uploaded file to destination
-		if _, err := io.Copy(dst, file); err != nil {
-			continue
-		}
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
 
-		// Add to responses
-		baseURL := os.Getenv("BASE_URL")
-		if baseURL == "" {
-			baseURL = "http://localhost:8080"
-		}
+	// Generate unique filename
+	ext := filepath.Ext(header.Filename)
+	filename := uuid.New().String() + ext
+	filepath := filepath.Join(h.uploadDir, filename)
 
-		responses = append(responses, UploadResponse{
-			URL:      baseURL + "/uploads/" + filename,
-			Filename: filename,
-		})
+	// Create file
+	dst, err := os.Create(filepath)
+	if err != nil {
+		return nil, err
 	}
+	defer dst.Close()
 
-	if len(responses) == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload any files"})
-		return
+	// Copy uploaded file to destination
+	if _, err := io.Copy(dst, file); err != nil {
+		return nil, err
 	}
 
-	c.JSON(http.StatusOK, gin.H{"images": responses})
+	// Return URL (using public endpoint)
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
+	return &UploadResponse{
+		URL:      baseURL + "/uploads/" + filename,
+		Filename: filename,
+	}, nil
+}
+
+// deleteImage removes a file stored by saveImage
+func (h *UploadHandler) deleteImage(filename string) error {
+	if err := os.Remove(filepath.Join(h.uploadDir, filename)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
 }
 
 // ServeUploadedFile serves uploaded files
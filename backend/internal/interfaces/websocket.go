@@ -0,0 +1,54 @@
+package interfaces
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsBearerProtocol is the subprotocol a browser offers together with its
+// access token, as it cannot set an Authorization header on a handshake
+const wsBearerProtocol = "bearer"
+
+// newUpgrader accepts WebSocket handshakes from the origins the CORS policy
+// allows. Requests without an Origin header come from non-browser clients,
+// which cookies cannot be borrowed from, so they pass.
+func newUpgrader(allowedOrigins []string) websocket.Upgrader {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[normalizeOrigin(origin)] = true
+	}
+
+	return websocket.Upgrader{
+		// Only the bearer marker is ever echoed back, never the token
+		Subprotocols: []string{wsBearerProtocol},
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" || allowed["*"] {
+				return true
+			}
+			return allowed[normalizeOrigin(origin)]
+		},
+	}
+}
+
+func normalizeOrigin(origin string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+}
+
+// handshakeToken returns the access token sent with a WebSocket handshake,
+// either as "Authorization: Bearer <token>" or as the subprotocols
+// "bearer, <token>". Tokens are never read from the URL, which ends up in
+// access logs.
+func handshakeToken(r *http.Request) string {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) == 2 && parts[0] == "Bearer" {
+		return parts[1]
+	}
+	protocols := websocket.Subprotocols(r)
+	if len(protocols) == 2 && protocols[0] == wsBearerProtocol {
+		return protocols[1]
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ecomate/backend/internal/config"
+	"gorm.io/gorm"
+)
+
+// Broker carries published messages to every hub subscribed to the topic,
+// whichever replica they run in
+type Broker interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe calls deliver with each message published on topic until
+	// the returned cancel is called
+	Subscribe(topic string, deliver func(payload []byte)) (cancel func(), err error)
+	Close() error
+}
+
+// NewBroker builds the broker selected in cfg
+func NewBroker(cfg *config.RealtimeConfig, db *gorm.DB) (Broker, error) {
+	switch cfg.Broker {
+	case "", "memory":
+		return NewMemoryBroker(), nil
+	case "mysql":
+		return NewSQLBroker(db, time.Duration(cfg.PollIntervalMs)*time.Millisecond)
+	default:
+		return nil, fmt.Errorf("unknown realtime broker: %s", cfg.Broker)
+	}
+}
+
+// subscriptions tracks the deliver functions of each topic
+type subscriptions struct {
+	mu     sync.RWMutex
+	nextID int
+	topics map[string]map[int]func([]byte)
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{topics: make(map[string]map[int]func([]byte))}
+}
+
+func (s *subscriptions) add(topic string, deliver func([]byte)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	id := s.nextID
+	if s.topics[topic] == nil {
+		s.topics[topic] = make(map[int]func([]byte))
+	}
+	s.topics[topic][id] = deliver
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.topics[topic], id)
+		if len(s.topics[topic]) == 0 {
+			delete(s.topics, topic)
+		}
+	}
+}
+
+// deliver runs outside the lock so a subscriber may subscribe or cancel
+// while handling a message
+func (s *subscriptions) deliver(topic string, payload []byte) {
+	s.mu.RLock()
+	delivers := make([]func([]byte), 0, len(s.topics[topic]))
+	for _, deliver := range s.topics[topic] {
+		delivers = append(delivers, deliver)
+	}
+	s.mu.RUnlock()
+
+	for _, deliver := range delivers {
+		deliver(payload)
+	}
+}
+
+// MemoryBroker connects the hubs of one process
+type MemoryBroker struct {
+	subs *subscriptions
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subs: newSubscriptions()}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, topic string, payload []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.subs.deliver(topic, payload)
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(topic string, deliver func(payload []byte)) (func(), error) {
+	return b.subs.add(topic, deliver), nil
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
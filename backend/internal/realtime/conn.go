@@ -0,0 +1,184 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultSendBuffer     = 64
+	defaultMaxMessageSize = 64 * 1024
+	defaultPongWait       = 60 * time.Second
+	defaultWriteWait      = 10 * time.Second
+)
+
+var (
+	ErrClientClosed  = errors.New("client is closed")
+	ErrClientEvicted = errors.New("client send buffer is full")
+)
+
+// ConnOptions limits a WebSocket connection; zero values take the defaults
+type ConnOptions struct {
+	// SendBuffer is how many messages may wait for a slow client before it
+	// is disconnected
+	SendBuffer int
+	// MaxMessageSize is the largest message read from the client, in bytes
+	MaxMessageSize int64
+	// PongWait is how long the client may stay silent; it is pinged at 9/10
+	// of this interval
+	PongWait time.Duration
+	// WriteWait bounds each write to the client
+	WriteWait time.Duration
+}
+
+func (o ConnOptions) withDefaults() ConnOptions {
+	if o.SendBuffer <= 0 {
+		o.SendBuffer = defaultSendBuffer
+	}
+	if o.MaxMessageSize <= 0 {
+		o.MaxMessageSize = defaultMaxMessageSize
+	}
+	if o.PongWait <= 0 {
+		o.PongWait = defaultPongWait
+	}
+	if o.WriteWait <= 0 {
+		o.WriteWait = defaultWriteWait
+	}
+	return o
+}
+
+// ConnClient is a Client writing to a WebSocket connection. Messages queue
+// on a bounded channel drained by the connection's own writer goroutine, so
+// a slow client never holds up the others: once its queue is full it is
+// disconnected. The owner keeps reading from the connection, which applies
+// the read deadline and answers pings, and calls Close when done.
+type ConnClient struct {
+	conn      *websocket.Conn
+	opts      ConnOptions
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+}
+
+func NewConnClient(conn *websocket.Conn, opts ConnOptions) *ConnClient {
+	opts = opts.withDefaults()
+	c := &ConnClient{
+		conn: conn,
+		opts: opts,
+		send: make(chan []byte, opts.SendBuffer),
+		done: make(chan struct{}),
+	}
+
+	conn.SetReadLimit(opts.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(opts.PongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(opts.PongWait))
+	})
+
+	go c.writePump()
+	return c
+}
+
+// Send queues payload without blocking
+func (c *ConnClient) Send(payload []byte) error {
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+
+	select {
+	case c.send <- payload:
+		return nil
+	case <-c.done:
+		return ErrClientClosed
+	default:
+		c.shutdown(websocket.ClosePolicyViolation)
+		return ErrClientEvicted
+	}
+}
+
+func (c *ConnClient) WriteJSON(v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Send(payload)
+}
+
+// Close writes the queued messages and closes the connection
+func (c *ConnClient) Close() error {
+	c.shutdown(websocket.CloseNormalClosure)
+	return nil
+}
+
+// Done is closed once the client stops sending
+func (c *ConnClient) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *ConnClient) shutdown(code int) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		close(c.done)
+	})
+}
+
+func (c *ConnClient) writePump() {
+	ticker := time.NewTicker(c.opts.PongWait * 9 / 10)
+	defer func() {
+		ticker.Stop()
+		// Closing the connection also ends the owner's reads
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case payload := <-c.send:
+			if err := c.write(websocket.TextMessage, payload); err != nil {
+				c.shutdown(websocket.CloseAbnormalClosure)
+				return
+			}
+		case <-ticker.C:
+			if err := c.write(websocket.PingMessage, nil); err != nil {
+				c.shutdown(websocket.CloseAbnormalClosure)
+				return
+			}
+		case <-c.done:
+			c.finish()
+			return
+		}
+	}
+}
+
+// finish flushes what a normally closed client still has queued and says
+// goodbye; an evicted client is cut off
+func (c *ConnClient) finish() {
+	switch c.closeCode {
+	case websocket.CloseNormalClosure:
+		// The pump is the only reader of send, so this never blocks
+		for len(c.send) > 0 {
+			if err := c.write(websocket.TextMessage, <-c.send); err != nil {
+				return
+			}
+		}
+	case websocket.CloseAbnormalClosure:
+		return
+	}
+
+	message := websocket.FormatCloseMessage(c.closeCode, "")
+	if c.closeCode == websocket.ClosePolicyViolation {
+		message = websocket.FormatCloseMessage(c.closeCode, ErrClientEvicted.Error())
+	}
+	c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(c.opts.WriteWait))
+}
+
+func (c *ConnClient) write(messageType int, payload []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(c.opts.WriteWait))
+	return c.conn.WriteMessage(messageType, payload)
+}
@@ -0,0 +1,102 @@
+package realtime_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ecomate/backend/internal/realtime"
+)
+
+func TestConnClient_EvictsClientThatStopsReading(t *testing.T) {
+	// Arrange: one socket keeps up, the other never reads
+	broker := realtime.NewMemoryBroker()
+	hub, server := newReplicaWithOptions(t, broker, realtime.ConnOptions{SendBuffer: 32, WriteWait: 200 * time.Millisecond})
+	auctionID := uuid.New()
+	topic := realtime.AuctionTopic(auctionID)
+
+	reader := dial(t, server, auctionID)
+	dial(t, server, auctionID)
+	waitForSubscribers(t, hub, topic, 2)
+
+	received := make(chan int, 1)
+	go func() {
+		count := 0
+		for {
+			if _, _, err := reader.ReadMessage(); err != nil {
+				received <- count
+				return
+			}
+			count++
+		}
+	}()
+
+	// Act: publish, at a pace a reading client keeps up with, more than the
+	// stalled socket's buffers hold
+	payload := bytes.Repeat([]byte("x"), 64*1024)
+	for i := 0; i < 5000 && hub.Subscribers(topic) == 2; i++ {
+		require.NoError(t, hub.Publish(context.Background(), topic, payload))
+		time.Sleep(time.Millisecond)
+	}
+
+	// Assert: only the stalled socket is dropped
+	waitForSubscribers(t, hub, topic, 1)
+
+	require.NoError(t, hub.Publish(context.Background(), topic, "still here"))
+	reader.Close()
+	assert.Greater(t, <-received, 0)
+}
+
+func TestConnClient_DropsClientThatStopsAnsweringPings(t *testing.T) {
+	// Arrange
+	broker := realtime.NewMemoryBroker()
+	hub, server := newReplicaWithOptions(t, broker, realtime.ConnOptions{PongWait: 200 * time.Millisecond})
+	auctionID := uuid.New()
+	topic := realtime.AuctionTopic(auctionID)
+
+	// Pongs are only sent while the connection is read
+	alive := dial(t, server, auctionID)
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	silent := dial(t, server, auctionID)
+	waitForSubscribers(t, hub, topic, 2)
+
+	// Act & Assert: after several ping periods only the answering socket remains
+	waitForSubscribers(t, hub, topic, 1)
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, 1, hub.Subscribers(topic))
+
+	silent.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		if _, _, err := silent.ReadMessage(); err != nil {
+			assert.False(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+			break
+		}
+	}
+}
+
+func TestConnClient_RejectsOversizedMessages(t *testing.T) {
+	// Arrange
+	broker := realtime.NewMemoryBroker()
+	hub, server := newReplicaWithOptions(t, broker, realtime.ConnOptions{MaxMessageSize: 1024})
+	auctionID := uuid.New()
+	topic := realtime.AuctionTopic(auctionID)
+	conn := dial(t, server, auctionID)
+	waitForSubscribers(t, hub, topic, 1)
+
+	// Act
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, bytes.Repeat([]byte("x"), 2048)))
+
+	// Assert
+	waitForSubscribers(t, hub, topic, 0)
+}
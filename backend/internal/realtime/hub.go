@@ -0,0 +1,170 @@
+// Package realtime delivers WebSocket events to clients connected to any API
+// replica. Handlers publish to a topic on their Hub; the Broker carries the
+// message to the Hub of every replica, which writes it to its own clients.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+func ConversationTopic(conversationID uuid.UUID) string {
+	return "conversation:" + conversationID.String()
+}
+
+func AuctionTopic(auctionID uuid.UUID) string {
+	return "auction:" + auctionID.String()
+}
+
+// UserTopic carries events for one user wherever they are in the app
+func UserTopic(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+// Client receives the messages of the topics it subscribed to
+type Client interface {
+	Send(payload []byte) error
+}
+
+// Hub keeps the local clients of each topic and subscribes to the broker for
+// a topic while it has any
+type Hub struct {
+	broker   Broker
+	connOpts ConnOptions
+	mu       sync.RWMutex
+	topics   map[string]*topicClients
+}
+
+type topicClients struct {
+	// clients maps each client to the identity it subscribed as
+	clients map[Client]string
+	cancel  func()
+}
+
+// envelope is what hubs exchange through the broker
+type envelope struct {
+	Except  string          `json:"except,omitempty"`
+	Message json.RawMessage `json:"message"`
+}
+
+func NewHub(broker Broker, connOpts ConnOptions) *Hub {
+	return &Hub{
+		broker:   broker,
+		connOpts: connOpts,
+		topics:   make(map[string]*topicClients),
+	}
+}
+
+// Connect wraps an upgraded connection in a client held to the hub's limits
+func (h *Hub) Connect(conn *websocket.Conn) *ConnClient {
+	return NewConnClient(conn, h.connOpts)
+}
+
+// Subscribe sends client every message published to topic on any hub sharing
+// the broker, until the returned function is called
+func (h *Hub) Subscribe(topic string, client Client) (func(), error) {
+	return h.SubscribeAs(topic, "", client)
+}
+
+// SubscribeAs subscribes a client on behalf of identity, typically a user ID,
+// so that PublishExcept can leave it out
+func (h *Hub) SubscribeAs(topic, identity string, client Client) (func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t := h.topics[topic]
+	if t == nil {
+		cancel, err := h.broker.Subscribe(topic, func(payload []byte) {
+			h.deliver(topic, payload)
+		})
+		if err != nil {
+			return nil, err
+		}
+		t = &topicClients{clients: make(map[Client]string), cancel: cancel}
+		h.topics[topic] = t
+	}
+	t.clients[client] = identity
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { h.unsubscribe(topic, client) })
+	}, nil
+}
+
+func (h *Hub) unsubscribe(topic string, client Client) {
+	h.mu.Lock()
+	t := h.topics[topic]
+	if t == nil {
+		h.mu.Unlock()
+		return
+	}
+	delete(t.clients, client)
+	if len(t.clients) > 0 {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.topics, topic)
+	h.mu.Unlock()
+
+	t.cancel()
+}
+
+// Publish sends message, encoded as JSON, to the subscribers of topic
+func (h *Hub) Publish(ctx context.Context, topic string, message interface{}) error {
+	return h.PublishExcept(ctx, topic, "", message)
+}
+
+// PublishExcept is Publish leaving out the clients subscribed as identity
+func (h *Hub) PublishExcept(ctx context.Context, topic, identity string, message interface{}) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(envelope{Except: identity, Message: encoded})
+	if err != nil {
+		return err
+	}
+	return h.broker.Publish(ctx, topic, payload)
+}
+
+// Subscribers is the number of local clients of topic
+func (h *Hub) Subscribers(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if t := h.topics[topic]; t != nil {
+		return len(t.clients)
+	}
+	return 0
+}
+
+func (h *Hub) deliver(topic string, payload []byte) {
+	var env envelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		log.Printf("Failed to decode %s event: %v", topic, err)
+		return
+	}
+
+	h.mu.RLock()
+	var clients []Client
+	if t := h.topics[topic]; t != nil {
+		clients = make([]Client, 0, len(t.clients))
+		for client, identity := range t.clients {
+			if env.Except == "" || identity != env.Except {
+				clients = append(clients, client)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		// Closed clients are about to unsubscribe
+		if err := client.Send(env.Message); err != nil && err != ErrClientClosed {
+			log.Printf("Failed to send %s event: %v", topic, err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package realtime_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ecomate/backend/internal/realtime"
+)
+
+// recordingClient keeps what it is sent
+type recordingClient struct {
+	mu       sync.Mutex
+	payloads []string
+}
+
+func (c *recordingClient) Send(payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.payloads = append(c.payloads, string(payload))
+	return nil
+}
+
+func (c *recordingClient) received() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.payloads...)
+}
+
+// newReplica serves a hub the way the API does: each socket subscribes to
+// the auction named in the URL
+func newReplica(t *testing.T, broker realtime.Broker) (*realtime.Hub, *httptest.Server) {
+	return newReplicaWithOptions(t, broker, realtime.ConnOptions{})
+}
+
+func newReplicaWithOptions(t *testing.T, broker realtime.Broker, opts realtime.ConnOptions) (*realtime.Hub, *httptest.Server) {
+	hub := realtime.NewHub(broker, opts)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := hub.Connect(conn)
+		defer client.Close()
+
+		auctionID := uuid.MustParse(strings.TrimPrefix(r.URL.Path, "/ws/auctions/"))
+		unsubscribe, err := hub.Subscribe(realtime.AuctionTopic(auctionID), client)
+		if err != nil {
+			return
+		}
+		defer unsubscribe()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return hub, server
+}
+
+func dial(t *testing.T, server *httptest.Server, auctionID uuid.UUID) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/auctions/" + auctionID.String()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func waitForSubscribers(t *testing.T, hub *realtime.Hub, topic string, n int) {
+	require.Eventually(t, func() bool { return hub.Subscribers(topic) == n }, time.Second, 5*time.Millisecond)
+}
+
+func TestHub_PublishReachesSocketsOnEveryReplica(t *testing.T) {
+	// Arrange: two replicas share one broker
+	broker := realtime.NewMemoryBroker()
+	hubA, serverA := newReplica(t, broker)
+	hubB, serverB := newReplica(t, broker)
+
+	auctionID := uuid.New()
+	otherAuctionID := uuid.New()
+	topic := realtime.AuctionTopic(auctionID)
+	onA := dial(t, serverA, auctionID)
+	onB := dial(t, serverB, auctionID)
+	elsewhere := dial(t, serverB, otherAuctionID)
+	waitForSubscribers(t, hubA, topic, 1)
+	waitForSubscribers(t, hubB, topic, 1)
+	waitForSubscribers(t, hubB, realtime.AuctionTopic(otherAuctionID), 1)
+
+	// Act: a bid is placed through replica A
+	err := hubA.Publish(context.Background(), topic, map[string]interface{}{"type": "new_bid", "data": 1500})
+
+	// Assert
+	require.NoError(t, err)
+	for _, conn := range []*websocket.Conn{onA, onB} {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, payload, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"type": "new_bid", "data": 1500}`, string(payload))
+	}
+
+	elsewhere.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, _, err = elsewhere.ReadMessage()
+	assert.Error(t, err, "sockets of other auctions get nothing")
+
+	// A socket that closes is dropped from its hub
+	onB.Close()
+	waitForSubscribers(t, hubB, topic, 0)
+}
+
+func TestHub_DeliversOnceAndStopsAfterUnsubscribe(t *testing.T) {
+	// Arrange
+	broker := realtime.NewMemoryBroker()
+	hubA := realtime.NewHub(broker, realtime.ConnOptions{})
+	hubB := realtime.NewHub(broker, realtime.ConnOptions{})
+	topic := realtime.ConversationTopic(uuid.New())
+
+	first, second, remote := &recordingClient{}, &recordingClient{}, &recordingClient{}
+	unsubscribeFirst, err := hubA.Subscribe(topic, first)
+	require.NoError(t, err)
+	unsubscribeSecond, err := hubA.Subscribe(topic, second)
+	require.NoError(t, err)
+	unsubscribeRemote, err := hubB.Subscribe(topic, remote)
+	require.NoError(t, err)
+
+	// Act
+	require.NoError(t, hubB.Publish(context.Background(), topic, "hello"))
+	unsubscribeFirst()
+	unsubscribeFirst()
+	require.NoError(t, hubA.Publish(context.Background(), topic, "again"))
+	unsubscribeSecond()
+	unsubscribeRemote()
+	require.NoError(t, hubA.Publish(context.Background(), topic, "nobody"))
+
+	// Assert: each client got each message once, and only while subscribed
+	assert.Equal(t, []string{`"hello"`}, first.received())
+	assert.Equal(t, []string{`"hello"`, `"again"`}, second.received())
+	assert.Equal(t, []string{`"hello"`, `"again"`}, remote.received())
+	assert.Equal(t, 0, hubA.Subscribers(topic))
+}
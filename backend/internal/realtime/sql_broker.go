@@ -0,0 +1,146 @@
+package realtime
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPollInterval = 200 * time.Millisecond
+	// Events are kept long enough for every replica to read them
+	eventRetention = time.Minute
+	pollBatchSize  = 1000
+	// An insert can commit after a later one; re-reading this many IDs below
+	// the newest seen picks such events up
+	pollLookback = 256
+)
+
+// realtimeEvent is a published message waiting to be read by every replica
+type realtimeEvent struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement"`
+	Topic     string    `gorm:"type:varchar(191);not null"`
+	Payload   []byte    `gorm:"type:mediumblob;not null"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (realtimeEvent) TableName() string {
+	return "realtime_events"
+}
+
+// SQLBroker connects replicas through a table of events that each of them
+// polls; it needs nothing beyond the database the API already uses
+type SQLBroker struct {
+	db       *gorm.DB
+	interval time.Duration
+	subs     *subscriptions
+
+	startID   uint64 // events up to this one predate the broker
+	lastID    uint64
+	delivered map[uint64]bool
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewSQLBroker(db *gorm.DB, interval time.Duration) (*SQLBroker, error) {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	if err := db.AutoMigrate(&realtimeEvent{}); err != nil {
+		return nil, err
+	}
+
+	b := &SQLBroker{
+		db:        db,
+		interval:  interval,
+		subs:      newSubscriptions(),
+		delivered: make(map[uint64]bool),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	// Only events published from now on are delivered
+	if err := db.Model(&realtimeEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&b.startID).Error; err != nil {
+		return nil, err
+	}
+	b.lastID = b.startID
+
+	go b.run()
+	return b, nil
+}
+
+func (b *SQLBroker) Publish(ctx context.Context, topic string, payload []byte) error {
+	return b.db.WithContext(ctx).Create(&realtimeEvent{Topic: topic, Payload: payload}).Error
+}
+
+func (b *SQLBroker) Subscribe(topic string, deliver func(payload []byte)) (func(), error) {
+	return b.subs.add(topic, deliver), nil
+}
+
+func (b *SQLBroker) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.stop)
+		<-b.done
+	})
+	return nil
+}
+
+func (b *SQLBroker) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	// The first tick also clears events left over from before a restart
+	var lastCleanup time.Time
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case now := <-ticker.C:
+			if err := b.poll(); err != nil {
+				log.Printf("Failed to poll realtime events: %v", err)
+			}
+			if now.Sub(lastCleanup) >= eventRetention {
+				lastCleanup = now
+				if err := b.db.Where("created_at < ?", now.Add(-eventRetention)).Delete(&realtimeEvent{}).Error; err != nil {
+					log.Printf("Failed to delete old realtime events: %v", err)
+				}
+			}
+		}
+	}
+}
+
+func (b *SQLBroker) poll() error {
+	var floor uint64
+	if b.lastID > pollLookback {
+		floor = b.lastID - pollLookback
+	}
+
+	var events []realtimeEvent
+	if err := b.db.Where("id > ?", floor).Order("id").Limit(pollBatchSize).Find(&events).Error; err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if event.ID > b.lastID {
+			b.lastID = event.ID
+		}
+		if event.ID <= b.startID || b.delivered[event.ID] {
+			continue
+		}
+		b.delivered[event.ID] = true
+		b.subs.deliver(event.Topic, event.Payload)
+	}
+
+	for id := range b.delivered {
+		if id+pollLookback < b.lastID {
+			delete(b.delivered, id)
+		}
+	}
+	return nil
+}
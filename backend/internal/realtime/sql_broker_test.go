@@ -0,0 +1,142 @@
+package realtime_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ecomate/backend/internal/realtime"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testPollInterval = 5 * time.Millisecond
+
+func newSQLiteDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "events.db")),
+		&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	// SQLite has one writer at a time; sharing a connection avoids lock errors
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	return db
+}
+
+func newSQLBroker(t *testing.T, db *gorm.DB) *realtime.SQLBroker {
+	broker, err := realtime.NewSQLBroker(db, testPollInterval)
+	require.NoError(t, err)
+	t.Cleanup(func() { broker.Close() })
+	return broker
+}
+
+// payloadRecorder keeps the payloads delivered to a subscription
+type payloadRecorder struct {
+	mu       sync.Mutex
+	payloads []string
+}
+
+func (r *payloadRecorder) deliver(payload []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloads = append(r.payloads, string(payload))
+}
+
+func (r *payloadRecorder) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.payloads...)
+}
+
+func subscribe(t *testing.T, broker realtime.Broker, topic string) *payloadRecorder {
+	recorder := &payloadRecorder{}
+	cancel, err := broker.Subscribe(topic, recorder.deliver)
+	require.NoError(t, err)
+	t.Cleanup(cancel)
+	return recorder
+}
+
+// insertEvent writes an event row as a publishing replica would, with a
+// chosen ID
+func insertEvent(t *testing.T, db *gorm.DB, id uint64, topic, payload string, createdAt time.Time) {
+	require.NoError(t, db.Table("realtime_events").Create(map[string]interface{}{
+		"id":         id,
+		"topic":      topic,
+		"payload":    []byte(payload),
+		"created_at": createdAt,
+	}).Error)
+}
+
+func TestSQLBroker_DeliversInOrderToEveryReplica(t *testing.T) {
+	// Arrange: an event published before replica B started is not replayed
+	db := newSQLiteDB(t)
+	brokerA := newSQLBroker(t, db)
+	onA := subscribe(t, brokerA, "auction:1")
+	require.NoError(t, brokerA.Publish(context.Background(), "auction:1", []byte("opened")))
+	require.Eventually(t, func() bool { return len(onA.received()) == 1 }, time.Second, testPollInterval)
+
+	brokerB := newSQLBroker(t, db)
+	onB := subscribe(t, brokerB, "auction:1")
+	elsewhere := subscribe(t, brokerB, "auction:2")
+
+	// Act
+	var bids []string
+	for i := 0; i < 20; i++ {
+		bids = append(bids, fmt.Sprintf("bid %d", i))
+		require.NoError(t, brokerA.Publish(context.Background(), "auction:1", []byte(bids[i])))
+	}
+
+	// Assert
+	assert.Eventually(t, func() bool { return len(onB.received()) == len(bids) }, time.Second, testPollInterval)
+	assert.Eventually(t, func() bool { return len(onA.received()) == len(bids)+1 }, time.Second, testPollInterval)
+	time.Sleep(5 * testPollInterval)
+	assert.Equal(t, bids, onB.received())
+	assert.Equal(t, append([]string{"opened"}, bids...), onA.received())
+	assert.Empty(t, elsewhere.received())
+}
+
+func TestSQLBroker_PicksUpLateCommitsWithinLookback(t *testing.T) {
+	// Arrange
+	db := newSQLiteDB(t)
+	broker := newSQLBroker(t, db)
+	recorder := subscribe(t, broker, "conversation:1")
+
+	insertEvent(t, db, 1000, "conversation:1", "newest", time.Now())
+	require.Eventually(t, func() bool { return len(recorder.received()) == 1 }, time.Second, testPollInterval)
+
+	// Act: transactions that took IDs below it commit afterwards, one inside
+	// the lookback window and one far outside it
+	insertEvent(t, db, 900, "conversation:1", "late", time.Now())
+	insertEvent(t, db, 700, "conversation:1", "too late", time.Now())
+
+	// Assert: the window is re-read on every poll but nothing is delivered twice
+	assert.Eventually(t, func() bool { return len(recorder.received()) == 2 }, time.Second, testPollInterval)
+	time.Sleep(10 * testPollInterval)
+	assert.Equal(t, []string{"newest", "late"}, recorder.received())
+}
+
+func TestSQLBroker_DeletesOldEvents(t *testing.T) {
+	// Arrange: one event is past the retention of a minute
+	db := newSQLiteDB(t)
+	newSQLBroker(t, db).Close()
+	insertEvent(t, db, 1, "auction:1", "stale", time.Now().Add(-2*time.Minute))
+	insertEvent(t, db, 2, "auction:1", "fresh", time.Now())
+
+	// Act: a replica starting up clears old events on its first poll
+	newSQLBroker(t, db)
+
+	// Assert
+	var payloads []string
+	assert.Eventually(t, func() bool {
+		payloads = nil
+		db.Table("realtime_events").Order("id").Pluck("payload", &payloads)
+		return len(payloads) == 1
+	}, time.Second, testPollInterval)
+	assert.Equal(t, []string{"fresh"}, payloads)
+}
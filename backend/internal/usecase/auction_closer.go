@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"log"
+	"time"
+
+	"github.com/yourusername/ecomate/backend/internal/domain"
+)
+
+// AuctionCloser completes auctions whose end time has passed. Every replica
+// runs it as a LeasedJob, so only one of them closes auctions at a time.
+type AuctionCloser struct {
+	auctionUseCase AuctionUseCase
+	auctionRepo    domain.AuctionRepository
+	batchSize      int
+	onClosed       func(auction *domain.Auction)
+}
+
+// NewAuctionCloser creates a closer. onClosed is called with each closed
+// auction, e.g. to broadcast the result.
+func NewAuctionCloser(
+	auctionUseCase AuctionUseCase,
+	auctionRepo domain.AuctionRepository,
+	onClosed func(auction *domain.Auction),
+) *AuctionCloser {
+	return &AuctionCloser{
+		auctionUseCase: auctionUseCase,
+		auctionRepo:    auctionRepo,
+		batchSize:      50,
+		onClosed:       onClosed,
+	}
+}
+
+// CloseExpired closes the auctions that ended by now and returns how many it
+// closed
+func (c *AuctionCloser) CloseExpired(now time.Time) (int, error) {
+	expired, err := c.auctionRepo.FindExpired(now, c.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, auction := range expired {
+		if err := c.auctionUseCase.CompleteAuction(auction.ID); err != nil {
+			log.Printf("Warning: Failed to complete auction %s: %v", auction.ID, err)
+			continue
+		}
+		closed++
+
+		if c.onClosed == nil {
+			continue
+		}
+		completed, err := c.auctionUseCase.GetAuction(auction.ID)
+		if err != nil {
+			continue
+		}
+		c.onClosed(completed)
+	}
+
+	return closed, nil
+}
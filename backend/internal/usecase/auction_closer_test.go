@@ -0,0 +1,228 @@
+package usecase_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/usecase"
+	"gorm.io/gorm"
+)
+
+// memAuctionRepo is only used inside a unit of work, so the store is already locked
+type memAuctionRepo struct {
+	domain.AuctionRepository
+	store *memStore
+}
+
+func (r *memAuctionRepo) FindByIDForUpdate(id uuid.UUID) (*domain.Auction, error) {
+	a, ok := r.store.auctions[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &a, nil
+}
+
+func (r *memAuctionRepo) FindByID(id uuid.UUID) (*domain.Auction, error) {
+	return r.FindByIDForUpdate(id)
+}
+
+func (r *memAuctionRepo) RaiseBid(id uuid.UUID, amount int, now time.Time) (bool, error) {
+	a, ok := r.store.auctions[id]
+	if !ok || a.Status != domain.AuctionStatusActive || !a.EndTime.After(now) || a.CurrentBid+a.MinBidIncrement > amount {
+		return false, nil
+	}
+	a.CurrentBid = amount
+	r.store.auctions[id] = a
+	return true, nil
+}
+
+func (r *memAuctionRepo) Update(auction *domain.Auction) error {
+	r.store.auctions[auction.ID] = *auction
+	return nil
+}
+
+type memBidRepo struct {
+	domain.BidRepository
+	store *memStore
+}
+
+func (r *memBidRepo) Create(bid *domain.Bid) error {
+	if bid.ID == uuid.Nil {
+		bid.ID = uuid.New()
+	}
+	r.store.bids = append(r.store.bids, *bid)
+	return nil
+}
+
+func (r *memBidRepo) UpdateWinningStatus(auctionID, bidID uuid.UUID) error {
+	for i := range r.store.bids {
+		if r.store.bids[i].AuctionID == auctionID {
+			r.store.bids[i].IsWinning = r.store.bids[i].ID == bidID
+		}
+	}
+	return nil
+}
+
+func (r *memBidRepo) FindWinningBid(auctionID uuid.UUID) (*domain.Bid, error) {
+	if r.store.bidErr != nil {
+		return nil, r.store.bidErr
+	}
+	for _, b := range r.store.bids {
+		if b.AuctionID == auctionID && b.IsWinning {
+			return &b, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// readAuctionRepo serves reads made outside a unit of work
+type readAuctionRepo struct {
+	domain.AuctionRepository
+	store *memStore
+}
+
+func (r *readAuctionRepo) FindByID(id uuid.UUID) (*domain.Auction, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	a, ok := r.store.auctions[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &a, nil
+}
+
+func (r *readAuctionRepo) FindExpired(now time.Time, limit int) ([]*domain.Auction, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var auctions []*domain.Auction
+	for _, a := range r.store.auctions {
+		if a.Status == domain.AuctionStatusActive && !a.EndTime.After(now) {
+			a := a
+			auctions = append(auctions, &a)
+		}
+	}
+	return auctions, nil
+}
+
+type memLeaseRepo struct {
+	mu     sync.Mutex
+	leases map[string]domain.Lease
+}
+
+func (r *memLeaseRepo) TryAcquire(name, holder string, ttl time.Duration, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.leases[name]; ok && lease.Holder != holder && !lease.ExpiresAt.Before(now) {
+		return false, nil
+	}
+	r.leases[name] = domain.Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (r *memLeaseRepo) Release(name, holder string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.leases[name].Holder == holder {
+		delete(r.leases, name)
+	}
+	return nil
+}
+
+func TestAuctionCloser_ClosesExpiredAuctionsOnOneReplica(t *testing.T) {
+	// Arrange
+	start := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	clock := func() time.Time { return now }
+
+	store := newMemStore()
+	product := domain.Product{
+		ID:          uuid.New(),
+		SellerID:    uuid.New(),
+		Price:       3000,
+		CO2ImpactKg: 2,
+		Status:      domain.StatusActive,
+	}
+	store.products[product.ID] = product
+
+	winnerID := uuid.New()
+	sold := domain.Auction{
+		ID:        uuid.New(),
+		ProductID: product.ID,
+		SellerID:  product.SellerID,
+		Status:    domain.AuctionStatusActive,
+		EndTime:   start.Add(time.Hour),
+	}
+	unsold := domain.Auction{
+		ID:        uuid.New(),
+		ProductID: uuid.New(),
+		SellerID:  uuid.New(),
+		Status:    domain.AuctionStatusActive,
+		EndTime:   start.Add(time.Hour),
+	}
+	store.auctions[sold.ID] = sold
+	store.auctions[unsold.ID] = unsold
+	store.bids = []domain.Bid{
+		{ID: uuid.New(), AuctionID: sold.ID, BidderID: uuid.New(), Amount: 3500},
+		{ID: uuid.New(), AuctionID: sold.ID, BidderID: winnerID, Amount: 4200, IsWinning: true},
+	}
+
+	auctionRepo := &readAuctionRepo{store: store}
+	auctionUseCase := usecase.NewAuctionUseCase(&memUnitOfWork{store: store}, auctionRepo, nil, nil, nil, clock)
+	leases := &memLeaseRepo{leases: make(map[string]domain.Lease)}
+
+	// Each replica runs the closer as a leased job
+	var broadcast []*domain.Auction
+	closed := map[string]int{}
+	closingJob := func(replica string, onClosed func(*domain.Auction)) *usecase.LeasedJob {
+		closer := usecase.NewAuctionCloser(auctionUseCase, auctionRepo, onClosed)
+		return usecase.NewLeasedJob(leases, "auction_closer", replica, time.Minute, clock, func(now time.Time) {
+			n, err := closer.CloseExpired(now)
+			assert.NoError(t, err)
+			closed[replica] += n
+		})
+	}
+	replicaA := closingJob("replica-a", func(auction *domain.Auction) { broadcast = append(broadcast, auction) })
+	replicaB := closingJob("replica-b", nil)
+
+	// Act
+	now = start.Add(59 * time.Minute)
+	_, err := replicaA.RunOnce()
+	assert.NoError(t, err)
+	beforeEnd := closed["replica-a"]
+
+	now = start.Add(time.Hour)
+	blocked, err := replicaB.RunOnce()
+	assert.NoError(t, err)
+	_, err = replicaA.RunOnce()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 0, beforeEnd)
+	assert.False(t, blocked)
+	assert.Equal(t, 2, closed["replica-a"])
+	assert.Zero(t, closed["replica-b"])
+	assert.Len(t, broadcast, 2)
+
+	completed := store.auctions[sold.ID]
+	assert.Equal(t, domain.AuctionStatusCompleted, completed.Status)
+	assert.Equal(t, winnerID, *completed.WinnerID)
+	assert.Equal(t, domain.StatusSold, store.products[product.ID].Status)
+
+	purchase := store.purchases[*completed.PurchaseID]
+	assert.Equal(t, winnerID, purchase.BuyerID)
+	assert.Equal(t, 4200, purchase.Price)
+	assert.Equal(t, domain.PurchaseStatusAwaitingPayment, purchase.Status)
+
+	noBids := store.auctions[unsold.ID]
+	assert.Equal(t, domain.AuctionStatusCompleted, noBids.Status)
+	assert.Nil(t, noBids.WinnerID)
+}
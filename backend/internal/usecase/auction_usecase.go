@@ -2,40 +2,83 @@ package usecase
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/yourusername/ecomate/backend/internal/domain"
+	"gorm.io/gorm"
 )
 
 type AuctionUseCase interface {
-	CreateAuction(sellerID, productID uuid.UUID, startPrice, minIncrement, durationMinutes int) (*domain.Auction, error)
-	PlaceBid(auctionID, bidderID uuid.UUID, amount int) (*domain.Bid, error)
+	CreateAuction(sellerID, productID uuid.UUID, req *domain.CreateAuctionRequest) (*domain.Auction, error)
+	PlaceBid(auctionID, bidderID uuid.UUID, amount int) (*domain.BidOutcome, error)
+	SetProxyBid(auctionID, bidderID uuid.UUID, maxAmount int) (*domain.ProxyBid, *domain.BidOutcome, error)
+	BuyNow(auctionID, buyerID uuid.UUID) (*domain.Auction, error)
+	AcceptDutchPrice(auctionID, buyerID uuid.UUID) (*domain.Auction, error)
 	GetAuction(auctionID uuid.UUID) (*domain.Auction, error)
 	GetActiveAuctions() ([]*domain.Auction, error)
+	GetUpcomingAuctions() ([]*domain.Auction, error)
 	GetAuctionBids(auctionID uuid.UUID) ([]*domain.Bid, error)
 	CompleteAuction(auctionID uuid.UUID) error
 }
 
+// Clock returns the current time. Time-dependent logic takes one so tests
+// can control it; production code passes time.Now.
+type Clock func() time.Time
+
 type auctionUseCase struct {
+	uow         domain.UnitOfWork
 	auctionRepo domain.AuctionRepository
 	bidRepo     domain.BidRepository
 	productRepo domain.ProductRepository
+	notifier    NotificationUseCase
+	now         Clock
 }
 
 func NewAuctionUseCase(
+	uow domain.UnitOfWork,
 	auctionRepo domain.AuctionRepository,
 	bidRepo domain.BidRepository,
 	productRepo domain.ProductRepository,
+	notifier NotificationUseCase,
+	clock Clock,
 ) AuctionUseCase {
 	return &auctionUseCase{
+		uow:         uow,
 		auctionRepo: auctionRepo,
 		bidRepo:     bidRepo,
 		productRepo: productRepo,
+		notifier:    notifier,
+		now:         clock,
 	}
 }
 
-func (u *auctionUseCase) CreateAuction(sellerID, productID uuid.UUID, startPrice, minIncrement, durationMinutes int) (*domain.Auction, error) {
+// maxScheduleAhead limits how far in the future an auction can be scheduled
+const maxScheduleAhead = 30 * 24 * time.Hour
+
+func (u *auctionUseCase) CreateAuction(sellerID, productID uuid.UUID, req *domain.CreateAuctionRequest) (*domain.Auction, error) {
+	auctionType := req.Type
+	if auctionType == "" {
+		auctionType = domain.AuctionTypeEnglish
+	}
+	if err := validateAuctionRequest(auctionType, req); err != nil {
+		return nil, err
+	}
+
+	now := u.now()
+	startTime := now
+	if req.StartTime != nil {
+		if req.StartTime.Before(now) {
+			return nil, errors.New("start time must be in the future")
+		}
+		if req.StartTime.After(now.Add(maxScheduleAhead)) {
+			return nil, errors.New("auctions can be scheduled at most 30 days ahead")
+		}
+		startTime = *req.StartTime
+	}
+
 	// Get product
 	product, err := u.productRepo.FindByID(productID)
 	if err != nil {
@@ -53,28 +96,283 @@ func (u *auctionUseCase) CreateAuction(sellerID, productID uuid.UUID, startPrice
 	}
 
 	// Create auction
-	now := time.Now()
+	softClose := req.SoftCloseMinutes
+	if softClose == 0 {
+		softClose = domain.DefaultSoftCloseMinutes
+	}
 	auction := &domain.Auction{
-		ProductID:       productID,
-		SellerID:        sellerID,
-		StartPrice:      startPrice,
-		CurrentBid:      startPrice,
-		MinBidIncrement: minIncrement,
-		Status:          domain.AuctionStatusActive,
-		StartTime:       now,
-		EndTime:         now.Add(time.Duration(durationMinutes) * time.Minute),
+		ProductID:        productID,
+		SellerID:         sellerID,
+		Type:             auctionType,
+		StartPrice:       req.StartPrice,
+		CurrentBid:       req.StartPrice,
+		MinBidIncrement:  req.MinBidIncrement,
+		ReservePrice:     req.ReservePrice,
+		HasReserve:       req.ReservePrice > 0,
+		BuyNowPrice:      req.BuyNowPrice,
+		SoftCloseMinutes: softClose,
+		FloorPrice:       req.FloorPrice,
+		PriceDropAmount:  req.PriceDropAmount,
+		Status:           domain.AuctionStatusActive,
+		StartTime:        startTime,
+		EndTime:          startTime.Add(time.Duration(req.DurationMinutes) * time.Minute),
+
+		PriceDropIntervalMinutes: req.PriceDropIntervalMinutes,
 	}
 
 	if err := u.auctionRepo.Create(auction); err != nil {
 		return nil, err
 	}
 
-	return u.auctionRepo.FindByID(auction.ID)
+	return u.GetAuction(auction.ID)
+}
+
+func validateAuctionRequest(auctionType domain.AuctionType, req *domain.CreateAuctionRequest) error {
+	switch auctionType {
+	case domain.AuctionTypeEnglish:
+		if req.MinBidIncrement < 1 {
+			return errors.New("min bid increment is required")
+		}
+		if req.ReservePrice > 0 && req.ReservePrice < req.StartPrice {
+			return errors.New("reserve price must be at least the start price")
+		}
+		if req.BuyNowPrice > 0 && (req.BuyNowPrice <= req.StartPrice || req.BuyNowPrice < req.ReservePrice) {
+			return errors.New("buy it now price must be above the start and reserve prices")
+		}
+	case domain.AuctionTypeDutch:
+		if req.FloorPrice < 1 || req.FloorPrice >= req.StartPrice {
+			return errors.New("floor price must be below the start price")
+		}
+		if req.PriceDropAmount < 1 || req.PriceDropIntervalMinutes < 1 {
+			return errors.New("price drop amount and interval are required")
+		}
+		if req.ReservePrice > 0 || req.BuyNowPrice > 0 {
+			return errors.New("reserve and buy it now prices apply to english auctions only")
+		}
+	default:
+		return fmt.Errorf("unknown auction type: %s", auctionType)
+	}
+	return nil
+}
+
+// PlaceBid records a bid if it beats the current one. The auction row is
+// raised with a conditional update, so of two concurrent bids only one can
+// win and the other gets an *domain.OutbidError. Proxies then respond to the
+// new price in the same transaction.
+func (u *auctionUseCase) PlaceBid(auctionID, bidderID uuid.UUID, amount int) (*domain.BidOutcome, error) {
+	outcome := &domain.BidOutcome{}
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		now := u.now()
+		auction, err := u.biddableAuction(repos, auctionID, bidderID, now)
+		if err != nil {
+			return err
+		}
+		originalEnd := auction.EndTime
+
+		// Check minimum bid
+		minBid := auction.CurrentBid + auction.MinBidIncrement
+		if amount < minBid {
+			return &domain.OutbidError{MinBid: minBid}
+		}
+
+		outcome.Bid, auction, err = placeBid(repos, auctionID, bidderID, amount, false, now)
+		if err != nil {
+			return err
+		}
+
+		outcome.AutoBids, auction, err = runProxyBids(repos, auction, now)
+		if err != nil {
+			return err
+		}
+
+		outcome.EndTime = auction.EndTime
+		outcome.Extended = auction.EndTime.After(originalEnd)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return outcome, nil
 }
 
-func (u *auctionUseCase) PlaceBid(auctionID, bidderID uuid.UUID, amount int) (*domain.Bid, error) {
+// SetProxyBid registers or updates the bidder's private maximum and lets
+// proxies bid straight away if the bidder is not already leading
+func (u *auctionUseCase) SetProxyBid(auctionID, bidderID uuid.UUID, maxAmount int) (*domain.ProxyBid, *domain.BidOutcome, error) {
+	var proxy *domain.ProxyBid
+	outcome := &domain.BidOutcome{}
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		now := u.now()
+		auction, err := u.biddableAuction(repos, auctionID, bidderID, now)
+		if err != nil {
+			return err
+		}
+		originalEnd := auction.EndTime
+
+		minBid := auction.CurrentBid + auction.MinBidIncrement
+		leader, err := findWinningBid(repos, auctionID)
+		if err != nil {
+			return err
+		}
+		if leader != nil && leader.BidderID == bidderID {
+			minBid = auction.CurrentBid
+		}
+		if maxAmount < minBid {
+			return &domain.OutbidError{MinBid: minBid}
+		}
+
+		proxy = &domain.ProxyBid{
+			AuctionID:    auctionID,
+			BidderID:     bidderID,
+			MaxAmount:    maxAmount,
+			RegisteredAt: now,
+		}
+		if err := repos.ProxyBids.Upsert(proxy); err != nil {
+			return err
+		}
+
+		outcome.AutoBids, auction, err = runProxyBids(repos, auction, now)
+		if err != nil {
+			return err
+		}
+
+		outcome.EndTime = auction.EndTime
+		outcome.Extended = auction.EndTime.After(originalEnd)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return proxy, outcome, nil
+}
+
+// BuyNow ends the auction immediately, selling to buyerID at the buy-it-now price
+func (u *auctionUseCase) BuyNow(auctionID, buyerID uuid.UUID) (*domain.Auction, error) {
+	var auction *domain.Auction
+	var bid *domain.Bid
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		var err error
+		auction, err = repos.Auctions.FindByIDForUpdate(auctionID)
+		if err != nil {
+			return errors.New("auction not found")
+		}
+
+		if auction.SellerID == buyerID {
+			return errors.New("cannot buy your own auction")
+		}
+
+		now := u.now()
+		if !auction.HasStarted(now) {
+			return errors.New("auction has not started yet")
+		}
+		if now.After(auction.EndTime) {
+			return errors.New("auction has ended")
+		}
+
+		if !auction.BuyNowAvailable() {
+			return errors.New("buy it now is not available for this auction")
+		}
+
+		bid = &domain.Bid{
+			AuctionID: auctionID,
+			BidderID:  buyerID,
+			Amount:    auction.BuyNowPrice,
+			IsWinning: true,
+			CreatedAt: now,
+		}
+		if err := repos.Bids.Create(bid); err != nil {
+			return err
+		}
+		if err := repos.Bids.UpdateWinningStatus(auctionID, bid.ID); err != nil {
+			return err
+		}
+
+		auction.CurrentBid = auction.BuyNowPrice
+		auction.ReserveMet = true
+		sold, err := sellToWinner(repos, auction, bid, now)
+		if err != nil {
+			return err
+		}
+		if !sold {
+			return errors.New("product is not available")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.notifyCompleted(auction, bid)
+
+	return u.GetAuction(auctionID)
+}
+
+// AcceptDutchPrice sells a Dutch auction to the first buyer to accept its
+// current price. The auction row is locked, so only one buyer can win.
+func (u *auctionUseCase) AcceptDutchPrice(auctionID, buyerID uuid.UUID) (*domain.Auction, error) {
+	var auction *domain.Auction
+	var bid *domain.Bid
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		var err error
+		auction, err = repos.Auctions.FindByIDForUpdate(auctionID)
+		if err != nil {
+			return errors.New("auction not found")
+		}
+
+		if auction.Type != domain.AuctionTypeDutch {
+			return errors.New("only dutch auctions can be accepted")
+		}
+		if auction.Status != domain.AuctionStatusActive {
+			return errors.New("auction is not active")
+		}
+		if auction.SellerID == buyerID {
+			return errors.New("cannot buy your own auction")
+		}
+
+		now := u.now()
+		if !auction.HasStarted(now) {
+			return errors.New("auction has not started yet")
+		}
+		if now.After(auction.EndTime) {
+			return errors.New("auction has ended")
+		}
+
+		price := auction.PriceAt(now)
+		bid = &domain.Bid{
+			AuctionID: auctionID,
+			BidderID:  buyerID,
+			Amount:    price,
+			IsWinning: true,
+			CreatedAt: now,
+		}
+		if err := repos.Bids.Create(bid); err != nil {
+			return err
+		}
+
+		auction.CurrentBid = price
+		sold, err := sellToWinner(repos, auction, bid, now)
+		if err != nil {
+			return err
+		}
+		if !sold {
+			return errors.New("product is not available")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.notifyCompleted(auction, bid)
+
+	return u.GetAuction(auctionID)
+}
+
+// biddableAuction loads an auction that bidderID may currently bid on
+func (u *auctionUseCase) biddableAuction(repos *domain.Repositories, auctionID, bidderID uuid.UUID, now time.Time) (*domain.Auction, error) {
 	// Get auction
-	auction, err := u.auctionRepo.FindByID(auctionID)
+	auction, err := repos.Auctions.FindByID(auctionID)
 	if err != nil {
 		return nil, errors.New("auction not found")
 	}
@@ -84,20 +382,65 @@ func (u *auctionUseCase) PlaceBid(auctionID, bidderID uuid.UUID, amount int) (*d
 		return nil, errors.New("auction is not active")
 	}
 
+	if !auction.HasStarted(now) {
+		return nil, errors.New("auction has not started yet")
+	}
+
 	// Check if auction has ended
-	if time.Now().After(auction.EndTime) {
+	if now.After(auction.EndTime) {
 		return nil, errors.New("auction has ended")
 	}
 
+	if auction.Type == domain.AuctionTypeDutch {
+		return nil, errors.New("dutch auctions take no bids; accept the current price instead")
+	}
+
 	// Cannot bid on own auction
 	if auction.SellerID == bidderID {
 		return nil, errors.New("cannot bid on your own auction")
 	}
 
-	// Check minimum bid
-	minBid := auction.CurrentBid + auction.MinBidIncrement
-	if amount < minBid {
-		return nil, errors.New("bid amount must be at least " + string(rune(minBid)))
+	return auction, nil
+}
+
+// placeBid raises the auction to amount and records the bid as winning. A
+// bid inside the soft-close window pushes the end time back so others have
+// a chance to respond. It returns the auction as updated by the bid.
+func placeBid(repos *domain.Repositories, auctionID, bidderID uuid.UUID, amount int, auto bool, now time.Time) (*domain.Bid, *domain.Auction, error) {
+	raised, err := repos.Auctions.RaiseBid(auctionID, amount, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !raised {
+		latest, err := repos.Auctions.FindByID(auctionID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if latest.Status != domain.AuctionStatusActive {
+			return nil, nil, errors.New("auction is not active")
+		}
+		return nil, nil, &domain.OutbidError{MinBid: latest.CurrentBid + latest.MinBidIncrement}
+	}
+
+	// The row is locked by the update above, so this read is current
+	auction, err := repos.Auctions.FindByIDForUpdate(auctionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed := false
+	if !auction.ReserveMet && amount >= auction.ReservePrice {
+		auction.ReserveMet = true
+		changed = true
+	}
+	if window := auction.SoftCloseWindow(); auction.EndTime.Sub(now) < window {
+		auction.EndTime = now.Add(window)
+		changed = true
+	}
+	if changed {
+		if err := repos.Auctions.Update(auction); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// Create bid
@@ -106,59 +449,250 @@ func (u *auctionUseCase) PlaceBid(auctionID, bidderID uuid.UUID, amount int) (*d
 		BidderID:  bidderID,
 		Amount:    amount,
 		IsWinning: true,
+		IsAuto:    auto,
+		CreatedAt: now,
+	}
+	if err := repos.Bids.Create(bid); err != nil {
+		return nil, nil, err
 	}
 
-	if err := u.bidRepo.Create(bid); err != nil {
-		return nil, err
+	if err := repos.Bids.UpdateWinningStatus(auctionID, bid.ID); err != nil {
+		return nil, nil, err
 	}
+	return bid, auction, nil
+}
 
-	// Update auction current bid and winning status
-	if err := u.bidRepo.UpdateWinningStatus(auctionID, bid.ID); err != nil {
-		return nil, err
+// runProxyBids places the bids registered proxies make at the current price
+func runProxyBids(repos *domain.Repositories, auction *domain.Auction, now time.Time) ([]*domain.Bid, *domain.Auction, error) {
+	proxies, err := repos.ProxyBids.FindByAuctionID(auction.ID)
+	if err != nil || len(proxies) == 0 {
+		return nil, auction, err
 	}
 
-	auction.CurrentBid = amount
-	if err := u.auctionRepo.Update(auction); err != nil {
-		return nil, err
+	winning, err := findWinningBid(repos, auction.ID)
+	if err != nil {
+		return nil, auction, err
+	}
+	var leader *uuid.UUID
+	if winning != nil {
+		leader = &winning.BidderID
 	}
 
-	return bid, nil
+	var bids []*domain.Bid
+	for _, auto := range ResolveProxyBids(auction.CurrentBid, leader, auction.MinBidIncrement, proxies) {
+		var bid *domain.Bid
+		bid, auction, err = placeBid(repos, auction.ID, auto.BidderID, auto.Amount, true, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		bids = append(bids, bid)
+	}
+	return bids, auction, nil
+}
+
+// AutoBid is a bid a proxy places on its bidder's behalf
+type AutoBid struct {
+	BidderID uuid.UUID
+	Amount   int
+}
+
+// ResolveProxyBids works out, eBay-style, how proxies respond to the current
+// price. The proxy with the highest maximum wins, ties going to the earliest
+// registration, and pays one increment over the runner-up's maximum, capped
+// at its own. If the winner can still beat it, the runner-up first bids its
+// full maximum so the price history shows how it was outbid. The result is
+// stable: running it again on the new price yields no further bids.
+func ResolveProxyBids(current int, leader *uuid.UUID, increment int, proxies []*domain.ProxyBid) []AutoBid {
+	leads := func(bidderID uuid.UUID) bool {
+		return leader != nil && *leader == bidderID
+	}
+
+	// The leader's proxy defends; anyone else needs to be able to beat the price
+	contenders := make([]*domain.ProxyBid, 0, len(proxies))
+	for _, proxy := range proxies {
+		if leads(proxy.BidderID) || proxy.MaxAmount >= current+increment {
+			contenders = append(contenders, proxy)
+		}
+	}
+	if len(contenders) == 0 || (len(contenders) == 1 && leads(contenders[0].BidderID)) {
+		return nil
+	}
+
+	sort.SliceStable(contenders, func(i, j int) bool {
+		if contenders[i].MaxAmount != contenders[j].MaxAmount {
+			return contenders[i].MaxAmount > contenders[j].MaxAmount
+		}
+		return contenders[i].RegisteredAt.Before(contenders[j].RegisteredAt)
+	})
+
+	top := contenders[0]
+	var bids []AutoBid
+	price := current
+	target := current + increment
+	if len(contenders) > 1 {
+		runnerUp := contenders[1]
+		if runnerUp.MaxAmount >= price+increment && runnerUp.MaxAmount+increment <= top.MaxAmount {
+			bids = append(bids, AutoBid{BidderID: runnerUp.BidderID, Amount: runnerUp.MaxAmount})
+			price = runnerUp.MaxAmount
+		}
+		target = runnerUp.MaxAmount + increment
+	}
+
+	if target < price+increment {
+		target = price + increment
+	}
+	if target > top.MaxAmount {
+		target = top.MaxAmount
+	}
+	if target < price+increment {
+		return bids
+	}
+	return append(bids, AutoBid{BidderID: top.BidderID, Amount: target})
 }
 
 func (u *auctionUseCase) GetAuction(auctionID uuid.UUID) (*domain.Auction, error) {
-	return u.auctionRepo.FindByID(auctionID)
+	auction, err := u.auctionRepo.FindByID(auctionID)
+	if err != nil {
+		return nil, err
+	}
+	auction.CurrentPrice = auction.PriceAt(u.now())
+	return auction, nil
 }
 
 func (u *auctionUseCase) GetActiveAuctions() ([]*domain.Auction, error) {
-	return u.auctionRepo.FindActiveAuctions()
+	return u.withCurrentPrices(u.auctionRepo.FindActiveAuctions())
+}
+
+// GetUpcomingAuctions is the "starting soon" listing
+func (u *auctionUseCase) GetUpcomingAuctions() ([]*domain.Auction, error) {
+	now := u.now()
+	return u.withCurrentPrices(u.auctionRepo.FindUpcoming(now, now.Add(domain.StartingSoonWindow)))
+}
+
+func (u *auctionUseCase) withCurrentPrices(auctions []*domain.Auction, err error) ([]*domain.Auction, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	now := u.now()
+	for _, auction := range auctions {
+		auction.CurrentPrice = auction.PriceAt(now)
+	}
+	return auctions, nil
 }
 
 func (u *auctionUseCase) GetAuctionBids(auctionID uuid.UUID) ([]*domain.Bid, error) {
 	return u.bidRepo.FindByAuctionID(auctionID)
 }
 
+// CompleteAuction closes an ended auction. If there is a winning bid that
+// meets the reserve, the product is sold to the winner at the bid amount in
+// the same transaction.
 func (u *auctionUseCase) CompleteAuction(auctionID uuid.UUID) error {
-	auction, err := u.auctionRepo.FindByID(auctionID)
+	var auction *domain.Auction
+	var winningBid *domain.Bid
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		var err error
+		auction, err = repos.Auctions.FindByIDForUpdate(auctionID)
+		if err != nil {
+			return errors.New("auction not found")
+		}
+
+		if auction.Status != domain.AuctionStatusActive {
+			return errors.New("auction is not active")
+		}
+
+		// Check if auction has ended (a late bid may have extended it)
+		now := u.now()
+		if now.Before(auction.EndTime) {
+			return errors.New("auction has not ended yet")
+		}
+
+		// Get winning bid
+		winningBid, err = findWinningBid(repos, auctionID)
+		if err != nil {
+			return err
+		}
+		if winningBid == nil || winningBid.Amount < auction.ReservePrice {
+			// No bids or reserve not met, just mark as completed
+			winningBid = nil
+			auction.Status = domain.AuctionStatusCompleted
+			return repos.Auctions.Update(auction)
+		}
+
+		sold, err := sellToWinner(repos, auction, winningBid, now)
+		if !sold {
+			winningBid = nil
+		}
+		return err
+	})
 	if err != nil {
-		return errors.New("auction not found")
+		return err
 	}
 
-	// Check if auction has ended
-	if time.Now().Before(auction.EndTime) {
-		return errors.New("auction has not ended yet")
+	u.notifyCompleted(auction, winningBid)
+	return nil
+}
+
+// findWinningBid returns the leading bid of an auction, or nil if nobody has
+// bid. Other errors are returned so the unit of work rolls back.
+func findWinningBid(repos *domain.Repositories, auctionID uuid.UUID) (*domain.Bid, error) {
+	bid, err := repos.Bids.FindWinningBid(auctionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
 	}
+	if err != nil {
+		return nil, err
+	}
+	return bid, nil
+}
 
-	// Get winning bid
-	winningBid, err := u.bidRepo.FindWinningBid(auctionID)
+// sellToWinner completes the auction by selling the product to the winning
+// bidder. If the product was sold or withdrawn while the auction ran, the
+// auction is cancelled instead and sold is false.
+func sellToWinner(repos *domain.Repositories, auction *domain.Auction, winningBid *domain.Bid, now time.Time) (sold bool, err error) {
+	product, err := repos.Products.FindByIDForUpdate(auction.ProductID)
 	if err != nil {
-		// No bids, just mark as completed
-		auction.Status = domain.AuctionStatusCompleted
-		return u.auctionRepo.Update(auction)
+		return false, errors.New("product not found")
+	}
+
+	if product.Status != domain.StatusActive {
+		auction.Status = domain.AuctionStatusCancelled
+		return false, repos.Auctions.Update(auction)
+	}
+
+	purchase := &domain.Purchase{
+		BuyerID: winningBid.BidderID,
+		Price:   winningBid.Amount,
+	}
+	if err := sellProduct(repos, product, purchase, now); err != nil {
+		return false, err
 	}
 
 	// Update auction with winner
-	auction.WinnerID = &winningBid.BidderID
 	auction.Status = domain.AuctionStatusCompleted
+	auction.WinnerID = &winningBid.BidderID
+	auction.PurchaseID = &purchase.ID
+	return true, repos.Auctions.Update(auction)
+}
+
+func (u *auctionUseCase) notifyCompleted(auction *domain.Auction, winningBid *domain.Bid) {
+	if u.notifier == nil {
+		return
+	}
+
+	link := fmt.Sprintf("/auctions/%s", auction.ID)
+	if winningBid == nil {
+		message := "The auction ended without a winning bid"
+		if auction.HasReserve && !auction.ReserveMet {
+			message = "The auction ended without meeting your reserve price"
+		}
+		_ = u.notifier.Create(auction.SellerID, domain.NotificationTypeAuction, "Your auction has ended", message, link)
+		return
+	}
 
-	return u.auctionRepo.Update(auction)
+	_ = u.notifier.Create(winningBid.BidderID, domain.NotificationTypeAuction, "You won the auction",
+		fmt.Sprintf("Your bid of ¥%d won. Please complete payment.", winningBid.Amount), "/purchases")
+	_ = u.notifier.Create(auction.SellerID, domain.NotificationTypeAuction, "Your auction has ended",
+		fmt.Sprintf("The item sold for ¥%d", winningBid.Amount), link)
 }
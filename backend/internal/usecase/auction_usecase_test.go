@@ -0,0 +1,436 @@
+package usecase_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/usecase"
+)
+
+type memProxyBidRepo struct {
+	store *memStore
+}
+
+func (r *memProxyBidRepo) Upsert(proxy *domain.ProxyBid) error {
+	for i, p := range r.store.proxies {
+		if p.AuctionID == proxy.AuctionID && p.BidderID == proxy.BidderID {
+			r.store.proxies[i].MaxAmount = proxy.MaxAmount
+			r.store.proxies[i].RegisteredAt = proxy.RegisteredAt
+			return nil
+		}
+	}
+	if proxy.ID == uuid.Nil {
+		proxy.ID = uuid.New()
+	}
+	r.store.proxies = append(r.store.proxies, *proxy)
+	return nil
+}
+
+func (r *memProxyBidRepo) FindByAuctionID(auctionID uuid.UUID) ([]*domain.ProxyBid, error) {
+	var proxies []*domain.ProxyBid
+	for _, p := range r.store.proxies {
+		if p.AuctionID == auctionID {
+			p := p
+			proxies = append(proxies, &p)
+		}
+	}
+	return proxies, nil
+}
+
+func newAuctionFixture(now time.Time, opts ...func(*domain.Auction)) (*memStore, usecase.AuctionUseCase, domain.Auction) {
+	store := newMemStore()
+	auction := domain.Auction{
+		ID:              uuid.New(),
+		ProductID:       uuid.New(),
+		SellerID:        uuid.New(),
+		StartPrice:      1000,
+		CurrentBid:      1000,
+		MinBidIncrement: 100,
+		Status:          domain.AuctionStatusActive,
+		StartTime:       now,
+		EndTime:         now.Add(time.Hour),
+	}
+	for _, opt := range opts {
+		opt(&auction)
+	}
+	store.auctions[auction.ID] = auction
+
+	clock := func() time.Time { return now }
+	useCase := usecase.NewAuctionUseCase(&memUnitOfWork{store: store}, &readAuctionRepo{store: store}, nil, nil, nil, clock)
+	return store, useCase, auction
+}
+
+func TestAuctionUseCase_PlaceBid_ConcurrentBidsKeepHighest(t *testing.T) {
+	// Arrange
+	store, useCase, auction := newAuctionFixture(time.Now())
+
+	const bidders = 30
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var outbid int
+	start := make(chan struct{})
+
+	// Act
+	for i := 0; i < bidders; i++ {
+		wg.Add(1)
+		go func(amount int) {
+			defer wg.Done()
+			<-start
+			_, err := useCase.PlaceBid(auction.ID, uuid.New(), amount)
+
+			var outbidErr *domain.OutbidError
+			if errors.As(err, &outbidErr) {
+				mu.Lock()
+				outbid++
+				mu.Unlock()
+			}
+		}(1100 + i*100)
+	}
+	close(start)
+	wg.Wait()
+
+	// Assert
+	highest := 1100 + (bidders-1)*100
+	assert.Equal(t, highest, store.auctions[auction.ID].CurrentBid)
+	assert.Equal(t, bidders, len(store.bids)+outbid)
+
+	winning := 0
+	for _, bid := range store.bids {
+		if bid.IsWinning {
+			winning++
+			assert.Equal(t, highest, bid.Amount)
+		}
+	}
+	assert.Equal(t, 1, winning)
+}
+
+func TestAuctionUseCase_PlaceBid_TooLowReturnsOutbidError(t *testing.T) {
+	// Arrange
+	_, useCase, auction := newAuctionFixture(time.Now())
+
+	// Act
+	_, err := useCase.PlaceBid(auction.ID, uuid.New(), 1050)
+
+	// Assert
+	var outbid *domain.OutbidError
+	assert.True(t, errors.As(err, &outbid))
+	assert.Equal(t, 1100, outbid.MinBid)
+	assert.EqualError(t, err, "bid amount must be at least 1100")
+}
+
+func TestResolveProxyBids(t *testing.T) {
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	t0 := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	proxy := func(bidder uuid.UUID, max int, registered time.Duration) *domain.ProxyBid {
+		return &domain.ProxyBid{BidderID: bidder, MaxAmount: max, RegisteredAt: t0.Add(registered)}
+	}
+
+	tests := []struct {
+		name    string
+		current int
+		leader  *uuid.UUID
+		proxies []*domain.ProxyBid
+		want    []usecase.AutoBid
+	}{
+		{
+			name:    "no proxies",
+			current: 1000,
+			want:    nil,
+		},
+		{
+			name:    "single proxy outbids manual leader by one increment",
+			current: 1000,
+			leader:  &carol,
+			proxies: []*domain.ProxyBid{proxy(alice, 3000, 0)},
+			want:    []usecase.AutoBid{{BidderID: alice, Amount: 1100}},
+		},
+		{
+			name:    "leading proxy does not bid against itself",
+			current: 1100,
+			leader:  &alice,
+			proxies: []*domain.ProxyBid{proxy(alice, 3000, 0)},
+			want:    nil,
+		},
+		{
+			name:    "proxy below the next minimum stays quiet",
+			current: 1000,
+			leader:  &carol,
+			proxies: []*domain.ProxyBid{proxy(alice, 1050, 0)},
+			want:    nil,
+		},
+		{
+			name:    "runner-up bids its maximum and winner beats it by one increment",
+			current: 1000,
+			leader:  &carol,
+			proxies: []*domain.ProxyBid{proxy(alice, 2000, 0), proxy(bob, 3000, time.Minute)},
+			want: []usecase.AutoBid{
+				{BidderID: alice, Amount: 2000},
+				{BidderID: bob, Amount: 2100},
+			},
+		},
+		{
+			name:    "leading proxy defends against a lower challenger",
+			current: 1500,
+			leader:  &alice,
+			proxies: []*domain.ProxyBid{proxy(alice, 3000, 0), proxy(bob, 2000, time.Minute)},
+			want: []usecase.AutoBid{
+				{BidderID: bob, Amount: 2000},
+				{BidderID: alice, Amount: 2100},
+			},
+		},
+		{
+			name:    "winner is capped at its own maximum",
+			current: 1000,
+			leader:  &carol,
+			proxies: []*domain.ProxyBid{proxy(alice, 2000, 0), proxy(bob, 2050, time.Minute)},
+			want:    []usecase.AutoBid{{BidderID: bob, Amount: 2050}},
+		},
+		{
+			name:    "tie goes to the earliest registration",
+			current: 1000,
+			leader:  &carol,
+			proxies: []*domain.ProxyBid{proxy(bob, 2000, time.Minute), proxy(alice, 2000, 0)},
+			want:    []usecase.AutoBid{{BidderID: alice, Amount: 2000}},
+		},
+		{
+			name:    "tie with the leader keeps the earlier leader in front",
+			current: 1200,
+			leader:  &alice,
+			proxies: []*domain.ProxyBid{proxy(alice, 2000, 0), proxy(bob, 2000, time.Minute)},
+			want:    []usecase.AutoBid{{BidderID: alice, Amount: 2000}},
+		},
+		{
+			name:    "third proxy only affects the order, not the price",
+			current: 1000,
+			leader:  nil,
+			proxies: []*domain.ProxyBid{proxy(alice, 1500, 0), proxy(bob, 2500, time.Minute), proxy(carol, 1800, 2*time.Minute)},
+			want: []usecase.AutoBid{
+				{BidderID: carol, Amount: 1800},
+				{BidderID: bob, Amount: 1900},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := usecase.ResolveProxyBids(tt.current, tt.leader, 100, tt.proxies)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAuctionUseCase_ProxyBidRespondsToManualBid(t *testing.T) {
+	// Arrange
+	store, useCase, auction := newAuctionFixture(time.Now())
+	alice, bob := uuid.New(), uuid.New()
+
+	_, opening, err := useCase.SetProxyBid(auction.ID, alice, 2000)
+	assert.NoError(t, err)
+
+	// Act
+	outcome, err := useCase.PlaceBid(auction.ID, bob, 1500)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, opening.AutoBids, 1)
+	assert.Equal(t, 1100, opening.AutoBids[0].Amount)
+	assert.False(t, outcome.Bid.IsAuto)
+	autoBids := outcome.AutoBids
+	assert.Len(t, autoBids, 1)
+	assert.Equal(t, alice, autoBids[0].BidderID)
+	assert.Equal(t, 1600, autoBids[0].Amount)
+	assert.True(t, autoBids[0].IsAuto)
+	assert.Equal(t, 1600, store.auctions[auction.ID].CurrentBid)
+
+	// A bid above alice's maximum takes the lead and her proxy stops
+	outcome, err = useCase.PlaceBid(auction.ID, bob, 2100)
+	assert.NoError(t, err)
+	assert.Empty(t, outcome.AutoBids)
+}
+
+func TestAuctionUseCase_PlaceBid_SoftCloseExtendsEndTime(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	store, useCase, auction := newAuctionFixture(now, func(a *domain.Auction) {
+		a.SoftCloseMinutes = 2
+		a.EndTime = now.Add(30 * time.Second)
+	})
+
+	// Act
+	outcome, err := useCase.PlaceBid(auction.ID, uuid.New(), 1100)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, outcome.Extended)
+	assert.Equal(t, now.Add(2*time.Minute), outcome.EndTime)
+	assert.Equal(t, outcome.EndTime, store.auctions[auction.ID].EndTime)
+}
+
+func TestAuctionUseCase_CompleteAuction_ReserveNotMet(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	store, useCase, auction := newAuctionFixture(now, func(a *domain.Auction) {
+		a.ReservePrice = 5000
+		a.HasReserve = true
+	})
+	_, err := useCase.PlaceBid(auction.ID, uuid.New(), 1500)
+	assert.NoError(t, err)
+	ended := store.auctions[auction.ID]
+	ended.EndTime = now
+	store.auctions[auction.ID] = ended
+
+	// Act
+	err = useCase.CompleteAuction(auction.ID)
+
+	// Assert
+	assert.NoError(t, err)
+	closed := store.auctions[auction.ID]
+	assert.Equal(t, domain.AuctionStatusCompleted, closed.Status)
+	assert.False(t, closed.ReserveMet)
+	assert.Nil(t, closed.WinnerID)
+	assert.Empty(t, store.purchases)
+}
+
+func TestAuctionUseCase_CompleteAuction_LookupFailureIsNotNoBids(t *testing.T) {
+	// Arrange: a bid meets the reserve but the database fails on completion
+	now := time.Now()
+	store, useCase, auction := newAuctionFixture(now)
+	_, err := useCase.PlaceBid(auction.ID, uuid.New(), 1500)
+	assert.NoError(t, err)
+	ended := store.auctions[auction.ID]
+	ended.EndTime = now
+	store.auctions[auction.ID] = ended
+	store.bidErr = errors.New("connection reset")
+
+	// Act
+	err = useCase.CompleteAuction(auction.ID)
+
+	// Assert: the auction is left for the next attempt
+	assert.EqualError(t, err, "connection reset")
+	assert.Equal(t, domain.AuctionStatusActive, store.auctions[auction.ID].Status)
+}
+
+func TestAuctionUseCase_BuyNow(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	product := domain.Product{ID: uuid.New(), SellerID: uuid.New(), Status: domain.StatusActive}
+	store, useCase, auction := newAuctionFixture(now, func(a *domain.Auction) {
+		a.ProductID = product.ID
+		a.SellerID = product.SellerID
+		a.BuyNowPrice = 4000
+	})
+	store.products[product.ID] = product
+	buyer := uuid.New()
+
+	// Act
+	_, err := useCase.BuyNow(auction.ID, buyer)
+
+	// Assert
+	assert.NoError(t, err)
+	closed := store.auctions[auction.ID]
+	assert.Equal(t, domain.AuctionStatusCompleted, closed.Status)
+	assert.Equal(t, buyer, *closed.WinnerID)
+	assert.Equal(t, 4000, store.purchases[*closed.PurchaseID].Price)
+	assert.Equal(t, domain.StatusSold, store.products[product.ID].Status)
+
+	_, err = useCase.BuyNow(auction.ID, uuid.New())
+	assert.Error(t, err)
+}
+
+func TestAuctionUseCase_BuyNow_UnavailableOnceBidMeetsReserve(t *testing.T) {
+	// Arrange
+	_, useCase, auction := newAuctionFixture(time.Now(), func(a *domain.Auction) {
+		a.BuyNowPrice = 4000
+	})
+	_, err := useCase.PlaceBid(auction.ID, uuid.New(), 1100)
+	assert.NoError(t, err)
+
+	// Act
+	_, err = useCase.BuyNow(auction.ID, uuid.New())
+
+	// Assert
+	assert.EqualError(t, err, "buy it now is not available for this auction")
+}
+
+func TestAuction_PriceAt(t *testing.T) {
+	start := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	dutch := &domain.Auction{
+		Type:                     domain.AuctionTypeDutch,
+		StartPrice:               10000,
+		FloorPrice:               6500,
+		PriceDropAmount:          1000,
+		PriceDropIntervalMinutes: 30,
+		StartTime:                start,
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{name: "before start", at: start.Add(-time.Hour), want: 10000},
+		{name: "at start", at: start, want: 10000},
+		{name: "just before first drop", at: start.Add(29 * time.Minute), want: 10000},
+		{name: "after one drop", at: start.Add(30 * time.Minute), want: 9000},
+		{name: "after three drops", at: start.Add(95 * time.Minute), want: 7000},
+		{name: "clamped at floor", at: start.Add(10 * time.Hour), want: 6500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, dutch.PriceAt(tt.at))
+		})
+	}
+}
+
+func TestAuctionUseCase_AcceptDutchPrice(t *testing.T) {
+	// Arrange
+	start := time.Now()
+	product := domain.Product{ID: uuid.New(), SellerID: uuid.New(), Status: domain.StatusActive}
+	store, useCase, auction := newAuctionFixture(start.Add(65*time.Minute), func(a *domain.Auction) {
+		a.Type = domain.AuctionTypeDutch
+		a.ProductID = product.ID
+		a.SellerID = product.SellerID
+		a.StartPrice = 5000
+		a.FloorPrice = 2000
+		a.PriceDropAmount = 500
+		a.PriceDropIntervalMinutes = 30
+		a.StartTime = start
+		a.EndTime = start.Add(4 * time.Hour)
+	})
+	store.products[product.ID] = product
+	buyer := uuid.New()
+
+	// Act
+	_, bidErr := useCase.PlaceBid(auction.ID, uuid.New(), 6000)
+	_, err := useCase.AcceptDutchPrice(auction.ID, buyer)
+	_, lateErr := useCase.AcceptDutchPrice(auction.ID, uuid.New())
+
+	// Assert
+	assert.Error(t, bidErr)
+	assert.NoError(t, err)
+	assert.Error(t, lateErr)
+	closed := store.auctions[auction.ID]
+	assert.Equal(t, buyer, *closed.WinnerID)
+	assert.Equal(t, 4000, store.purchases[*closed.PurchaseID].Price)
+}
+
+func TestAuctionUseCase_ScheduledAuctionRejectsEarlyBids(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	_, useCase, auction := newAuctionFixture(now, func(a *domain.Auction) {
+		a.StartTime = now.Add(time.Hour)
+		a.EndTime = now.Add(2 * time.Hour)
+	})
+
+	// Act
+	_, err := useCase.PlaceBid(auction.ID, uuid.New(), 1100)
+
+	// Assert
+	assert.EqualError(t, err, "auction has not started yet")
+}
@@ -0,0 +1,465 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+)
+
+const (
+	chatAgentMaxSteps     = 5
+	chatAgentSearchLimit  = 5
+	chatToolCallListLimit = 50
+)
+
+// chatAgentTools is the whitelist of actions the chat agent may take
+var chatAgentTools = []infrastructure.ChatAgentTool{
+	{
+		Name:        "search_products",
+		Description: "出品中の商品を検索する",
+		Arguments:   `{"query": "自転車", "category": "sports", "condition": "good", "min_price": 0, "max_price": 20000, "sort": "price_asc"}`,
+	},
+	{
+		Name:        "add_favorite",
+		Description: "商品をお気に入りに追加する",
+		Arguments:   `{"product_id": "<商品ID>"}`,
+		Confirm:     true,
+	},
+	{
+		Name:        "create_offer",
+		Description: "商品の出品者に価格交渉（オファー）を送る",
+		Arguments:   `{"product_id": "<商品ID>", "price": 15000, "message": "よろしくお願いします"}`,
+		Confirm:     true,
+	},
+	{
+		Name:        "shipping_status",
+		Description: "購入した商品の配送状況を確認する",
+		Arguments:   `{"purchase_id": "<購入ID>"}`,
+	},
+}
+
+// chatToolArgs holds the arguments of every tool
+type chatToolArgs struct {
+	Query      string `json:"query"`
+	Category   string `json:"category"`
+	Condition  string `json:"condition"`
+	MinPrice   int    `json:"min_price"`
+	MaxPrice   int    `json:"max_price"`
+	Sort       string `json:"sort"`
+	ProductID  string `json:"product_id"`
+	PurchaseID string `json:"purchase_id"`
+	Price      int    `json:"price"`
+	Message    string `json:"message"`
+}
+
+// chatAgentStep is one answer of the model: a tool call or the final reply
+type chatAgentStep struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+	Reply     string          `json:"reply"`
+}
+
+// ChatAgentReply is the agent's answer with every tool it called on the way.
+// PendingAction is set when the agent proposes an action the user must
+// confirm before it runs.
+type ChatAgentReply struct {
+	Message       string                 `json:"message"`
+	ToolCalls     []*domain.ChatToolCall `json:"tool_calls"`
+	PendingAction *domain.ChatToolCall   `json:"pending_action,omitempty"`
+}
+
+// ChatAgentUseCase lets the chatbot act for a signed-in user through a fixed
+// set of tools. Every invocation is kept as a domain.ChatToolCall.
+type ChatAgentUseCase interface {
+	Run(ctx context.Context, userID uuid.UUID, messages []infrastructure.ChatMessage) (*ChatAgentReply, error)
+	ConfirmAction(userID, callID uuid.UUID) (*domain.ChatToolCall, error)
+	RejectAction(userID, callID uuid.UUID) (*domain.ChatToolCall, error)
+	GetToolCalls(userID uuid.UUID) ([]*domain.ChatToolCall, error)
+}
+
+type chatAgentUseCase struct {
+	aiClient     *infrastructure.AIClient
+	toolCallRepo domain.ChatToolCallRepository
+	productRepo  domain.ProductRepository
+	favorites    domain.SustainabilityRepository
+	offers       OfferUseCase
+	purchaseRepo domain.PurchaseRepository
+	shippingRepo domain.ShippingTrackingRepository
+	clock        Clock
+}
+
+func NewChatAgentUseCase(
+	aiClient *infrastructure.AIClient,
+	toolCallRepo domain.ChatToolCallRepository,
+	productRepo domain.ProductRepository,
+	favorites domain.SustainabilityRepository,
+	offers OfferUseCase,
+	purchaseRepo domain.PurchaseRepository,
+	shippingRepo domain.ShippingTrackingRepository,
+	clock Clock,
+) ChatAgentUseCase {
+	return &chatAgentUseCase{
+		aiClient:     aiClient,
+		toolCallRepo: toolCallRepo,
+		productRepo:  productRepo,
+		favorites:    favorites,
+		offers:       offers,
+		purchaseRepo: purchaseRepo,
+		shippingRepo: shippingRepo,
+		clock:        clock,
+	}
+}
+
+// Run lets the model call tools until it has an answer. Read-only tools run
+// straight away and their results are fed back to the model; a tool that
+// changes state ends the turn with a pending action instead.
+func (u *chatAgentUseCase) Run(ctx context.Context, userID uuid.UUID, messages []infrastructure.ChatMessage) (*ChatAgentReply, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("no messages to reply to")
+	}
+
+	system, err := infrastructure.RenderPrompt(infrastructure.PromptChatAgent, chatAgentTools)
+	if err != nil {
+		return nil, err
+	}
+
+	conversation := append([]infrastructure.ChatMessage{}, messages...)
+	reply := &ChatAgentReply{ToolCalls: []*domain.ChatToolCall{}}
+	for step := 0; step < chatAgentMaxSteps; step++ {
+		text, err := u.aiClient.Complete(ctx, &infrastructure.LLMRequest{System: system, Messages: conversation, JSON: true})
+		if err != nil {
+			return nil, err
+		}
+
+		next, ok := parseChatAgentStep(text)
+		if !ok {
+			// The model answered in plain text
+			reply.Message = strings.TrimSpace(text)
+			return reply, nil
+		}
+		if next.Tool == "" {
+			reply.Message = next.Reply
+			return reply, nil
+		}
+
+		call, err := u.invoke(userID, next)
+		if err != nil {
+			return nil, err
+		}
+		reply.ToolCalls = append(reply.ToolCalls, call)
+
+		if call.Status == domain.ChatToolCallPending {
+			reply.PendingAction = call
+			reply.Message = "次の操作を実行してよろしいですか？\n" + call.Summary
+			return reply, nil
+		}
+
+		conversation = append(conversation,
+			infrastructure.ChatMessage{Role: "assistant", Content: text},
+			infrastructure.ChatMessage{Role: "user", Content: "TOOL_RESULT " + call.Tool + ": " + toolOutcome(call)},
+		)
+	}
+
+	reply.Message = "申し訳ありません。リクエストを完了できませんでした。もう一度お試しください。"
+	return reply, nil
+}
+
+func (u *chatAgentUseCase) ConfirmAction(userID, callID uuid.UUID) (*domain.ChatToolCall, error) {
+	call, err := u.pendingCall(userID, callID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := u.clock()
+	if now.After(call.CreatedAt.Add(domain.ChatToolConfirmationWindow)) {
+		if _, err := u.toolCallRepo.Transition(call.ID, domain.ChatToolCallPending, domain.ChatToolCallExpired); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("action confirmation has expired")
+	}
+
+	// Claim the call first so a double confirmation cannot run it twice
+	claimed, err := u.toolCallRepo.Transition(call.ID, domain.ChatToolCallPending, domain.ChatToolCallExecuted)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, errors.New("action already handled")
+	}
+
+	var args chatToolArgs
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+		return nil, errors.New("invalid action arguments")
+	}
+
+	call.ConfirmedAt = &now
+	result, err := u.execute(userID, call.Tool, args)
+	recordToolOutcome(call, result, err)
+	if err := u.toolCallRepo.Update(call); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+func (u *chatAgentUseCase) RejectAction(userID, callID uuid.UUID) (*domain.ChatToolCall, error) {
+	call, err := u.pendingCall(userID, callID)
+	if err != nil {
+		return nil, err
+	}
+
+	rejected, err := u.toolCallRepo.Transition(call.ID, domain.ChatToolCallPending, domain.ChatToolCallRejected)
+	if err != nil {
+		return nil, err
+	}
+	if !rejected {
+		return nil, errors.New("action already handled")
+	}
+	call.Status = domain.ChatToolCallRejected
+	return call, nil
+}
+
+func (u *chatAgentUseCase) GetToolCalls(userID uuid.UUID) ([]*domain.ChatToolCall, error) {
+	return u.toolCallRepo.FindByUserID(userID, chatToolCallListLimit)
+}
+
+func (u *chatAgentUseCase) pendingCall(userID, callID uuid.UUID) (*domain.ChatToolCall, error) {
+	call, err := u.toolCallRepo.FindByID(callID)
+	if err != nil {
+		return nil, errors.New("action not found")
+	}
+	if call.UserID != userID {
+		return nil, errors.New("unauthorized: not your action")
+	}
+	if call.Status != domain.ChatToolCallPending {
+		return nil, errors.New("action already handled")
+	}
+	return call, nil
+}
+
+// invoke runs or stages one tool call and records it
+func (u *chatAgentUseCase) invoke(userID uuid.UUID, step chatAgentStep) (*domain.ChatToolCall, error) {
+	arguments := step.Arguments
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	call := &domain.ChatToolCall{
+		UserID:    userID,
+		Tool:      step.Tool,
+		Arguments: string(arguments),
+		CreatedAt: u.clock(),
+	}
+
+	var args chatToolArgs
+	tool, known := findChatAgentTool(step.Tool)
+	switch {
+	case !known:
+		recordToolOutcome(call, nil, errors.New("unknown tool"))
+	case json.Unmarshal(arguments, &args) != nil:
+		recordToolOutcome(call, nil, errors.New("invalid arguments"))
+	case tool.Confirm:
+		summary, err := u.describe(userID, step.Tool, args)
+		if err != nil {
+			recordToolOutcome(call, nil, err)
+		} else {
+			call.Status = domain.ChatToolCallPending
+			call.Summary = summary
+		}
+	default:
+		result, err := u.execute(userID, step.Tool, args)
+		recordToolOutcome(call, result, err)
+	}
+
+	if err := u.toolCallRepo.Create(call); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+// describe checks an action that needs confirmation and says what it will do
+func (u *chatAgentUseCase) describe(userID uuid.UUID, tool string, args chatToolArgs) (string, error) {
+	product, err := u.findProduct(args.ProductID)
+	if err != nil {
+		return "", err
+	}
+
+	switch tool {
+	case "add_favorite":
+		return fmt.Sprintf("「%s」をお気に入りに追加します。", product.Title), nil
+	case "create_offer":
+		if product.SellerID == userID {
+			return "", errors.New("cannot make offer on your own product")
+		}
+		if args.Price <= 0 {
+			return "", errors.New("offer price must be positive")
+		}
+		return fmt.Sprintf("「%s」（¥%d）に ¥%d で価格交渉を送ります。", product.Title, product.Price, args.Price), nil
+	}
+	return "", errors.New("unknown tool")
+}
+
+func (u *chatAgentUseCase) execute(userID uuid.UUID, tool string, args chatToolArgs) (interface{}, error) {
+	switch tool {
+	case "search_products":
+		return u.searchProducts(args)
+	case "add_favorite":
+		product, err := u.findProduct(args.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if err := u.favorites.AddFavorite(userID, product.ID); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"product_id": product.ID, "favorited": true}, nil
+	case "create_offer":
+		productID, err := uuid.Parse(args.ProductID)
+		if err != nil {
+			return nil, errors.New("invalid product ID")
+		}
+		offer, err := u.offers.CreateOffer(userID, productID, args.Price, args.Message)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"offer_id": offer.ID, "status": offer.Status, "offer_price": offer.OfferPrice}, nil
+	case "shipping_status":
+		return u.shippingStatus(userID, args.PurchaseID)
+	}
+	return nil, errors.New("unknown tool")
+}
+
+func (u *chatAgentUseCase) searchProducts(args chatToolArgs) (interface{}, error) {
+	query := chatQuery{
+		Search:   strings.TrimSpace(args.Query),
+		Category: args.Category,
+		MinPrice: args.MinPrice,
+		MaxPrice: args.MaxPrice,
+	}
+	if containsString(chatConditions, args.Condition) {
+		query.Condition = args.Condition
+	}
+	if containsString(chatSorts, args.Sort) {
+		query.Sort = args.Sort
+	}
+
+	products, err := searchProducts(u.productRepo, query, chatAgentSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	type productResult struct {
+		ID        uuid.UUID `json:"id"`
+		Title     string    `json:"title"`
+		Price     int       `json:"price"`
+		Category  string    `json:"category"`
+		Condition string    `json:"condition"`
+		CO2Impact float64   `json:"co2_impact_kg"`
+	}
+	results := make([]productResult, 0, len(products))
+	for _, p := range products {
+		results = append(results, productResult{
+			ID:        p.ID,
+			Title:     p.Title,
+			Price:     p.Price,
+			Category:  p.Category,
+			Condition: string(p.Condition),
+			CO2Impact: p.CO2ImpactKg,
+		})
+	}
+	return results, nil
+}
+
+func (u *chatAgentUseCase) shippingStatus(userID uuid.UUID, purchaseID string) (interface{}, error) {
+	id, err := uuid.Parse(purchaseID)
+	if err != nil {
+		return nil, errors.New("invalid purchase ID")
+	}
+	purchase, err := u.purchaseRepo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("purchase not found")
+	}
+	if purchase.BuyerID != userID && purchase.SellerID != userID {
+		return nil, errors.New("unauthorized: not a party to this purchase")
+	}
+
+	result := map[string]interface{}{
+		"purchase_id":     purchase.ID,
+		"purchase_status": purchase.Status,
+		"shipping_status": "not_shipped",
+	}
+	tracking, err := u.shippingRepo.GetByPurchaseID(purchase.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tracking != nil {
+		result["shipping_status"] = tracking.Status
+		result["carrier"] = tracking.Carrier
+		result["tracking_number"] = tracking.TrackingNumber
+		result["estimated_arrival"] = tracking.EstimatedArrival
+	}
+	return result, nil
+}
+
+func (u *chatAgentUseCase) findProduct(productID string) (*domain.Product, error) {
+	id, err := uuid.Parse(productID)
+	if err != nil {
+		return nil, errors.New("invalid product ID")
+	}
+	product, err := u.productRepo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+	return product, nil
+}
+
+func findChatAgentTool(name string) (infrastructure.ChatAgentTool, bool) {
+	for _, tool := range chatAgentTools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return infrastructure.ChatAgentTool{}, false
+}
+
+// parseChatAgentStep reads the JSON object the model answered with
+func parseChatAgentStep(text string) (chatAgentStep, bool) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start == -1 || end <= start {
+		return chatAgentStep{}, false
+	}
+
+	var step chatAgentStep
+	if err := json.Unmarshal([]byte(text[start:end+1]), &step); err != nil {
+		return chatAgentStep{}, false
+	}
+	if step.Tool == "" && step.Reply == "" {
+		return chatAgentStep{}, false
+	}
+	return step, true
+}
+
+func recordToolOutcome(call *domain.ChatToolCall, result interface{}, err error) {
+	if err != nil {
+		call.Status = domain.ChatToolCallFailed
+		call.Error = err.Error()
+		return
+	}
+	call.Status = domain.ChatToolCallExecuted
+	if data, err := json.Marshal(result); err == nil {
+		call.Result = string(data)
+	}
+}
+
+// toolOutcome is what the model is told about a call it made
+func toolOutcome(call *domain.ChatToolCall) string {
+	if call.Status == domain.ChatToolCallFailed {
+		data, _ := json.Marshal(map[string]string{"error": call.Error})
+		return string(data)
+	}
+	return call.Result
+}
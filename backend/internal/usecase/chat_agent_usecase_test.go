@@ -0,0 +1,190 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+	"github.com/yourusername/ecomate/backend/internal/usecase"
+)
+
+// memToolCallRepo keeps the audit trail in memory
+type memToolCallRepo struct {
+	calls []*domain.ChatToolCall
+}
+
+func (r *memToolCallRepo) Create(call *domain.ChatToolCall) error {
+	call.ID = uuid.New()
+	saved := *call
+	r.calls = append(r.calls, &saved)
+	return nil
+}
+
+func (r *memToolCallRepo) FindByID(id uuid.UUID) (*domain.ChatToolCall, error) {
+	for _, call := range r.calls {
+		if call.ID == id {
+			found := *call
+			return &found, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memToolCallRepo) FindByUserID(userID uuid.UUID, limit int) ([]*domain.ChatToolCall, error) {
+	var calls []*domain.ChatToolCall
+	for i := len(r.calls) - 1; i >= 0; i-- {
+		if r.calls[i].UserID == userID {
+			calls = append(calls, r.calls[i])
+		}
+	}
+	return calls, nil
+}
+
+func (r *memToolCallRepo) Update(call *domain.ChatToolCall) error {
+	for i, saved := range r.calls {
+		if saved.ID == call.ID {
+			updated := *call
+			r.calls[i] = &updated
+		}
+	}
+	return nil
+}
+
+func (r *memToolCallRepo) Transition(id uuid.UUID, from, to domain.ChatToolCallStatus) (bool, error) {
+	for _, call := range r.calls {
+		if call.ID == id && call.Status == from {
+			call.Status = to
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// recordingFavorites records favorites; nothing else is used by the agent
+type recordingFavorites struct {
+	domain.SustainabilityRepository
+	added []uuid.UUID
+}
+
+func (r *recordingFavorites) AddFavorite(userID, productID uuid.UUID) error {
+	r.added = append(r.added, productID)
+	return nil
+}
+
+// scriptedLLM answers each request with the next reply and keeps the requests
+func scriptedLLM(replies ...string) (*infrastructure.AIClient, *[]*infrastructure.LLMRequest) {
+	var requests []*infrastructure.LLMRequest
+	llm := infrastructure.NewFakeLLMProvider(func(req *infrastructure.LLMRequest) (string, error) {
+		requests = append(requests, req)
+		if len(requests) > len(replies) {
+			return "", errors.New("no more replies")
+		}
+		return replies[len(requests)-1], nil
+	})
+	return infrastructure.NewTextAIClient(llm), &requests
+}
+
+func TestChatAgentUseCase_Run_SearchesThenFavoritesAfterConfirmation(t *testing.T) {
+	// Arrange
+	userID := uuid.New()
+	bike := &domain.Product{ID: uuid.New(), SellerID: uuid.New(), Title: "City bike", Price: 18000, Category: "sports", Condition: domain.ConditionGood}
+	mockProductRepo := new(MockProductRepository)
+	mockProductRepo.On("List", &domain.ProductFilters{Search: "bike", MaxPrice: 20000, Sort: "price_asc", Limit: 5}).
+		Return([]*domain.Product{bike}, &domain.PaginationResponse{}, nil)
+	mockProductRepo.On("FindByID", bike.ID).Return(bike, nil)
+	aiClient, requests := scriptedLLM(
+		`{"tool": "search_products", "arguments": {"query": "bike", "max_price": 20000, "sort": "price_asc"}}`,
+		`{"tool": "add_favorite", "arguments": {"product_id": "`+bike.ID.String()+`"}}`,
+	)
+	toolCalls := &memToolCallRepo{}
+	favorites := &recordingFavorites{}
+	useCase := usecase.NewChatAgentUseCase(aiClient, toolCalls, mockProductRepo, favorites, nil, nil, nil, time.Now)
+
+	// Act
+	reply, err := useCase.Run(context.Background(), userID, []infrastructure.ChatMessage{
+		{Role: "user", Content: "Find me a used bike under 20000 yen and favorite the best one"},
+	})
+
+	// Assert: the search ran and its result went back to the model, but the
+	// favorite waits for the user
+	assert.NoError(t, err)
+	assert.Len(t, *requests, 2)
+	assert.Contains(t, (*requests)[0].System, "search_products")
+	assert.Contains(t, (*requests)[1].Messages[2].Content, "TOOL_RESULT search_products: ")
+	assert.Contains(t, (*requests)[1].Messages[2].Content, bike.ID.String())
+	assert.Len(t, reply.ToolCalls, 2)
+	assert.Equal(t, domain.ChatToolCallExecuted, reply.ToolCalls[0].Status)
+	assert.NotNil(t, reply.PendingAction)
+	assert.Equal(t, domain.ChatToolCallPending, reply.PendingAction.Status)
+	assert.Contains(t, reply.Message, "「City bike」をお気に入りに追加します。")
+	assert.Empty(t, favorites.added)
+
+	// Nobody else can confirm it
+	_, err = useCase.ConfirmAction(uuid.New(), reply.PendingAction.ID)
+	assert.EqualError(t, err, "unauthorized: not your action")
+
+	call, err := useCase.ConfirmAction(userID, reply.PendingAction.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ChatToolCallExecuted, call.Status)
+	assert.NotNil(t, call.ConfirmedAt)
+	assert.Equal(t, []uuid.UUID{bike.ID}, favorites.added)
+
+	// It runs only once
+	_, err = useCase.ConfirmAction(userID, reply.PendingAction.ID)
+	assert.EqualError(t, err, "action already handled")
+	assert.Len(t, favorites.added, 1)
+
+	audit, err := useCase.GetToolCalls(userID)
+	assert.NoError(t, err)
+	assert.Len(t, audit, 2)
+	assert.Equal(t, "add_favorite", audit[0].Tool)
+	assert.Equal(t, domain.ChatToolCallExecuted, audit[0].Status)
+}
+
+func TestChatAgentUseCase_Run_RecordsFailuresAndExpiresUnconfirmedOffers(t *testing.T) {
+	// Arrange
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	userID := uuid.New()
+	lamp := &domain.Product{ID: uuid.New(), SellerID: uuid.New(), Title: "Desk lamp", Price: 3000}
+	mockProductRepo := new(MockProductRepository)
+	mockProductRepo.On("FindByID", lamp.ID).Return(lamp, nil)
+	aiClient, requests := scriptedLLM(
+		`{"tool": "delete_account", "arguments": {}}`,
+		`{"tool": "create_offer", "arguments": {"product_id": "`+lamp.ID.String()+`", "price": 2500}}`,
+	)
+	toolCalls := &memToolCallRepo{}
+	mockOfferRepo := new(MockOfferRepository)
+	offers := usecase.NewOfferUseCase(nil, mockOfferRepo, mockProductRepo, nil, nil, clock)
+	useCase := usecase.NewChatAgentUseCase(aiClient, toolCalls, mockProductRepo, nil, offers, nil, nil, clock)
+
+	// Act
+	reply, err := useCase.Run(context.Background(), userID, []infrastructure.ChatMessage{
+		{Role: "user", Content: "Offer 2500 for the lamp"},
+	})
+
+	// Assert: the tool outside the whitelist failed and the model was told so
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ChatToolCallFailed, reply.ToolCalls[0].Status)
+	assert.Equal(t, "unknown tool", reply.ToolCalls[0].Error)
+	assert.Contains(t, (*requests)[1].Messages[2].Content, `"error":"unknown tool"`)
+	assert.Equal(t, "create_offer", reply.PendingAction.Tool)
+	assert.Contains(t, reply.PendingAction.Summary, "¥2500")
+
+	// Too late to confirm
+	now = now.Add(domain.ChatToolConfirmationWindow + time.Minute)
+	_, err = useCase.ConfirmAction(userID, reply.PendingAction.ID)
+	assert.EqualError(t, err, "action confirmation has expired")
+	mockOfferRepo.AssertNotCalled(t, "Create", mock.Anything)
+
+	audit, _ := useCase.GetToolCalls(userID)
+	assert.Equal(t, domain.ChatToolCallExpired, audit[0].Status)
+	_, err = useCase.RejectAction(userID, reply.PendingAction.ID)
+	assert.EqualError(t, err, "action already handled")
+}
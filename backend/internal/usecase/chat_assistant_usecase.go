@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+)
+
+const (
+	chatRetrievalLimit      = 6
+	chatReviewsPerProduct   = 3
+	chatDescriptionMaxRunes = 200
+)
+
+// ChatReply is the assistant's answer and the products it is based on
+type ChatReply struct {
+	Message   string
+	Citations []uuid.UUID
+}
+
+// ChatAssistantUseCase answers chatbot conversations grounded in the products
+// relevant to the question. Replies to signed-in users (a non-nil userID) are
+// saved to their chat history once complete.
+type ChatAssistantUseCase interface {
+	Reply(ctx context.Context, userID uuid.UUID, messages []infrastructure.ChatMessage, clientContext string) (*ChatReply, error)
+	// StreamReply passes the reply to onToken as it is generated. If ctx is
+	// cancelled or onToken fails, generation stops and nothing is saved.
+	StreamReply(ctx context.Context, userID uuid.UUID, messages []infrastructure.ChatMessage, clientContext string, onToken func(string) error) (*ChatReply, error)
+}
+
+type chatAssistantUseCase struct {
+	aiClient    *infrastructure.AIClient
+	productRepo domain.ProductRepository
+	reviewRepo  domain.ReviewRepository
+	history     *ChatHistoryUseCase
+}
+
+func NewChatAssistantUseCase(
+	aiClient *infrastructure.AIClient,
+	productRepo domain.ProductRepository,
+	reviewRepo domain.ReviewRepository,
+	history *ChatHistoryUseCase,
+) ChatAssistantUseCase {
+	return &chatAssistantUseCase{
+		aiClient:    aiClient,
+		productRepo: productRepo,
+		reviewRepo:  reviewRepo,
+		history:     history,
+	}
+}
+
+func (u *chatAssistantUseCase) Reply(ctx context.Context, userID uuid.UUID, messages []infrastructure.ChatMessage, clientContext string) (*ChatReply, error) {
+	return u.StreamReply(ctx, userID, messages, clientContext, nil)
+}
+
+func (u *chatAssistantUseCase) StreamReply(ctx context.Context, userID uuid.UUID, messages []infrastructure.ChatMessage, clientContext string, onToken func(string) error) (*ChatReply, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("no messages to reply to")
+	}
+	question := messages[len(messages)-1].Content
+
+	query := planChatQuery(u.aiClient, question)
+	products := u.retrieve(query)
+
+	chatReq := &infrastructure.ChatRequest{
+		Messages: messages,
+		Context:  u.productContext(query, products),
+	}
+
+	response, err := u.aiClient.ChatStream(ctx, chatReq, onToken)
+	if err != nil {
+		return nil, err
+	}
+	// The client may have gone away right after the last token
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	u.saveExchange(userID, question, response, clientContext)
+	return &ChatReply{Message: response, Citations: citedProductIDs(response, products)}, nil
+}
+
+// retrieve finds the products a question is about
+func (u *chatAssistantUseCase) retrieve(query chatQuery) []*domain.Product {
+	if !query.WantsProducts {
+		return nil
+	}
+
+	products, err := searchProducts(u.productRepo, query, chatRetrievalLimit)
+	if err != nil {
+		log.Printf("Failed to retrieve products for chat: %v", err)
+		return nil
+	}
+	return products
+}
+
+// saveExchange records a finished exchange. A failure here should not cost
+// the user the reply they already received.
+func (u *chatAssistantUseCase) saveExchange(userID uuid.UUID, message, response, clientContext string) {
+	if u.history == nil || userID == uuid.Nil {
+		return
+	}
+	if err := u.history.SaveHistory(userID, message, response, clientContext); err != nil {
+		log.Printf("Failed to save chat history for user %s: %v", userID, err)
+	}
+}
+
+// productContext describes the retrieved products, with their reviews and
+// CO2 savings, for the assistant to answer from
+func (u *chatAssistantUseCase) productContext(query chatQuery, products []*domain.Product) string {
+	if !query.WantsProducts {
+		return ""
+	}
+	if len(products) == 0 {
+		return "条件に合う商品は現在出品されていません。検索条件を変えることを提案してください。"
+	}
+
+	type ReviewInfo struct {
+		Rating  int    `json:"rating"`
+		Comment string `json:"comment,omitempty"`
+	}
+
+	type ProductInfo struct {
+		ID            string       `json:"id"`
+		Title         string       `json:"title"`
+		Description   string       `json:"description"`
+		Price         int          `json:"price"`
+		Category      string       `json:"category"`
+		Condition     string       `json:"condition"`
+		CO2Impact     float64      `json:"co2_impact_kg"`
+		ImageURL      string       `json:"image_url"`
+		AverageRating float64      `json:"average_rating,omitempty"`
+		Reviews       []ReviewInfo `json:"reviews,omitempty"`
+	}
+
+	totalCO2 := 0.0
+	productList := make([]ProductInfo, 0, len(products))
+	for _, p := range products {
+		info := ProductInfo{
+			ID:          p.ID.String(),
+			Title:       p.Title,
+			Description: truncateRunes(p.Description, chatDescriptionMaxRunes),
+			Price:       p.Price,
+			Category:    p.Category,
+			Condition:   string(p.Condition),
+			CO2Impact:   p.CO2ImpactKg,
+			ImageURL:    primaryImageURL(p),
+		}
+		totalCO2 += p.CO2ImpactKg
+
+		if u.reviewRepo != nil {
+			if reviews, err := u.reviewRepo.FindByProductID(p.ID); err == nil && len(reviews) > 0 {
+				sum := 0
+				for i, r := range reviews {
+					sum += r.Rating
+					if i < chatReviewsPerProduct {
+						info.Reviews = append(info.Reviews, ReviewInfo{Rating: r.Rating, Comment: r.Comment})
+					}
+				}
+				info.AverageRating = float64(sum) / float64(len(reviews))
+			}
+		}
+
+		productList = append(productList, info)
+	}
+
+	jsonData, err := json.Marshal(productList)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf(`
+質問に関連する商品リスト（JSON形式）:
+%s
+
+co2_impact_kg は新品の代わりにその商品を購入した場合のCO2削減量です。上記の商品をすべて中古で購入すると合計 %.1f kg のCO2削減になります。
+
+商品を推薦する時は、以下のフォーマットを使用してください：
+[PRODUCT:商品ID:商品名:商品画像URL]
+
+例: [PRODUCT:123e4567-e89b-12d3-a456-426614174000:ハイトップスニーカー:https://example.com/image.jpg]
+
+このフォーマットを使うと、フロントエンドで商品カードとして表示されます。
+複数の商品を推薦する場合は、改行して複数の[PRODUCT]タグを含めてください。
+`, string(jsonData), totalCO2)
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// primaryImageURL prefers the primary image and the CDN copy of an image
+func primaryImageURL(p *domain.Product) string {
+	if len(p.Images) == 0 {
+		return ""
+	}
+	img := p.Images[0]
+	for _, candidate := range p.Images {
+		if candidate.IsPrimary {
+			img = candidate
+			break
+		}
+	}
+	if img.CDNURL != "" {
+		return img.CDNURL
+	}
+	return img.ImageURL
+}
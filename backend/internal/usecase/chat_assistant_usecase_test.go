@@ -0,0 +1,179 @@
+package usecase_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+	"github.com/yourusername/ecomate/backend/internal/usecase"
+)
+
+// memChatHistoryRepo keeps saved exchanges in memory
+type memChatHistoryRepo struct {
+	saved []*domain.ChatHistory
+}
+
+func (r *memChatHistoryRepo) Create(history *domain.ChatHistory) error {
+	r.saved = append(r.saved, history)
+	return nil
+}
+
+func (r *memChatHistoryRepo) GetByUserID(userID uuid.UUID, limit int) ([]*domain.ChatHistory, error) {
+	return r.saved, nil
+}
+
+func (r *memChatHistoryRepo) Delete(id uuid.UUID) error {
+	return nil
+}
+
+// memReviewRepo serves fixed reviews per product
+type memReviewRepo struct {
+	reviews map[uuid.UUID][]*domain.Review
+}
+
+func (r *memReviewRepo) Create(review *domain.Review) error {
+	return nil
+}
+
+func (r *memReviewRepo) FindByProductID(productID uuid.UUID) ([]*domain.Review, error) {
+	return r.reviews[productID], nil
+}
+
+func (r *memReviewRepo) FindByID(id uuid.UUID) (*domain.Review, error) {
+	return nil, nil
+}
+
+func (r *memReviewRepo) GetAverageRating(productID uuid.UUID) (float64, error) {
+	return 0, nil
+}
+
+func newChatProductRepo(products ...*domain.Product) *MockProductRepository {
+	mockProductRepo := new(MockProductRepository)
+	mockProductRepo.On("List", mock.Anything).Return(products, &domain.PaginationResponse{}, nil)
+	return mockProductRepo
+}
+
+func TestChatAssistantUseCase_StreamReply_RelaysTokensAndSavesHistory(t *testing.T) {
+	tent := &domain.Product{ID: uuid.New(), Title: "Tent", Price: 10000, Category: "sports", Condition: domain.ConditionGood, CO2ImpactKg: 12.5}
+
+	// Arrange: an OpenAI-compatible server that plans the search, then streams
+	// the reply in three events
+	var chatPrompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"stream":true`) {
+			_, _ = io.WriteString(w, `{"choices": [{"message": {"role": "assistant", "content": "{\"wants_products\": true, \"search\": \"tent\", \"category\": \"sports\", \"max_price\": 20000, \"sort\": \"cheapest\"}"}}]}`)
+			return
+		}
+		chatPrompt = string(body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{"This ", "tent fits. ", "[PRODUCT:" + tent.ID.String() + ":Tent:]"} {
+			_, _ = io.WriteString(w, `data: {"choices":[{"delta":{"content":"`+delta+`"}}]}`+"\n\n")
+		}
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	llm := infrastructure.NewManagedLLMProvider(
+		infrastructure.NewOpenAIProvider("", server.URL, "local-model"), time.Second, 0, time.Millisecond)
+	mockProductRepo := newChatProductRepo(tent)
+	reviewRepo := &memReviewRepo{reviews: map[uuid.UUID][]*domain.Review{
+		tent.ID: {{ProductID: tent.ID, Rating: 5, Comment: "Kept us dry all weekend"}, {ProductID: tent.ID, Rating: 4}},
+	}}
+	historyRepo := &memChatHistoryRepo{}
+	useCase := usecase.NewChatAssistantUseCase(
+		infrastructure.NewTextAIClient(llm), mockProductRepo, reviewRepo, usecase.NewChatHistoryUseCase(historyRepo))
+	userID := uuid.New()
+	messages := []infrastructure.ChatMessage{{Role: "user", Content: "Any tents?"}}
+
+	// Act
+	var tokens []string
+	reply, err := useCase.StreamReply(context.Background(), userID, messages, "camping", func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"This ", "tent fits. ", "[PRODUCT:" + tent.ID.String() + ":Tent:]"}, tokens)
+	assert.Equal(t, "This tent fits. [PRODUCT:"+tent.ID.String()+":Tent:]", reply.Message)
+	assert.Equal(t, []uuid.UUID{tent.ID}, reply.Citations)
+	mockProductRepo.AssertCalled(t, "List", &domain.ProductFilters{Search: "tent", Category: "sports", MaxPrice: 20000, Limit: 6})
+	// Only the retrieved product is in the prompt, with its reviews and CO2 savings
+	assert.Contains(t, chatPrompt, tent.ID.String())
+	assert.Contains(t, chatPrompt, "Kept us dry all weekend")
+	assert.Contains(t, chatPrompt, `\"average_rating\":4.5`)
+	assert.Contains(t, chatPrompt, "12.5 kg")
+
+	assert.Len(t, historyRepo.saved, 1)
+	assert.Equal(t, userID, historyRepo.saved[0].UserID)
+	assert.Equal(t, "Any tents?", historyRepo.saved[0].Message)
+	assert.Equal(t, reply.Message, historyRepo.saved[0].Response)
+	assert.Equal(t, "camping", historyRepo.saved[0].Context)
+	assert.Equal(t, 2, llm.Usage().Calls)
+}
+
+func TestChatAssistantUseCase_Reply_RetrievesByKeywordsWithoutModel(t *testing.T) {
+	camera := &domain.Product{ID: uuid.New(), Title: "Film camera", Price: 2800, Category: "electronics", Condition: domain.ConditionGood}
+	mockProductRepo := new(MockProductRepository)
+	strict := &domain.ProductFilters{Search: "カメラ", Category: "electronics", MaxPrice: 3000, Limit: 6}
+	relaxed := &domain.ProductFilters{Category: "electronics", MaxPrice: 3000, Limit: 6}
+	mockProductRepo.On("List", strict).Return([]*domain.Product{}, &domain.PaginationResponse{}, nil)
+	mockProductRepo.On("List", relaxed).Return([]*domain.Product{camera}, &domain.PaginationResponse{}, nil)
+	useCase := usecase.NewChatAssistantUseCase(nil, mockProductRepo, nil, nil)
+
+	reply, err := useCase.Reply(context.Background(), uuid.Nil,
+		[]infrastructure.ChatMessage{{Role: "user", Content: "3,000円以下のカメラを探しています"}}, "")
+
+	// Full-text search found nothing, so the category and budget alone were used
+	assert.NoError(t, err)
+	assert.NotEmpty(t, reply.Message)
+	assert.Equal(t, []uuid.UUID{camera.ID}, reply.Citations)
+	mockProductRepo.AssertExpectations(t)
+}
+
+func TestChatAssistantUseCase_StreamReply_StopsWhenClientCancels(t *testing.T) {
+	llm := infrastructure.NewFakeLLMProvider(func(req *infrastructure.LLMRequest) (string, error) {
+		if req.JSON {
+			return `{"wants_products": false}`, nil
+		}
+		return "one two three four", nil
+	})
+	historyRepo := &memChatHistoryRepo{}
+	mockProductRepo := newChatProductRepo()
+	useCase := usecase.NewChatAssistantUseCase(
+		infrastructure.NewTextAIClient(llm), mockProductRepo, nil, usecase.NewChatHistoryUseCase(historyRepo))
+	messages := []infrastructure.ChatMessage{{Role: "user", Content: "Hello"}}
+
+	// The client disconnects after the first token
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var received strings.Builder
+	_, err := useCase.StreamReply(ctx, uuid.New(), messages, "", func(token string) error {
+		received.WriteString(token)
+		cancel()
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "one ", received.String())
+	assert.Empty(t, historyRepo.saved)
+
+	// Anonymous replies are answered but not saved
+	reply, err := useCase.Reply(context.Background(), uuid.Nil, messages, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "one two three four", reply.Message)
+	assert.Empty(t, reply.Citations)
+	assert.Empty(t, historyRepo.saved)
+	// A greeting needs no catalog search
+	mockProductRepo.AssertNotCalled(t, "List", mock.Anything)
+}
@@ -0,0 +1,244 @@
+package usecase
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+)
+
+// chatQuery is the catalog search a chat question turns into
+type chatQuery struct {
+	WantsProducts bool   `json:"wants_products"`
+	Search        string `json:"search"`
+	Category      string `json:"category"`
+	Condition     string `json:"condition"`
+	MinPrice      int    `json:"min_price"`
+	MaxPrice      int    `json:"max_price"`
+	Sort          string `json:"sort"`
+}
+
+func (q chatQuery) filters(limit int) *domain.ProductFilters {
+	return &domain.ProductFilters{
+		Category:  q.Category,
+		Condition: domain.ProductCondition(q.Condition),
+		MinPrice:  q.MinPrice,
+		MaxPrice:  q.MaxPrice,
+		Search:    q.Search,
+		Sort:      q.Sort,
+		Limit:     limit,
+	}
+}
+
+// chatCategory lists words that point at a category. Names are the category
+// itself; items are specific enough to also search for.
+type chatCategory struct {
+	category string
+	names    []string
+	items    []string
+}
+
+// Checked in order, so more specific categories come first
+var chatCategories = []chatCategory{
+	{"electronics", []string{"電化製品", "家電", "electronics"}, []string{"カメラ", "スマホ", "パソコン", "イヤホン", "ヘッドホン", "camera", "phone", "laptop", "headphones"}},
+	{"furniture", []string{"家具", "インテリア", "furniture"}, []string{"椅子", "チェア", "デスク", "テーブル", "ソファ", "chair", "desk", "table", "sofa"}},
+	{"sports", []string{"スポーツ", "アウトドア", "sports"}, []string{"テント", "自転車", "ボール", "tent", "bike", "bicycle"}},
+	{"toys", []string{"おもちゃ", "ホビー", "toys"}, []string{"ゲーム", "ぬいぐるみ", "game", "toy"}},
+	{"books", []string{"書籍", "雑誌", "books"}, []string{"漫画", "マンガ", "小説", "本", "book", "novel"}},
+	{"clothing", []string{"衣類", "服", "ファッション", "clothing", "fashion"}, []string{"スニーカー", "シューズ", "靴", "シャツ", "ジャケット", "sneakers", "shoes", "shirt", "jacket"}},
+}
+
+var (
+	chatShoppingWords = []string{"探", "欲しい", "ほしい", "おすすめ", "オススメ", "買いたい", "商品", "ありますか", "looking for", "recommend", "buy"}
+	chatMaxPrice      = []*regexp.Regexp{
+		regexp.MustCompile(`(\d[\d,]*)\s*(?:円|yen)?\s*(?:以下|まで|未満)`),
+		regexp.MustCompile(`(?i)(?:under|below|less than|up to)\s*[¥￥]?\s*(\d[\d,]*)`),
+	}
+	chatMinPrice = []*regexp.Regexp{
+		regexp.MustCompile(`(\d[\d,]*)\s*(?:円|yen)?\s*以上`),
+		regexp.MustCompile(`(?i)(?:over|above|more than)\s*[¥￥]?\s*(\d[\d,]*)`),
+	}
+	chatCitation = regexp.MustCompile(`\[PRODUCT:([0-9a-fA-F-]{36}):`)
+)
+
+var chatConditions = []string{
+	string(domain.ConditionNew),
+	string(domain.ConditionLikeNew),
+	string(domain.ConditionGood),
+	string(domain.ConditionFair),
+	string(domain.ConditionPoor),
+}
+
+var chatSorts = []string{"price_asc", "price_desc", "eco_impact_desc"}
+
+// planChatQuery asks the model to turn question into a catalog search and
+// falls back to keyword matching when it cannot
+func planChatQuery(aiClient *infrastructure.AIClient, question string) chatQuery {
+	categories := make([]string, len(chatCategories))
+	for i, c := range chatCategories {
+		categories[i] = c.category
+	}
+
+	prompt, err := infrastructure.RenderPrompt(infrastructure.PromptChatRetrieval, infrastructure.ChatRetrievalPromptData{
+		Question:   question,
+		Categories: categories,
+		Conditions: chatConditions,
+	})
+	if err == nil {
+		if text, err := aiClient.GenerateJSON(prompt); err == nil {
+			if q, ok := parseChatQueryJSON(text, categories); ok {
+				return q
+			}
+		}
+	}
+	return parseChatQuery(question)
+}
+
+// parseChatQueryJSON reads the model's plan, dropping values outside the
+// catalog's vocabulary
+func parseChatQueryJSON(text string, categories []string) (chatQuery, bool) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start == -1 || end <= start {
+		return chatQuery{}, false
+	}
+
+	var q chatQuery
+	if err := json.Unmarshal([]byte(text[start:end+1]), &q); err != nil {
+		return chatQuery{}, false
+	}
+
+	q.Search = strings.TrimSpace(q.Search)
+	if !containsString(categories, q.Category) {
+		q.Category = ""
+	}
+	if !containsString(chatConditions, q.Condition) {
+		q.Condition = ""
+	}
+	if !containsString(chatSorts, q.Sort) {
+		q.Sort = ""
+	}
+	if q.MinPrice < 0 {
+		q.MinPrice = 0
+	}
+	if q.MaxPrice < 0 || (q.MaxPrice > 0 && q.MaxPrice < q.MinPrice) {
+		q.MaxPrice = 0
+	}
+	return q, true
+}
+
+// parseChatQuery finds categories, items, prices and conditions by keyword
+func parseChatQuery(question string) chatQuery {
+	var q chatQuery
+	lower := strings.ToLower(question)
+
+	for _, c := range chatCategories {
+		if item := firstContained(lower, c.items); item != "" {
+			q.Category = c.category
+			q.Search = item
+			break
+		}
+		if firstContained(lower, c.names) != "" {
+			q.Category = c.category
+			break
+		}
+	}
+
+	q.MaxPrice = firstPrice(question, chatMaxPrice)
+	q.MinPrice = firstPrice(question, chatMinPrice)
+	if q.MaxPrice > 0 && q.MaxPrice < q.MinPrice {
+		q.MaxPrice = 0
+	}
+
+	switch {
+	case strings.Contains(question, "未使用") || strings.Contains(question, "新品同様"):
+		q.Condition = string(domain.ConditionLikeNew)
+	case strings.Contains(question, "新品") || strings.Contains(lower, "brand new"):
+		q.Condition = string(domain.ConditionNew)
+	}
+
+	switch {
+	case strings.Contains(question, "安い") || strings.Contains(lower, "cheap"):
+		q.Sort = "price_asc"
+	case strings.Contains(question, "エコ") || strings.Contains(lower, "co2"):
+		q.Sort = "eco_impact_desc"
+	}
+
+	q.WantsProducts = q.Category != "" || q.MinPrice > 0 || q.MaxPrice > 0 || q.Condition != "" ||
+		firstContained(lower, chatShoppingWords) != ""
+	return q
+}
+
+// searchProducts lists products matching query. Full-text matching is strict,
+// so when it finds nothing the structured filters are tried on their own.
+func searchProducts(productRepo domain.ProductRepository, query chatQuery, limit int) ([]*domain.Product, error) {
+	products, _, err := productRepo.List(query.filters(limit))
+	if err == nil && len(products) == 0 && query.Search != "" {
+		relaxed := query
+		relaxed.Search = ""
+		products, _, err = productRepo.List(relaxed.filters(limit))
+	}
+	return products, err
+}
+
+// citedProductIDs returns the retrieved products the reply links to, in the
+// order it mentions them. A reply that links none cites everything retrieved,
+// since only relevant products were shown to the model.
+func citedProductIDs(reply string, products []*domain.Product) []uuid.UUID {
+	retrieved := make(map[uuid.UUID]bool, len(products))
+	for _, p := range products {
+		retrieved[p.ID] = true
+	}
+
+	var cited []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, match := range chatCitation.FindAllStringSubmatch(reply, -1) {
+		id, err := uuid.Parse(match[1])
+		if err != nil || !retrieved[id] || seen[id] {
+			continue
+		}
+		seen[id] = true
+		cited = append(cited, id)
+	}
+	if len(cited) > 0 {
+		return cited
+	}
+
+	for _, p := range products {
+		cited = append(cited, p.ID)
+	}
+	return cited
+}
+
+func firstContained(text string, words []string) string {
+	for _, w := range words {
+		if strings.Contains(text, w) {
+			return w
+		}
+	}
+	return ""
+}
+
+func firstPrice(text string, patterns []*regexp.Regexp) int {
+	for _, re := range patterns {
+		if m := re.FindStringSubmatch(text); m != nil {
+			if price, err := strconv.Atoi(strings.ReplaceAll(m[1], ",", "")); err == nil {
+				return price
+			}
+		}
+	}
+	return 0
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,415 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+)
+
+type DisputeUseCase interface {
+	Open(userID uuid.UUID, req *domain.OpenDisputeRequest) (*domain.Dispute, error)
+	GetByID(id uuid.UUID, viewerID uuid.UUID, moderator bool) (*domain.Dispute, error)
+	ListByUser(userID uuid.UUID) ([]*domain.Dispute, error)
+	AddEvidence(id uuid.UUID, userID uuid.UUID, imageURL, caption string) (*domain.DisputeEvidence, error)
+	PostMessage(id uuid.UUID, senderID uuid.UUID, req *domain.DisputeMessageRequest, moderator bool) (*domain.DisputeMessage, error)
+	Queue(status domain.DisputeStatus, page, limit int) ([]*domain.Dispute, *domain.PaginationResponse, error)
+	Assign(id uuid.UUID, moderatorID uuid.UUID) (*domain.Dispute, error)
+	Resolve(id uuid.UUID, moderatorID uuid.UUID, req *domain.ResolveDisputeRequest) (*domain.Dispute, error)
+	EscalateOverdue(now time.Time) (int, error)
+}
+
+type disputeUseCase struct {
+	uow         domain.UnitOfWork
+	disputeRepo domain.DisputeRepository
+	payments    domain.PaymentProvider
+	notifier    NotificationUseCase
+}
+
+func NewDisputeUseCase(
+	uow domain.UnitOfWork,
+	disputeRepo domain.DisputeRepository,
+	payments domain.PaymentProvider,
+	notifier NotificationUseCase,
+) DisputeUseCase {
+	return &disputeUseCase{
+		uow:         uow,
+		disputeRepo: disputeRepo,
+		payments:    payments,
+		notifier:    notifier,
+	}
+}
+
+// Open freezes the escrow of a purchase until a moderator settles the dispute
+func (u *disputeUseCase) Open(userID uuid.UUID, req *domain.OpenDisputeRequest) (*domain.Dispute, error) {
+	var dispute *domain.Dispute
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		purchase, err := repos.Purchases.FindByIDForUpdate(req.PurchaseID)
+		if err != nil {
+			return errors.New("purchase not found")
+		}
+
+		if purchase.BuyerID != userID && purchase.SellerID != userID {
+			return errors.New("unauthorized: not a party to this purchase")
+		}
+
+		if !purchase.Status.CanTransitionTo(domain.PurchaseStatusDisputed) {
+			return fmt.Errorf("a %s purchase cannot be disputed", purchase.Status)
+		}
+
+		now := time.Now()
+		dispute = &domain.Dispute{
+			PurchaseID:      purchase.ID,
+			BuyerID:         purchase.BuyerID,
+			SellerID:        purchase.SellerID,
+			OpenedBy:        userID,
+			Reason:          req.Reason,
+			Description:     req.Description,
+			Status:          domain.DisputeStatusOpen,
+			PurchaseStatus:  purchase.Status,
+			ResponseDueAt:   now.Add(domain.DisputeResponseSLA),
+			ResolutionDueAt: now.Add(domain.DisputeResolutionSLA),
+			CreatedAt:       now,
+		}
+
+		if err := transitionPurchase(purchase, domain.PurchaseStatusDisputed); err != nil {
+			return err
+		}
+		if err := repos.Purchases.Update(purchase); err != nil {
+			return err
+		}
+		return repos.Disputes.Create(dispute)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.notify(counterparty(dispute, userID), "A dispute was opened",
+		fmt.Sprintf("Please respond by %s", dispute.ResponseDueAt.Format(time.RFC3339)), dispute)
+
+	return u.disputeRepo.FindByID(dispute.ID)
+}
+
+func (u *disputeUseCase) GetByID(id uuid.UUID, viewerID uuid.UUID, moderator bool) (*domain.Dispute, error) {
+	dispute, err := u.disputeRepo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("dispute not found")
+	}
+
+	if !moderator && !dispute.IsParty(viewerID) {
+		return nil, errors.New("unauthorized: not a party to this dispute")
+	}
+
+	return dispute, nil
+}
+
+func (u *disputeUseCase) ListByUser(userID uuid.UUID) ([]*domain.Dispute, error) {
+	return u.disputeRepo.FindByUser(userID)
+}
+
+func (u *disputeUseCase) AddEvidence(id uuid.UUID, userID uuid.UUID, imageURL, caption string) (*domain.DisputeEvidence, error) {
+	var evidence *domain.DisputeEvidence
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		dispute, err := u.lockForParty(repos, id, userID)
+		if err != nil {
+			return err
+		}
+
+		count, err := repos.Disputes.CountEvidence(dispute.ID)
+		if err != nil {
+			return err
+		}
+		if count >= domain.MaxDisputeEvidence {
+			return fmt.Errorf("a dispute can have at most %d evidence images", domain.MaxDisputeEvidence)
+		}
+
+		now := time.Now()
+		evidence = &domain.DisputeEvidence{
+			DisputeID:  dispute.ID,
+			UploaderID: userID,
+			ImageURL:   imageURL,
+			Caption:    caption,
+			CreatedAt:  now,
+		}
+		if err := repos.Disputes.AddEvidence(evidence); err != nil {
+			return err
+		}
+		return markResponded(repos, dispute, userID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return evidence, nil
+}
+
+func (u *disputeUseCase) PostMessage(id uuid.UUID, senderID uuid.UUID, req *domain.DisputeMessageRequest, moderator bool) (*domain.DisputeMessage, error) {
+	var message *domain.DisputeMessage
+	var dispute *domain.Dispute
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		var err error
+		if moderator {
+			dispute, err = repos.Disputes.FindByIDForUpdate(id)
+			if err != nil {
+				return errors.New("dispute not found")
+			}
+			if dispute.Status == domain.DisputeStatusResolved {
+				return errors.New("dispute is already resolved")
+			}
+		} else {
+			dispute, err = u.lockForParty(repos, id, senderID)
+			if err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		message = &domain.DisputeMessage{
+			DisputeID:   dispute.ID,
+			SenderID:    senderID,
+			Content:     req.Content,
+			IsModerator: moderator,
+			CreatedAt:   now,
+		}
+		if err := repos.Disputes.AddMessage(message); err != nil {
+			return err
+		}
+
+		if moderator {
+			return nil
+		}
+		return markResponded(repos, dispute, senderID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if moderator {
+		u.notify(dispute.BuyerID, "New moderator message on your dispute", req.Content, dispute)
+		u.notify(dispute.SellerID, "New moderator message on your dispute", req.Content, dispute)
+	} else {
+		u.notify(counterparty(dispute, senderID), "New message on your dispute", req.Content, dispute)
+	}
+
+	return message, nil
+}
+
+// Queue lists disputes for moderators, most urgent first, flagging those past
+// their resolution SLA
+func (u *disputeUseCase) Queue(status domain.DisputeStatus, page, limit int) ([]*domain.Dispute, *domain.PaginationResponse, error) {
+	disputes, pagination, err := u.disputeRepo.FindQueue(status, page, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	for _, dispute := range disputes {
+		dispute.Overdue = dispute.IsOverdue(now)
+	}
+	return disputes, pagination, nil
+}
+
+// Assign lets a moderator take ownership of a dispute
+func (u *disputeUseCase) Assign(id uuid.UUID, moderatorID uuid.UUID) (*domain.Dispute, error) {
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		dispute, err := repos.Disputes.FindByIDForUpdate(id)
+		if err != nil {
+			return errors.New("dispute not found")
+		}
+
+		if dispute.Status == domain.DisputeStatusResolved {
+			return errors.New("dispute is already resolved")
+		}
+
+		dispute.ModeratorID = &moderatorID
+		dispute.Status = domain.DisputeStatusUnderReview
+		return repos.Disputes.Update(dispute)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return u.disputeRepo.FindByID(id)
+}
+
+// Resolve settles the disputed escrow: the buyer is refunded or the seller
+// paid. A refund or payout that failed after the resolution was committed is
+// retried by resolving the dispute again with the same outcome.
+func (u *disputeUseCase) Resolve(id uuid.UUID, moderatorID uuid.UUID, req *domain.ResolveDisputeRequest) (*domain.Dispute, error) {
+	var dispute *domain.Dispute
+	var purchase *domain.Purchase
+	retry := false
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		var err error
+		dispute, err = repos.Disputes.FindByIDForUpdate(id)
+		if err != nil {
+			return errors.New("dispute not found")
+		}
+
+		// A dispute a moderator has taken on is theirs to settle
+		if dispute.ModeratorID != nil && *dispute.ModeratorID != moderatorID {
+			return errors.New("unauthorized: dispute is assigned to another moderator")
+		}
+
+		purchase, err = repos.Purchases.FindByIDForUpdate(dispute.PurchaseID)
+		if err != nil {
+			return errors.New("purchase not found")
+		}
+
+		if dispute.Status == domain.DisputeStatusResolved {
+			settling := (dispute.Outcome == domain.DisputeOutcomeReleaseSeller && purchase.Status == domain.PurchaseStatusReleasing) ||
+				(dispute.Outcome == domain.DisputeOutcomeRefundBuyer && purchase.Status == domain.PurchaseStatusRefunding)
+			if req.Outcome == dispute.Outcome && settling {
+				retry = true
+				return nil
+			}
+			return errors.New("dispute is already resolved")
+		}
+
+		now := time.Now()
+		dispute.ModeratorID = &moderatorID
+		dispute.Status = domain.DisputeStatusResolved
+		dispute.Outcome = req.Outcome
+		dispute.ResolutionNote = req.Note
+		dispute.ResolvedAt = &now
+		if err := repos.Disputes.Update(dispute); err != nil {
+			return err
+		}
+
+		if err := repos.Disputes.AddMessage(&domain.DisputeMessage{
+			DisputeID:   dispute.ID,
+			SenderID:    moderatorID,
+			Content:     req.Note,
+			IsModerator: true,
+			CreatedAt:   now,
+		}); err != nil {
+			return err
+		}
+
+		// A refund or payout is only recorded as intended here and made once
+		// committed, so no provider call runs while the rows are locked
+		switch req.Outcome {
+		case domain.DisputeOutcomeRefundBuyer:
+			return beginRefund(repos, purchase)
+		case domain.DisputeOutcomeReleaseSeller:
+			return beginRelease(repos, purchase)
+		default:
+			return fmt.Errorf("unknown dispute outcome: %s", req.Outcome)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Outcome {
+	case domain.DisputeOutcomeRefundBuyer:
+		err = refundEscrow(u.uow, u.payments, purchase)
+	case domain.DisputeOutcomeReleaseSeller:
+		err = releaseFunds(u.uow, u.payments, purchase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if retry {
+		return u.disputeRepo.FindByID(id)
+	}
+
+	title := "Dispute resolved: buyer refunded"
+	if req.Outcome == domain.DisputeOutcomeReleaseSeller {
+		title = "Dispute resolved: payment released to seller"
+	}
+	u.notify(dispute.BuyerID, title, req.Note, dispute)
+	u.notify(dispute.SellerID, title, req.Note, dispute)
+
+	return u.disputeRepo.FindByID(id)
+}
+
+// EscalateOverdue hands disputes whose response deadline has passed to the
+// moderator queue. It is run periodically and returns how many were escalated.
+func (u *disputeUseCase) EscalateOverdue(now time.Time) (int, error) {
+	overdue, err := u.disputeRepo.FindAwaitingResponse(now)
+	if err != nil {
+		return 0, err
+	}
+
+	escalated := 0
+	for _, candidate := range overdue {
+		var dispute *domain.Dispute
+		err := u.uow.Do(func(repos *domain.Repositories) error {
+			var err error
+			dispute, err = repos.Disputes.FindByIDForUpdate(candidate.ID)
+			if err != nil {
+				return err
+			}
+
+			// Someone may have responded since the candidates were listed
+			if dispute.Status != domain.DisputeStatusOpen {
+				dispute = nil
+				return nil
+			}
+
+			dispute.Status = domain.DisputeStatusUnderReview
+			dispute.EscalatedAt = &now
+			return repos.Disputes.Update(dispute)
+		})
+		if err != nil {
+			return escalated, err
+		}
+		if dispute == nil {
+			continue
+		}
+
+		escalated++
+		u.notify(dispute.OpenedBy, "Your dispute was escalated",
+			"The other party did not respond in time, so a moderator will review your dispute", dispute)
+	}
+
+	return escalated, nil
+}
+
+// lockForParty loads an unresolved dispute the user takes part in
+func (u *disputeUseCase) lockForParty(repos *domain.Repositories, id uuid.UUID, userID uuid.UUID) (*domain.Dispute, error) {
+	dispute, err := repos.Disputes.FindByIDForUpdate(id)
+	if err != nil {
+		return nil, errors.New("dispute not found")
+	}
+
+	if !dispute.IsParty(userID) {
+		return nil, errors.New("unauthorized: not a party to this dispute")
+	}
+
+	if dispute.Status == domain.DisputeStatusResolved {
+		return nil, errors.New("dispute is already resolved")
+	}
+
+	return dispute, nil
+}
+
+// markResponded stops the response timer once the other party has answered
+func markResponded(repos *domain.Repositories, dispute *domain.Dispute, userID uuid.UUID, now time.Time) error {
+	if dispute.Status != domain.DisputeStatusOpen || userID == dispute.OpenedBy {
+		return nil
+	}
+
+	dispute.Status = domain.DisputeStatusUnderReview
+	dispute.RespondedAt = &now
+	return repos.Disputes.Update(dispute)
+}
+
+func counterparty(dispute *domain.Dispute, userID uuid.UUID) uuid.UUID {
+	if userID == dispute.BuyerID {
+		return dispute.SellerID
+	}
+	return dispute.BuyerID
+}
+
+func (u *disputeUseCase) notify(userID uuid.UUID, title, message string, dispute *domain.Dispute) {
+	if u.notifier == nil {
+		return
+	}
+
+	link := fmt.Sprintf("/disputes/%s", dispute.ID)
+	_ = u.notifier.Create(userID, domain.NotificationTypeDispute, title, message, link)
+}
@@ -0,0 +1,254 @@
+package usecase_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/usecase"
+)
+
+// memDisputeRepo is only used inside a unit of work, so the store is already locked
+type memDisputeRepo struct {
+	domain.DisputeRepository
+	store *memStore
+}
+
+func (r *memDisputeRepo) Create(dispute *domain.Dispute) error {
+	if dispute.ID == uuid.Nil {
+		dispute.ID = uuid.New()
+	}
+	r.store.disputes[dispute.ID] = *dispute
+	return nil
+}
+
+func (r *memDisputeRepo) FindByIDForUpdate(id uuid.UUID) (*domain.Dispute, error) {
+	d, ok := r.store.disputes[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &d, nil
+}
+
+func (r *memDisputeRepo) Update(dispute *domain.Dispute) error {
+	r.store.disputes[dispute.ID] = *dispute
+	return nil
+}
+
+func (r *memDisputeRepo) AddMessage(message *domain.DisputeMessage) error {
+	r.store.messages = append(r.store.messages, *message)
+	return nil
+}
+
+// readDisputeRepo serves reads made outside a unit of work
+type readDisputeRepo struct {
+	domain.DisputeRepository
+	store *memStore
+}
+
+func (r *readDisputeRepo) FindByID(id uuid.UUID) (*domain.Dispute, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	d, ok := r.store.disputes[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &d, nil
+}
+
+func (r *readDisputeRepo) FindAwaitingResponse(before time.Time) ([]*domain.Dispute, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var disputes []*domain.Dispute
+	for _, d := range r.store.disputes {
+		if d.Status == domain.DisputeStatusOpen && d.ResponseDueAt.Before(before) {
+			d := d
+			disputes = append(disputes, &d)
+		}
+	}
+	return disputes, nil
+}
+
+func (r *readDisputeRepo) FindQueue(status domain.DisputeStatus, page, limit int) ([]*domain.Dispute, *domain.PaginationResponse, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var disputes []*domain.Dispute
+	for _, d := range r.store.disputes {
+		if d.Status != domain.DisputeStatusResolved {
+			d := d
+			disputes = append(disputes, &d)
+		}
+	}
+	sort.Slice(disputes, func(i, j int) bool { return disputes[i].ResolutionDueAt.Before(disputes[j].ResolutionDueAt) })
+	return disputes, &domain.PaginationResponse{Page: page, Limit: limit, Total: len(disputes)}, nil
+}
+
+func TestDisputeUseCase_ResolveRefundsBuyer(t *testing.T) {
+	// Arrange
+	store, payments, purchaseUseCase, purchase := newPurchaseFixture(t)
+	_, err := purchaseUseCase.Pay(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+	_, err = purchaseUseCase.MarkShipped(purchase.ID, purchase.SellerID)
+	assert.NoError(t, err)
+
+	disputeUseCase := usecase.NewDisputeUseCase(&memUnitOfWork{store: store}, &readDisputeRepo{store: store}, payments, nil)
+	moderatorID := uuid.New()
+
+	// Act
+	dispute, err := disputeUseCase.Open(purchase.BuyerID, &domain.OpenDisputeRequest{
+		PurchaseID:  purchase.ID,
+		Reason:      domain.DisputeReasonNotReceived,
+		Description: "Tracking shows nothing for a week",
+	})
+	assert.NoError(t, err)
+	blockedErr := purchaseUseCase.CompletePurchase(purchase.ID, purchase.BuyerID)
+
+	_, err = disputeUseCase.PostMessage(dispute.ID, purchase.SellerID, &domain.DisputeMessageRequest{Content: "I shipped it"}, false)
+	assert.NoError(t, err)
+	responded := store.disputes[dispute.ID]
+
+	_, outsiderErr := disputeUseCase.PostMessage(dispute.ID, uuid.New(), &domain.DisputeMessageRequest{Content: "hi"}, false)
+
+	resolved, err := disputeUseCase.Resolve(dispute.ID, moderatorID, &domain.ResolveDisputeRequest{
+		Outcome: domain.DisputeOutcomeRefundBuyer,
+		Note:    "No proof of shipment",
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Error(t, blockedErr)
+	assert.Equal(t, domain.DisputeStatusUnderReview, responded.Status)
+	assert.NotNil(t, responded.RespondedAt)
+	assert.Error(t, outsiderErr)
+	assert.Equal(t, domain.DisputeStatusResolved, resolved.Status)
+	assert.Equal(t, moderatorID, *resolved.ModeratorID)
+
+	final := store.purchases[purchase.ID]
+	assert.Equal(t, domain.PurchaseStatusRefunded, final.Status)
+	assert.Equal(t, "refunded", payments.State(final.PaymentRef))
+	assert.Len(t, store.messages, 2)
+	assert.True(t, store.messages[1].IsModerator)
+
+	_, err = disputeUseCase.Resolve(dispute.ID, moderatorID, &domain.ResolveDisputeRequest{
+		Outcome: domain.DisputeOutcomeReleaseSeller,
+		Note:    "changed my mind",
+	})
+	assert.Error(t, err)
+}
+
+func TestDisputeUseCase_FailedRefundIsRetried(t *testing.T) {
+	// Arrange
+	store, local, purchaseUseCase, purchase := newPurchaseFixture(t)
+	_, err := purchaseUseCase.Pay(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+	payments := &flakyPayments{LocalPaymentProvider: local, failRefunds: 1}
+	disputeUseCase := usecase.NewDisputeUseCase(&memUnitOfWork{store: store}, &readDisputeRepo{store: store}, payments, nil)
+	dispute, err := disputeUseCase.Open(purchase.BuyerID, &domain.OpenDisputeRequest{
+		PurchaseID:  purchase.ID,
+		Reason:      domain.DisputeReasonNotReceived,
+		Description: "Never shipped",
+	})
+	assert.NoError(t, err)
+	moderatorID := uuid.New()
+	req := &domain.ResolveDisputeRequest{Outcome: domain.DisputeOutcomeRefundBuyer, Note: "No shipment"}
+
+	// Act
+	_, failedErr := disputeUseCase.Resolve(dispute.ID, moderatorID, req)
+	afterFailure := store.purchases[purchase.ID].Status
+	_, otherOutcomeErr := disputeUseCase.Resolve(dispute.ID, moderatorID, &domain.ResolveDisputeRequest{Outcome: domain.DisputeOutcomeReleaseSeller})
+	_, err = disputeUseCase.Resolve(dispute.ID, moderatorID, req)
+
+	// Assert
+	assert.Error(t, failedErr)
+	assert.Equal(t, domain.PurchaseStatusRefunding, afterFailure)
+	assert.EqualError(t, otherOutcomeErr, "dispute is already resolved")
+	assert.NoError(t, err)
+	final := store.purchases[purchase.ID]
+	assert.Equal(t, domain.PurchaseStatusRefunded, final.Status)
+	assert.Equal(t, "refunded", local.State(final.PaymentRef))
+}
+
+func TestDisputeUseCase_EscalateOverdue(t *testing.T) {
+	// Arrange
+	store, payments, purchaseUseCase, purchase := newPurchaseFixture(t)
+	_, err := purchaseUseCase.Pay(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+
+	disputeUseCase := usecase.NewDisputeUseCase(&memUnitOfWork{store: store}, &readDisputeRepo{store: store}, payments, nil)
+	dispute, err := disputeUseCase.Open(purchase.SellerID, &domain.OpenDisputeRequest{
+		PurchaseID:  purchase.ID,
+		Reason:      domain.DisputeReasonPaymentProblem,
+		Description: "Buyer asked to pay outside the platform",
+	})
+	assert.NoError(t, err)
+
+	// Act
+	early, err := disputeUseCase.EscalateOverdue(time.Now())
+	assert.NoError(t, err)
+	late, err := disputeUseCase.EscalateOverdue(time.Now().Add(domain.DisputeResponseSLA + time.Hour))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 0, early)
+	assert.Equal(t, 1, late)
+	escalated := store.disputes[dispute.ID]
+	assert.Equal(t, domain.DisputeStatusUnderReview, escalated.Status)
+	assert.NotNil(t, escalated.EscalatedAt)
+}
+
+func TestDisputeUseCase_OnlyAssignedModeratorResolves(t *testing.T) {
+	// Arrange: a moderator takes on a dispute
+	store, payments, purchaseUseCase, purchase := newPurchaseFixture(t)
+	_, err := purchaseUseCase.Pay(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+
+	disputeUseCase := usecase.NewDisputeUseCase(&memUnitOfWork{store: store}, &readDisputeRepo{store: store}, payments, nil)
+	dispute, err := disputeUseCase.Open(purchase.SellerID, &domain.OpenDisputeRequest{
+		PurchaseID: purchase.ID,
+		Reason:     domain.DisputeReasonOther,
+	})
+	assert.NoError(t, err)
+	assignedID := uuid.New()
+	_, err = disputeUseCase.Assign(dispute.ID, assignedID)
+	assert.NoError(t, err)
+
+	// Act
+	_, otherErr := disputeUseCase.Resolve(dispute.ID, uuid.New(), &domain.ResolveDisputeRequest{Outcome: domain.DisputeOutcomeReleaseSeller})
+	resolved, err := disputeUseCase.Resolve(dispute.ID, assignedID, &domain.ResolveDisputeRequest{Outcome: domain.DisputeOutcomeReleaseSeller})
+
+	// Assert
+	assert.EqualError(t, otherErr, "unauthorized: dispute is assigned to another moderator")
+	assert.NoError(t, err)
+	assert.Equal(t, assignedID, *resolved.ModeratorID)
+	final := store.purchases[purchase.ID]
+	assert.Equal(t, domain.PurchaseStatusFundsReleased, final.Status)
+	assert.Equal(t, "released", payments.State(final.PaymentRef))
+}
+
+func TestDisputeUseCase_QueueFlagsOverdueDisputes(t *testing.T) {
+	// Arrange: one dispute is past its resolution SLA
+	store := newMemStore()
+	now := time.Now()
+	overdue := domain.Dispute{ID: uuid.New(), Status: domain.DisputeStatusUnderReview, ResolutionDueAt: now.Add(-time.Hour)}
+	onTime := domain.Dispute{ID: uuid.New(), Status: domain.DisputeStatusOpen, ResolutionDueAt: now.Add(domain.DisputeResolutionSLA)}
+	store.disputes[overdue.ID] = overdue
+	store.disputes[onTime.ID] = onTime
+	disputeUseCase := usecase.NewDisputeUseCase(&memUnitOfWork{store: store}, &readDisputeRepo{store: store}, nil, nil)
+
+	// Act
+	queue, _, err := disputeUseCase.Queue("", 1, 20)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, queue, 2)
+	assert.Equal(t, overdue.ID, queue[0].ID)
+	assert.True(t, queue[0].Overdue)
+	assert.False(t, queue[1].Overdue)
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yourusername/ecomate/backend/internal/domain"
+)
+
+// LeasedJob runs periodic maintenance on one replica at a time. Every replica
+// runs the job, but only the holder of its lease in the database does the work.
+type LeasedJob struct {
+	leases   domain.LeaseRepository
+	name     string
+	holder   string
+	interval time.Duration
+	now      Clock
+	work     func(now time.Time)
+}
+
+// NewLeasedJob creates a job that calls work every interval while holder
+// (unique per replica) holds the lease called name
+func NewLeasedJob(
+	leases domain.LeaseRepository,
+	name string,
+	holder string,
+	interval time.Duration,
+	clock Clock,
+	work func(now time.Time),
+) *LeasedJob {
+	return &LeasedJob{
+		leases:   leases,
+		name:     name,
+		holder:   holder,
+		interval: interval,
+		now:      clock,
+		work:     work,
+	}
+}
+
+// Run does the work every interval until ctx is cancelled, then gives up the lease
+func (j *LeasedJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = j.leases.Release(j.name, j.holder)
+			return
+		case <-ticker.C:
+			if _, err := j.RunOnce(); err != nil {
+				log.Printf("Warning: Failed to acquire the %s lease: %v", j.name, err)
+			}
+		}
+	}
+}
+
+// RunOnce does the work if this replica holds the lease and reports whether it did
+func (j *LeasedJob) RunOnce() (bool, error) {
+	now := j.now()
+
+	// The lease outlives a tick so it is still ours on the next one, but
+	// lapses quickly if this replica dies
+	acquired, err := j.leases.TryAcquire(j.name, j.holder, 2*j.interval, now)
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	j.work(now)
+	return true, nil
+}
@@ -0,0 +1,72 @@
+package usecase_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/usecase"
+)
+
+func TestLeasedJob_RunsOnOneReplicaAndStops(t *testing.T) {
+	// Arrange: two replicas run the same job
+	start := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	clock := func() time.Time { return now }
+	leases := &memLeaseRepo{leases: make(map[string]domain.Lease)}
+
+	var runsA, runsB []time.Time
+	jobA := usecase.NewLeasedJob(leases, "offer_maintenance", "replica-a", time.Minute, clock,
+		func(at time.Time) { runsA = append(runsA, at) })
+	jobB := usecase.NewLeasedJob(leases, "offer_maintenance", "replica-b", time.Minute, clock,
+		func(at time.Time) { runsB = append(runsB, at) })
+
+	// Act
+	ranA, err := jobA.RunOnce()
+	assert.NoError(t, err)
+	ranB, err := jobB.RunOnce()
+	assert.NoError(t, err)
+
+	// Replica A dies; its lease lapses after two intervals
+	now = start.Add(2*time.Minute + time.Second)
+	takenOver, err := jobB.RunOnce()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, ranA)
+	assert.False(t, ranB)
+	assert.True(t, takenOver)
+	assert.Equal(t, []time.Time{start}, runsA)
+	assert.Equal(t, []time.Time{now}, runsB)
+}
+
+func TestLeasedJob_RunReleasesLeaseWhenCancelled(t *testing.T) {
+	// Arrange
+	leases := &memLeaseRepo{leases: make(map[string]domain.Lease)}
+	var runs int32
+	job := usecase.NewLeasedJob(leases, "dispute_escalation", "replica-a", 5*time.Millisecond, time.Now,
+		func(time.Time) { atomic.AddInt32(&runs, 1) })
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	// Act
+	go func() {
+		job.Run(ctx)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&runs) > 0 }, time.Second, time.Millisecond)
+	cancel()
+
+	// Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job did not stop")
+	}
+	leases.mu.Lock()
+	defer leases.mu.Unlock()
+	assert.NotContains(t, leases.leases, "dispute_escalation")
+}
@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+)
+
+const (
+	MaxAttachmentSize        = 10 << 20
+	MaxAttachmentsPerMessage = 5
+	attachmentThumbnailSize  = 320
+)
+
+// attachmentTypes maps the content types that may be attached, as sniffed
+// from the data, to the extension they are stored with
+var attachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+func (u *messageUseCase) SendAttachments(conversationID, senderID uuid.UUID, content string, uploads []domain.AttachmentUpload) (*domain.Message, error) {
+	if u.storage == nil {
+		return nil, errors.New("attachments are not available")
+	}
+
+	conversation, err := u.GetConversation(conversationID, senderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(uploads) == 0 {
+		return nil, errors.New("no attachments")
+	}
+	if len(uploads) > MaxAttachmentsPerMessage {
+		return nil, errors.New("too many attachments")
+	}
+
+	contentTypes := make([]string, len(uploads))
+	for i, upload := range uploads {
+		if len(upload.Data) > MaxAttachmentSize {
+			return nil, errors.New("attachment is too large")
+		}
+		contentType := http.DetectContentType(upload.Data)
+		if _, ok := attachmentTypes[contentType]; !ok {
+			return nil, errors.New("only images and PDF files can be attached")
+		}
+		contentTypes[i] = contentType
+	}
+
+	ctx := context.Background()
+	var stored []string
+	attachments := make([]domain.MessageAttachment, 0, len(uploads))
+	for i, upload := range uploads {
+		attachment, keys, err := u.storeAttachment(ctx, conversationID, upload, contentTypes[i])
+		stored = append(stored, keys...)
+		if err != nil {
+			u.deleteStored(ctx, stored)
+			return nil, err
+		}
+		attachments = append(attachments, *attachment)
+	}
+
+	message, err := u.createMessage(conversation, senderID, content, attachments)
+	if err != nil {
+		u.deleteStored(ctx, stored)
+		return nil, err
+	}
+	return message, nil
+}
+
+// storeAttachment uploads a file, and a thumbnail of images, returning the
+// storage keys it wrote
+func (u *messageUseCase) storeAttachment(ctx context.Context, conversationID uuid.UUID, upload domain.AttachmentUpload, contentType string) (*domain.MessageAttachment, []string, error) {
+	url, err := u.storage.Upload(ctx, bytes.NewReader(upload.Data), "attachment"+attachmentTypes[contentType])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attachment := &domain.MessageAttachment{
+		ID:             uuid.New(),
+		ConversationID: conversationID,
+		Filename:       attachmentFilename(upload.Filename, contentType),
+		ContentType:    contentType,
+		Size:           int64(len(upload.Data)),
+		StorageKey:     path.Base(url),
+	}
+	keys := []string{attachment.StorageKey}
+
+	if strings.HasPrefix(contentType, "image/") {
+		if width, height, err := u.images.ImageSize(upload.Data); err == nil {
+			attachment.Width, attachment.Height = width, height
+		}
+
+		// An image we cannot scale is still sent, just without a preview
+		thumbnail, err := u.images.GenerateThumbnail(upload.Data, attachmentThumbnailSize)
+		if err == nil {
+			url, err := u.storage.Upload(ctx, bytes.NewReader(thumbnail), "thumbnail.jpg")
+			if err != nil {
+				return nil, keys, err
+			}
+			attachment.ThumbnailKey = path.Base(url)
+			keys = append(keys, attachment.ThumbnailKey)
+		}
+	}
+
+	return attachment, keys, nil
+}
+
+func (u *messageUseCase) deleteStored(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := u.storage.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete attachment %s: %v", key, err)
+		}
+	}
+}
+
+func (u *messageUseCase) OpenAttachment(conversationID, attachmentID, userID uuid.UUID, thumbnail bool) (*domain.MessageAttachment, io.ReadCloser, error) {
+	if u.storage == nil {
+		return nil, nil, errors.New("attachments are not available")
+	}
+	if _, err := u.GetConversation(conversationID, userID); err != nil {
+		return nil, nil, err
+	}
+
+	attachment, err := u.messageRepo.FindAttachmentByID(attachmentID)
+	if err != nil || attachment.ConversationID != conversationID {
+		return nil, nil, errors.New("attachment not found")
+	}
+
+	key := attachment.StorageKey
+	if thumbnail {
+		if attachment.ThumbnailKey == "" {
+			return nil, nil, errors.New("attachment not found")
+		}
+		key = attachment.ThumbnailKey
+	}
+
+	file, err := u.storage.Open(context.Background(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	attachment.SetURLs()
+	return attachment, file, nil
+}
+
+// attachmentFilename keeps the name the sender gave, without any directory,
+// and with an extension matching what the file really is
+func attachmentFilename(name, contentType string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		name = "attachment"
+	}
+	ext := attachmentTypes[contentType]
+	if current := strings.ToLower(filepath.Ext(name)); current != ext && !(ext == ".jpg" && current == ".jpeg") {
+		name += ext
+	}
+	return name
+}
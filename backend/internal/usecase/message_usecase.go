@@ -2,33 +2,52 @@ package usecase
 
 import (
 	"errors"
+	"io"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
 )
 
 type MessageUseCase interface {
 	CreateConversation(userID uuid.UUID, req *domain.CreateConversationRequest) (*domain.Conversation, error)
 	GetOrCreateConversation(productID, buyerID, sellerID uuid.UUID) (*domain.Conversation, error)
-	GetConversation(conversationID uuid.UUID) (*domain.Conversation, error)
+	// GetConversation returns the conversation if userID takes part in it
+	GetConversation(conversationID, userID uuid.UUID) (*domain.Conversation, error)
 	GetUserConversations(userID uuid.UUID) ([]*domain.Conversation, error)
 	SendMessage(conversationID, senderID uuid.UUID, content string) (*domain.Message, error)
-	GetMessages(conversationID uuid.UUID, page, limit int) ([]*domain.Message, *domain.PaginationResponse, error)
+	GetMessages(conversationID, userID uuid.UUID, page, limit int, cursor string) ([]*domain.Message, *domain.PaginationResponse, error)
+	// MarkMessageRead records that userID read one message
+	MarkMessageRead(conversationID, messageID, userID uuid.UUID) (*domain.ReadReceipt, error)
+	// MarkConversationRead records that userID read the conversation up to a
+	// message, or all of it when upTo is nil
+	MarkConversationRead(conversationID, userID uuid.UUID, upTo *uuid.UUID) (*domain.ReadReceipt, error)
+	// SendAttachments sends images or PDFs, with optional text, as one message
+	SendAttachments(conversationID, senderID uuid.UUID, content string, uploads []domain.AttachmentUpload) (*domain.Message, error)
+	// OpenAttachment returns an attachment, or its thumbnail, to a participant
+	OpenAttachment(conversationID, attachmentID, userID uuid.UUID, thumbnail bool) (*domain.MessageAttachment, io.ReadCloser, error)
 }
 
 type messageUseCase struct {
 	messageRepo domain.MessageRepository
 	productRepo domain.ProductRepository
+	storage     infrastructure.ImageStorage
+	images      *infrastructure.ImageProcessor
 }
 
+// NewMessageUseCase creates the message use case; attachments are refused
+// when storage is nil
 func NewMessageUseCase(
 	messageRepo domain.MessageRepository,
 	productRepo domain.ProductRepository,
+	storage infrastructure.ImageStorage,
 ) MessageUseCase {
 	return &messageUseCase{
 		messageRepo: messageRepo,
 		productRepo: productRepo,
+		storage:     storage,
+		images:      infrastructure.NewImageProcessor(),
 	}
 }
 
@@ -73,6 +92,15 @@ func (u *messageUseCase) CreateConversation(userID uuid.UUID, req *domain.Create
 }
 
 func (u *messageUseCase) GetOrCreateConversation(productID, buyerID, sellerID uuid.UUID) (*domain.Conversation, error) {
+	// The conversation is with whoever sells the product, not anyone the caller names
+	product, err := u.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+	if product.SellerID != sellerID {
+		return nil, errors.New("seller does not sell this product")
+	}
+
 	// Check if conversation already exists
 	existing, _ := u.messageRepo.FindConversationByParticipants(productID, buyerID, sellerID)
 	if existing != nil {
@@ -106,8 +134,18 @@ func (u *messageUseCase) GetOrCreateConversation(productID, buyerID, sellerID uu
 	return u.messageRepo.FindConversationByID(conversation.ID)
 }
 
-func (u *messageUseCase) GetConversation(conversationID uuid.UUID) (*domain.Conversation, error) {
-	return u.messageRepo.FindConversationByID(conversationID)
+func (u *messageUseCase) GetConversation(conversationID, userID uuid.UUID) (*domain.Conversation, error) {
+	conversation, err := u.messageRepo.FindConversationByID(conversationID)
+	if err != nil {
+		return nil, errors.New("conversation not found")
+	}
+
+	for _, p := range conversation.Participants {
+		if p.UserID == userID {
+			return conversation, nil
+		}
+	}
+	return nil, errors.New("user is not a participant of this conversation")
 }
 
 func (u *messageUseCase) GetUserConversations(userID uuid.UUID) ([]*domain.Conversation, error) {
@@ -116,35 +154,31 @@ func (u *messageUseCase) GetUserConversations(userID uuid.UUID) ([]*domain.Conve
 
 func (u *messageUseCase) SendMessage(conversationID, senderID uuid.UUID, content string) (*domain.Message, error) {
 	// Verify conversation exists and user is participant
-	conversation, err := u.messageRepo.FindConversationByID(conversationID)
+	conversation, err := u.GetConversation(conversationID, senderID)
 	if err != nil {
-		return nil, errors.New("conversation not found")
-	}
-
-	isParticipant := false
-	for _, p := range conversation.Participants {
-		if p.UserID == senderID {
-			isParticipant = true
-			break
-		}
+		return nil, err
 	}
 
-	if !isParticipant {
-		return nil, errors.New("user is not a participant of this conversation")
-	}
+	return u.createMessage(conversation, senderID, content, nil)
+}
 
+func (u *messageUseCase) createMessage(conversation *domain.Conversation, senderID uuid.UUID, content string, attachments []domain.MessageAttachment) (*domain.Message, error) {
 	// Create message
 	message := &domain.Message{
-		ConversationID: conversationID,
+		ConversationID: conversation.ID,
 		SenderID:       senderID,
 		Content:        content,
 		IsRead:         false,
+		Attachments:    attachments,
 		CreatedAt:      time.Now(),
 	}
 
 	if err := u.messageRepo.CreateMessage(message); err != nil {
 		return nil, err
 	}
+	for i := range message.Attachments {
+		message.Attachments[i].SetURLs()
+	}
 
 	// Reload with sender info
 	message.Sender = &domain.User{}
@@ -158,6 +192,90 @@ func (u *messageUseCase) SendMessage(conversationID, senderID uuid.UUID, content
 	return message, nil
 }
 
-func (u *messageUseCase) GetMessages(conversationID uuid.UUID, page, limit int) ([]*domain.Message, *domain.PaginationResponse, error) {
-	return u.messageRepo.GetMessages(conversationID, page, limit)
+func (u *messageUseCase) GetMessages(conversationID, userID uuid.UUID, page, limit int, cursor string) ([]*domain.Message, *domain.PaginationResponse, error) {
+	if _, err := u.GetConversation(conversationID, userID); err != nil {
+		return nil, nil, err
+	}
+
+	messages, pagination, err := u.messageRepo.GetMessages(conversationID, page, limit, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, message := range messages {
+		for i := range message.Attachments {
+			message.Attachments[i].SetURLs()
+		}
+	}
+	return messages, pagination, nil
+}
+
+func (u *messageUseCase) MarkMessageRead(conversationID, messageID, userID uuid.UUID) (*domain.ReadReceipt, error) {
+	message, err := u.findMessage(conversationID, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &domain.ReadReceipt{
+		ConversationID: conversationID,
+		UserID:         userID,
+		MessageID:      &messageID,
+		MessageIDs:     []uuid.UUID{},
+		ReadAt:         time.Now(),
+	}
+	// Reading your own message is not news
+	if message.SenderID == userID {
+		return receipt, nil
+	}
+
+	read, err := u.messageRepo.MarkAsRead(messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if read {
+		receipt.MessageIDs = append(receipt.MessageIDs, messageID)
+	}
+	return receipt, nil
+}
+
+func (u *messageUseCase) MarkConversationRead(conversationID, userID uuid.UUID, upTo *uuid.UUID) (*domain.ReadReceipt, error) {
+	now := time.Now()
+	readUpTo := now
+	if upTo != nil {
+		message, err := u.findMessage(conversationID, *upTo, userID)
+		if err != nil {
+			return nil, err
+		}
+		readUpTo = message.CreatedAt
+	} else if _, err := u.GetConversation(conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	ids, err := u.messageRepo.MarkReadUpTo(conversationID, userID, readUpTo)
+	if err != nil {
+		return nil, err
+	}
+	if ids == nil {
+		ids = []uuid.UUID{}
+	}
+
+	return &domain.ReadReceipt{
+		ConversationID: conversationID,
+		UserID:         userID,
+		MessageIDs:     ids,
+		ReadAt:         now,
+		LastReadAt:     &readUpTo,
+	}, nil
+}
+
+// findMessage returns a message of a conversation userID takes part in
+func (u *messageUseCase) findMessage(conversationID, messageID, userID uuid.UUID) (*domain.Message, error) {
+	if _, err := u.GetConversation(conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	message, err := u.messageRepo.FindMessageByID(messageID)
+	if err != nil || message.ConversationID != conversationID {
+		return nil, errors.New("message not found")
+	}
+	return message, nil
 }
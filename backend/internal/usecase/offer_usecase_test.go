@@ -1,12 +1,22 @@
 package usecase_test
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/yourusername/ecomate/backend/internal/config"
 	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
 	"github.com/yourusername/ecomate/backend/internal/usecase"
 )
 
@@ -43,6 +53,39 @@ func (m *MockOfferRepository) FindBySellerID(sellerID uuid.UUID) ([]*domain.Offe
 	return args.Get(0).([]*domain.Offer), args.Error(1)
 }
 
+func (m *MockOfferRepository) FindByIDForUpdate(id uuid.UUID) (*domain.Offer, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Offer), args.Error(1)
+}
+
+func (m *MockOfferRepository) FindPendingByProductID(productID uuid.UUID) ([]*domain.Offer, error) {
+	args := m.Called(productID)
+	return args.Get(0).([]*domain.Offer), args.Error(1)
+}
+
+func (m *MockOfferRepository) FindExpired(now time.Time, limit int) ([]*domain.Offer, error) {
+	args := m.Called(now, limit)
+	return args.Get(0).([]*domain.Offer), args.Error(1)
+}
+
+func (m *MockOfferRepository) FindLapsedReservations(now time.Time, limit int) ([]*domain.Offer, error) {
+	args := m.Called(now, limit)
+	return args.Get(0).([]*domain.Offer), args.Error(1)
+}
+
+func (m *MockOfferRepository) FindAcceptedPriceRatios(category string, limit int) ([]float64, error) {
+	args := m.Called(category, limit)
+	return args.Get(0).([]float64), args.Error(1)
+}
+
+func (m *MockOfferRepository) AddRound(round *domain.OfferRound) error {
+	args := m.Called(round)
+	return args.Error(0)
+}
+
 func (m *MockOfferRepository) Update(offer *domain.Offer) error {
 	args := m.Called(offer)
 	return args.Error(0)
@@ -52,7 +95,7 @@ func TestOfferUseCase_CreateOffer_Success(t *testing.T) {
 	// Arrange
 	mockOfferRepo := new(MockOfferRepository)
 	mockProductRepo := new(MockProductRepository)
-	useCase := usecase.NewOfferUseCase(mockOfferRepo, mockProductRepo)
+	useCase := usecase.NewOfferUseCase(nil, mockOfferRepo, mockProductRepo, nil, nil, time.Now)
 
 	buyerID := uuid.New()
 	sellerID := uuid.New()
@@ -91,7 +134,7 @@ func TestOfferUseCase_CreateOffer_CannotOfferOnOwnProduct(t *testing.T) {
 	// Arrange
 	mockOfferRepo := new(MockOfferRepository)
 	mockProductRepo := new(MockProductRepository)
-	useCase := usecase.NewOfferUseCase(mockOfferRepo, mockProductRepo)
+	useCase := usecase.NewOfferUseCase(nil, mockOfferRepo, mockProductRepo, nil, nil, time.Now)
 
 	userID := uuid.New()
 	productID := uuid.New()
@@ -118,7 +161,7 @@ func TestOfferUseCase_CreateOffer_PriceTooHigh(t *testing.T) {
 	// Arrange
 	mockOfferRepo := new(MockOfferRepository)
 	mockProductRepo := new(MockProductRepository)
-	useCase := usecase.NewOfferUseCase(mockOfferRepo, mockProductRepo)
+	useCase := usecase.NewOfferUseCase(nil, mockOfferRepo, mockProductRepo, nil, nil, time.Now)
 
 	buyerID := uuid.New()
 	sellerID := uuid.New()
@@ -143,3 +186,519 @@ func TestOfferUseCase_CreateOffer_PriceTooHigh(t *testing.T) {
 	assert.Contains(t, err.Error(), "offer price must be less than current price")
 	mockProductRepo.AssertExpectations(t)
 }
+
+// memOfferRepo is only used inside a unit of work, so the store is already locked
+type memOfferRepo struct {
+	domain.OfferRepository
+	store *memStore
+}
+
+func (r *memOfferRepo) FindByID(id uuid.UUID) (*domain.Offer, error) {
+	o, ok := r.store.offers[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &o, nil
+}
+
+func (r *memOfferRepo) FindByIDForUpdate(id uuid.UUID) (*domain.Offer, error) {
+	return r.FindByID(id)
+}
+
+func (r *memOfferRepo) FindByProductID(productID uuid.UUID) ([]*domain.Offer, error) {
+	var offers []*domain.Offer
+	for _, o := range r.store.offers {
+		if o.ProductID == productID {
+			o := o
+			offers = append(offers, &o)
+		}
+	}
+	return offers, nil
+}
+
+func (r *memOfferRepo) FindPendingByProductID(productID uuid.UUID) ([]*domain.Offer, error) {
+	var offers []*domain.Offer
+	for _, o := range r.store.offers {
+		if o.ProductID == productID && o.Status == domain.OfferStatusPending {
+			o := o
+			offers = append(offers, &o)
+		}
+	}
+	return offers, nil
+}
+
+func (r *memOfferRepo) FindLapsedReservations(now time.Time, limit int) ([]*domain.Offer, error) {
+	var offers []*domain.Offer
+	for _, o := range r.store.offers {
+		if o.Status == domain.OfferStatusAccepted && o.ReservedUntil != nil && !o.HoldsReservation(now) {
+			o := o
+			offers = append(offers, &o)
+		}
+	}
+	return offers, nil
+}
+
+func (r *memOfferRepo) AddRound(round *domain.OfferRound) error {
+	r.store.rounds = append(r.store.rounds, *round)
+	return nil
+}
+
+func (r *memOfferRepo) Update(offer *domain.Offer) error {
+	r.store.offers[offer.ID] = *offer
+	return nil
+}
+
+// readOfferRepo serves the calls made outside a unit of work
+type readOfferRepo struct {
+	domain.OfferRepository
+	store *memStore
+}
+
+func (r *readOfferRepo) Create(offer *domain.Offer) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	offer.ID = uuid.New()
+	for _, round := range offer.Rounds {
+		round.OfferID = offer.ID
+		r.store.rounds = append(r.store.rounds, round)
+	}
+	stored := *offer
+	stored.Rounds = nil
+	r.store.offers[offer.ID] = stored
+	return nil
+}
+
+func (r *readOfferRepo) FindByID(id uuid.UUID) (*domain.Offer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	o, ok := r.store.offers[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	for _, round := range r.store.rounds {
+		if round.OfferID == id {
+			o.Rounds = append(o.Rounds, round)
+		}
+	}
+	return &o, nil
+}
+
+func (r *readOfferRepo) FindExpired(now time.Time, limit int) ([]*domain.Offer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var offers []*domain.Offer
+	for _, o := range r.store.offers {
+		if o.IsExpired(now) {
+			o := o
+			offers = append(offers, &o)
+		}
+	}
+	return offers, nil
+}
+
+// readProductRepo serves product lookups made outside a unit of work
+type readProductRepo struct {
+	domain.ProductRepository
+	store *memStore
+}
+
+func (r *readProductRepo) FindByID(id uuid.UUID) (*domain.Product, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.products[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &p, nil
+}
+
+// recordingMessages collects the negotiation events posted to conversations
+type recordingMessages struct {
+	usecase.MessageUseCase
+	mu     sync.Mutex
+	posted []string
+}
+
+func (m *recordingMessages) GetOrCreateConversation(productID, buyerID, sellerID uuid.UUID) (*domain.Conversation, error) {
+	return &domain.Conversation{ID: productID, ProductID: &productID}, nil
+}
+
+func (m *recordingMessages) SendMessage(conversationID, senderID uuid.UUID, content string) (*domain.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.posted = append(m.posted, content)
+	return &domain.Message{ConversationID: conversationID, SenderID: senderID, Content: content}, nil
+}
+
+func newOfferFixture(now *time.Time) (*memStore, *recordingMessages, usecase.OfferUseCase, domain.Product) {
+	store := newMemStore()
+	product := domain.Product{
+		ID:       uuid.New(),
+		SellerID: uuid.New(),
+		Title:    "Camping Stove",
+		Price:    1000,
+		Status:   domain.StatusActive,
+	}
+	store.products[product.ID] = product
+
+	messages := &recordingMessages{}
+	useCase := usecase.NewOfferUseCase(
+		&memUnitOfWork{store: store},
+		&readOfferRepo{store: store},
+		&readProductRepo{store: store},
+		messages,
+		nil,
+		func() time.Time { return *now },
+	)
+	return store, messages, useCase, product
+}
+
+func TestOfferUseCase_CounterOffer_NegotiatesToAcceptance(t *testing.T) {
+	// Arrange
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store, messages, useCase, product := newOfferFixture(&now)
+	buyerID := uuid.New()
+	rivalID := uuid.New()
+
+	offer, err := useCase.CreateOffer(buyerID, product.ID, 800, "Would you take 800?")
+	assert.NoError(t, err)
+	rival, err := useCase.CreateOffer(rivalID, product.ID, 700, "")
+	assert.NoError(t, err)
+
+	// Act & Assert: turns alternate between seller and buyer
+	_, err = useCase.CounterOffer(offer.ID, buyerID, 750, "")
+	assert.EqualError(t, err, "waiting for the other party to respond")
+
+	_, err = useCase.CounterOffer(offer.ID, product.SellerID, 780, "")
+	assert.EqualError(t, err, "counter price must be higher than the buyer's offer")
+
+	offer, err = useCase.CounterOffer(offer.ID, product.SellerID, 950, "Lowest I can go is 950")
+	assert.NoError(t, err)
+	assert.Equal(t, buyerID, *offer.AwaitingUserID)
+
+	offer, err = useCase.CounterOffer(offer.ID, buyerID, 900, "Meet in the middle?")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, offer.Round)
+
+	_, err = useCase.RespondOffer(offer.ID, buyerID, true, "")
+	assert.EqualError(t, err, "waiting for the other party to respond")
+
+	offer, err = useCase.RespondOffer(offer.ID, product.SellerID, true, "Deal")
+	assert.NoError(t, err)
+
+	// The agreed price and the full history are kept
+	assert.Equal(t, domain.OfferStatusAccepted, offer.Status)
+	assert.Equal(t, 900, offer.OfferPrice)
+	assert.Nil(t, offer.AwaitingUserID)
+	var actions []domain.OfferAction
+	for _, round := range offer.Rounds {
+		actions = append(actions, round.Action)
+	}
+	assert.Equal(t, []domain.OfferAction{
+		domain.OfferActionOffer, domain.OfferActionCounter, domain.OfferActionCounter, domain.OfferActionAccept,
+	}, actions)
+
+	// The product is held for the buyer without touching the listing price
+	assert.Equal(t, domain.StatusReserved, store.products[product.ID].Status)
+	assert.Equal(t, 1000, store.products[product.ID].Price)
+
+	// The competing offer is cancelled
+	assert.Equal(t, domain.OfferStatusCancelled, store.offers[rival.ID].Status)
+	_, err = useCase.CounterOffer(rival.ID, product.SellerID, 950, "")
+	assert.EqualError(t, err, "offer already responded")
+
+	// Every step was posted into the product conversation
+	assert.Equal(t, []string{
+		"[Offer] Offered ¥800\nWould you take 800?",
+		"[Offer] Offered ¥700",
+		"[Offer] Countered with ¥950\nLowest I can go is 950",
+		"[Offer] Countered with ¥900\nMeet in the middle?",
+		"[Offer] Accepted ¥900. The item is reserved for the buyer until 2025-03-02 12:00 UTC\nDeal",
+		"[Offer] Offer of ¥700 was cancelled because another offer was accepted",
+	}, messages.posted)
+}
+
+func TestOfferUseCase_ExpireOffers_ClosesStaleTurns(t *testing.T) {
+	// Arrange
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store, _, useCase, product := newOfferFixture(&now)
+	buyerID := uuid.New()
+
+	offer, err := useCase.CreateOffer(buyerID, product.ID, 800, "")
+	assert.NoError(t, err)
+
+	// Act
+	now = now.Add(domain.OfferExpiry - time.Minute)
+	expired, err := useCase.ExpireOffers(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, expired)
+
+	now = now.Add(time.Minute)
+	_, err = useCase.RespondOffer(offer.ID, product.SellerID, true, "")
+	assert.EqualError(t, err, "offer has expired")
+
+	expired, err = useCase.ExpireOffers(now)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Equal(t, domain.OfferStatusExpired, store.offers[offer.ID].Status)
+	assert.Equal(t, 1000, store.products[product.ID].Price)
+}
+
+func TestPurchaseUseCase_CheckoutOffer_BuysAtAgreedPriceOrReleases(t *testing.T) {
+	// Arrange: two accepted offers on two products
+	now := time.Now()
+	store, _, offers, product := newOfferFixture(&now)
+	other := product
+	other.ID = uuid.New()
+	store.products[other.ID] = other
+	buyerID := uuid.New()
+
+	accept := func(productID uuid.UUID) *domain.Offer {
+		offer, err := offers.CreateOffer(buyerID, productID, 800, "")
+		assert.NoError(t, err)
+		offer, err = offers.RespondOffer(offer.ID, product.SellerID, true, "")
+		assert.NoError(t, err)
+		return offer
+	}
+	paid := accept(product.ID)
+	unpaid := accept(other.ID)
+
+	purchases := usecase.NewPurchaseUseCase(
+		&memUnitOfWork{store: store},
+		&readPurchaseRepo{store: store},
+		nil,
+		nil,
+		infrastructure.NewLocalPaymentProvider(),
+		nil,
+	)
+	req := &domain.CheckoutOfferRequest{ShippingAddress: "Tokyo", PaymentMethod: "credit_card"}
+
+	// Other buyers can no longer purchase the reserved item
+	_, err := purchases.Create(uuid.New(), &domain.CreatePurchaseRequest{ProductID: product.ID, ShippingAddress: "Osaka", PaymentMethod: "credit_card"})
+	assert.EqualError(t, err, "product is not available for purchase")
+	_, err = purchases.CheckoutOffer(paid.ID, uuid.New(), req)
+	assert.EqualError(t, err, "only the buyer can check out this offer")
+
+	// Act: one buyer checks out and pays, the other checks out and walks away
+	purchase, err := purchases.CheckoutOffer(paid.ID, buyerID, req)
+	assert.NoError(t, err)
+	assert.Equal(t, 800, purchase.Price)
+	_, err = purchases.CheckoutOffer(paid.ID, buyerID, req)
+	assert.EqualError(t, err, "offer already checked out")
+	_, err = purchases.Pay(purchase.ID, buyerID)
+	assert.NoError(t, err)
+
+	abandoned, err := purchases.CheckoutOffer(unpaid.ID, buyerID, req)
+	assert.NoError(t, err)
+
+	released, err := purchases.ReleaseReservations(now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, released)
+	released, err = purchases.ReleaseReservations(now.Add(domain.OfferReservationWindow))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, released)
+	assert.Equal(t, domain.PurchaseStatusPaid, store.purchases[purchase.ID].Status)
+	assert.Equal(t, domain.StatusSold, store.products[product.ID].Status)
+	assert.Equal(t, domain.PurchaseStatusCancelled, store.purchases[abandoned.ID].Status)
+	assert.Equal(t, domain.StatusActive, store.products[other.ID].Status)
+	assert.Equal(t, domain.OfferStatusExpired, store.offers[unpaid.ID].Status)
+	assert.Equal(t, domain.OfferStatusAccepted, store.offers[paid.ID].Status)
+	assert.Nil(t, store.offers[paid.ID].ReservedUntil)
+}
+
+// newGeminiStub serves canned generateContent replies and records the prompts it receives
+func newGeminiStub(t *testing.T, reply string) (*infrastructure.AIClient, *[]string) {
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models/gemini-2.5-flash:generateContent", r.URL.Path)
+		assert.Equal(t, "test-key", r.URL.Query().Get("key"))
+
+		body, _ := io.ReadAll(r.Body)
+		var req infrastructure.GeminiRequest
+		assert.NoError(t, json.Unmarshal(body, &req))
+		prompts = append(prompts, req.Contents[0].Parts[0].Text)
+		assert.Equal(t, "application/json", req.GenerationConfig.ResponseMimeType)
+
+		_ = json.NewEncoder(w).Encode(infrastructure.GeminiResponse{
+			Candidates: []infrastructure.GeminiCandidate{{
+				Content: infrastructure.GeminiContent{Parts: []infrastructure.GeminiPart{{Text: reply}}},
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	llm, err := infrastructure.NewLLMProvider(&config.AIConfig{
+		LLMProvider: "gemini",
+		LLMAPIKey:   "test-key",
+		LLMBaseURL:  server.URL,
+	})
+	assert.NoError(t, err)
+	return infrastructure.NewTextAIClient(llm), &prompts
+}
+
+func newSuggestionMocks(product *domain.Product) (*MockOfferRepository, *MockProductRepository) {
+	mockOfferRepo := new(MockOfferRepository)
+	mockProductRepo := new(MockProductRepository)
+	mockProductRepo.On("FindByID", product.ID).Return(product, nil)
+	mockProductRepo.On("FindRecentSoldPrices", product.Category, mock.Anything).Return([]int{7000, 8000, 9000}, nil)
+	mockOfferRepo.On("FindByProductID", product.ID).Return([]*domain.Offer{}, nil)
+	mockOfferRepo.On("FindAcceptedPriceRatios", product.Category, mock.Anything).Return([]float64{0.8, 0.9}, nil)
+	return mockOfferRepo, mockProductRepo
+}
+
+func TestOfferUseCase_GetNegotiationSuggestion_ParsesModelJSON(t *testing.T) {
+	// Arrange
+	product := &domain.Product{ID: uuid.New(), Title: "Tent", Price: 10000, Category: "sports", Condition: domain.ConditionGood}
+	mockOfferRepo, mockProductRepo := newSuggestionMocks(product)
+	aiClient, prompts := newGeminiStub(t, "Here you go:\n```json\n"+
+		`{"recommended_price": 8200, "acceptance_rate": 0.72, "strategy": "Open at 8,200 yen.", "reasoning": "Similar tents sold for 8,000 yen."}`+
+		"\n```")
+	useCase := usecase.NewOfferUseCase(nil, mockOfferRepo, mockProductRepo, nil, aiClient, time.Now)
+
+	// Act
+	suggestion, err := useCase.GetNegotiationSuggestion(product.ID, uuid.New(), true)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &usecase.NegotiationSuggestion{
+		RecommendedPrice: 8200,
+		AcceptanceRate:   0.72,
+		Strategy:         "Open at 8,200 yen.",
+		Reasoning:        "Similar tents sold for 8,000 yen.",
+	}, suggestion)
+	assert.Len(t, *prompts, 1)
+	assert.True(t, strings.Contains((*prompts)[0], "2 accepted offers in this category, agreed at 85% of the listing price"))
+	assert.True(t, strings.Contains((*prompts)[0], "median sold price ¥8000"))
+}
+
+func TestOfferUseCase_GetNegotiationSuggestion_FallsBackOnUnusableOutput(t *testing.T) {
+	product := &domain.Product{ID: uuid.New(), Title: "Tent", Price: 10000, Category: "sports", Condition: domain.ConditionGood}
+
+	replies := []string{
+		"I would offer around 8000 yen.",
+		`{"recommended_price": 12000, "acceptance_rate": 0.5, "strategy": "Pay more."}`,
+		`{"recommended_price": 8000, "acceptance_rate": 1.7, "strategy": "Offer 8000."}`,
+		`{"recommended_price": "cheap"}`,
+	}
+	for _, reply := range replies {
+		mockOfferRepo, mockProductRepo := newSuggestionMocks(product)
+		aiClient, _ := newGeminiStub(t, reply)
+		useCase := usecase.NewOfferUseCase(nil, mockOfferRepo, mockProductRepo, nil, aiClient, time.Now)
+
+		suggestion, err := useCase.GetNegotiationSuggestion(product.ID, uuid.New(), true)
+
+		// Recent sales went below the usual 85% of listing, so the fallback follows the median
+		assert.NoError(t, err, reply)
+		assert.Equal(t, 8000, suggestion.RecommendedPrice, reply)
+		assert.Equal(t, 0.7, suggestion.AcceptanceRate, reply)
+		assert.Equal(t, "AI response could not be used, using default strategy.", suggestion.Reasoning, reply)
+	}
+}
+
+func TestOfferUseCase_GetNegotiationSuggestion_RetriesOpenAICompatibleProvider(t *testing.T) {
+	// Arrange: a local OpenAI-compatible server that is briefly overloaded
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, `{
+			"choices": [{"message": {"role": "assistant", "content": "{\"recommended_price\": 9000, \"acceptance_rate\": 0.6, \"strategy\": \"Offer 9,000 yen.\"}"}}],
+			"usage": {"prompt_tokens": 120, "completion_tokens": 30, "total_tokens": 150}
+		}`)
+	}))
+	defer server.Close()
+
+	llm := infrastructure.NewManagedLLMProvider(
+		infrastructure.NewOpenAIProvider("", server.URL+"/v1", "local-model"), time.Second, 2, time.Millisecond)
+	product := &domain.Product{ID: uuid.New(), Title: "Tent", Price: 10000, Category: "sports", Condition: domain.ConditionGood}
+	mockOfferRepo, mockProductRepo := newSuggestionMocks(product)
+	useCase := usecase.NewOfferUseCase(nil, mockOfferRepo, mockProductRepo, nil, infrastructure.NewTextAIClient(llm), time.Now)
+
+	// Act
+	suggestion, err := useCase.GetNegotiationSuggestion(product.ID, uuid.New(), true)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 9000, suggestion.RecommendedPrice)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, infrastructure.LLMUsageStats{
+		Calls:  1,
+		Tokens: infrastructure.TokenUsage{PromptTokens: 120, CompletionTokens: 30, TotalTokens: 150},
+	}, llm.Usage())
+}
+
+func TestPurchaseUseCase_CancelledOfferCheckoutKeepsReservation(t *testing.T) {
+	// Arrange: an accepted offer is checked out and the buyer changes their mind
+	now := time.Now()
+	store, _, offers, product := newOfferFixture(&now)
+	buyerID := uuid.New()
+	offer, err := offers.CreateOffer(buyerID, product.ID, 800, "")
+	assert.NoError(t, err)
+	offer, err = offers.RespondOffer(offer.ID, product.SellerID, true, "")
+	assert.NoError(t, err)
+
+	purchases := usecase.NewPurchaseUseCase(&memUnitOfWork{store: store}, &readPurchaseRepo{store: store}, nil, nil, infrastructure.NewLocalPaymentProvider(), nil)
+	req := &domain.CheckoutOfferRequest{ShippingAddress: "Tokyo", PaymentMethod: "credit_card"}
+	first, err := purchases.CheckoutOffer(offer.ID, buyerID, req)
+	assert.NoError(t, err)
+
+	// Act
+	_, err = purchases.Cancel(first.ID, buyerID, &domain.CancelPurchaseRequest{Reason: domain.CancelReasonChangedMind})
+	assert.NoError(t, err)
+	afterCancel := store.products[product.ID].Status
+	_, createErr := purchases.Create(uuid.New(), &domain.CreatePurchaseRequest{ProductID: product.ID, ShippingAddress: "Osaka", PaymentMethod: "credit_card"})
+	second, checkoutErr := purchases.CheckoutOffer(offer.ID, buyerID, req)
+	assert.NoError(t, checkoutErr)
+	_, err = purchases.Cancel(second.ID, buyerID, &domain.CancelPurchaseRequest{Reason: domain.CancelReasonChangedMind})
+	assert.NoError(t, err)
+	released, err := purchases.ReleaseReservations(now.Add(domain.OfferReservationWindow))
+
+	// Assert: the product stays reserved for the buyer until the reservation lapses
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusReserved, afterCancel)
+	assert.EqualError(t, createErr, "product is not available for purchase")
+	assert.Equal(t, 1, released)
+	assert.Equal(t, domain.StatusActive, store.products[product.ID].Status)
+	assert.Equal(t, domain.OfferStatusExpired, store.offers[offer.ID].Status)
+}
+
+func TestPurchaseUseCase_CancelAfterReservationLapsedReopensProduct(t *testing.T) {
+	// Arrange: an offer checkout is paid, which ends the reservation once it
+	// runs out
+	now := time.Now()
+	store, _, offers, product := newOfferFixture(&now)
+	buyerID := uuid.New()
+	offer, err := offers.CreateOffer(buyerID, product.ID, 800, "")
+	assert.NoError(t, err)
+	offer, err = offers.RespondOffer(offer.ID, product.SellerID, true, "")
+	assert.NoError(t, err)
+
+	purchases := usecase.NewPurchaseUseCase(&memUnitOfWork{store: store}, &readPurchaseRepo{store: store}, nil, nil, infrastructure.NewLocalPaymentProvider(), nil)
+	purchase, err := purchases.CheckoutOffer(offer.ID, buyerID, &domain.CheckoutOfferRequest{ShippingAddress: "Tokyo", PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	_, err = purchases.Pay(purchase.ID, buyerID)
+	assert.NoError(t, err)
+	_, err = purchases.ReleaseReservations(now.Add(domain.OfferReservationWindow))
+	assert.NoError(t, err)
+
+	// Act
+	_, err = purchases.Cancel(purchase.ID, buyerID, &domain.CancelPurchaseRequest{Reason: domain.CancelReasonChangedMind})
+
+	// Assert: nothing would ever release a reservation again
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PurchaseStatusRefunded, store.purchases[purchase.ID].Status)
+	assert.Equal(t, domain.StatusActive, store.products[product.ID].Status)
+}
@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/yourusername/ecomate/backend/internal/domain"
+)
+
+// Facets counts the products for each filter choice. Each facet ignores its
+// own selection so several categories or conditions can be combined.
+func (uc *ProductUseCase) Facets(filters domain.ProductFilters) (*domain.ProductFacets, error) {
+	counts, err := uc.productRepo.CountFacets(uc.resolveSearch(&filters))
+	if err != nil {
+		return nil, err
+	}
+	return buildFacets(counts, &filters), nil
+}
+
+func buildFacets(counts []*domain.ProductFacetCount, filters *domain.ProductFilters) *domain.ProductFacets {
+	categories := filters.CategoryValues()
+	conditions := filters.ConditionValues()
+
+	categoryCounts := make(map[string]int)
+	conditionCounts := make(map[string]int)
+	priceCounts := make([]int, len(domain.PriceFacetRanges))
+	co2Counts := make([]int, len(domain.CO2FacetRanges))
+	ratingCounts := make([]int, len(domain.SellerRatingFacetMins))
+
+	for _, c := range counts {
+		inCategory := len(categories) == 0 || containsString(categories, c.Category)
+		inCondition := len(conditions) == 0 || containsCondition(conditions, c.Condition)
+
+		if inCondition && c.PriceOK && c.RatingOK {
+			categoryCounts[c.Category] += c.Count
+		}
+		if inCategory && c.PriceOK && c.RatingOK {
+			conditionCounts[string(c.Condition)] += c.Count
+		}
+		if inCategory && inCondition && c.RatingOK && c.PriceBucket < len(priceCounts) {
+			priceCounts[c.PriceBucket] += c.Count
+		}
+		if inCategory && inCondition && c.PriceOK && c.RatingOK && c.CO2Bucket < len(co2Counts) {
+			co2Counts[c.CO2Bucket] += c.Count
+		}
+		if inCategory && inCondition && c.PriceOK {
+			for i, min := range domain.SellerRatingFacetMins {
+				if c.RatingFloor >= min {
+					ratingCounts[i] += c.Count
+				}
+			}
+		}
+	}
+
+	// Selected values stay listed even when nothing matches them any more
+	for _, category := range categories {
+		categoryCounts[category] += 0
+	}
+	for _, condition := range conditions {
+		conditionCounts[string(condition)] += 0
+	}
+
+	facets := &domain.ProductFacets{
+		Categories: valueBuckets(categoryCounts, func(v string) bool { return containsString(categories, v) }),
+		Conditions: valueBuckets(conditionCounts, func(v string) bool {
+			return containsCondition(conditions, domain.ProductCondition(v))
+		}),
+		PriceRanges: rangeBuckets(domain.PriceFacetRanges, priceCounts, func(r domain.FacetRange) bool {
+			// A bucket is picked as min_price=Min&max_price=Max-1
+			if r.Max == 0 {
+				return filters.MinPrice == int(r.Min) && filters.MaxPrice == 0
+			}
+			return filters.MinPrice == int(r.Min) && filters.MaxPrice == int(r.Max)-1
+		}),
+		CO2Impact: rangeBuckets(domain.CO2FacetRanges, co2Counts, func(domain.FacetRange) bool { return false }),
+	}
+	for i, min := range domain.SellerRatingFacetMins {
+		facets.SellerRating = append(facets.SellerRating, domain.FacetBucket{
+			Value:    strconv.Itoa(min) + "+",
+			Min:      float64(min),
+			Count:    ratingCounts[i],
+			Selected: filters.MinSellerRating == float64(min),
+		})
+	}
+	return facets
+}
+
+// valueBuckets lists values by count, most common first
+func valueBuckets(counts map[string]int, selected func(string) bool) []domain.FacetBucket {
+	buckets := make([]domain.FacetBucket, 0, len(counts))
+	for value, count := range counts {
+		buckets = append(buckets, domain.FacetBucket{Value: value, Count: count, Selected: selected(value)})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Count != buckets[j].Count {
+			return buckets[i].Count > buckets[j].Count
+		}
+		return buckets[i].Value < buckets[j].Value
+	})
+	return buckets
+}
+
+func rangeBuckets(ranges []domain.FacetRange, counts []int, selected func(domain.FacetRange) bool) []domain.FacetBucket {
+	buckets := make([]domain.FacetBucket, len(ranges))
+	for i, r := range ranges {
+		buckets[i] = domain.FacetBucket{Value: r.Label, Min: r.Min, Max: r.Max, Count: counts[i], Selected: selected(r)}
+	}
+	return buckets
+}
+
+func containsCondition(values []domain.ProductCondition, value domain.ProductCondition) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+const (
+	productIndexTopic     = "product_index"
+	productIndexQueueSize = 256
+)
+
+// ProductEventBroker carries messages between the API replicas;
+// realtime.Broker is one
+type ProductEventBroker interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	Subscribe(topic string, deliver func(payload []byte)) (cancel func(), err error)
+}
+
+// productIndexEvent says that a product changed on one replica
+type productIndexEvent struct {
+	ProductID uuid.UUID `json:"product_id"`
+	ReplicaID string    `json:"replica_id"`
+}
+
+// ProductIndexSync keeps the in-memory product indexes of every replica in
+// step. As a ProductIndexer it tells the other replicas about product writes;
+// Run applies their writes to this replica's indexers.
+type ProductIndexSync struct {
+	broker      ProductEventBroker
+	replicaID   string
+	productRepo domain.ProductRepository
+	indexers    []ProductIndexer
+	queue       chan uuid.UUID
+}
+
+// NewProductIndexSync creates the sync of replicaID (unique per replica) for
+// the local indexers
+func NewProductIndexSync(
+	broker ProductEventBroker,
+	replicaID string,
+	productRepo domain.ProductRepository,
+	indexers ...ProductIndexer,
+) *ProductIndexSync {
+	return &ProductIndexSync{
+		broker:      broker,
+		replicaID:   replicaID,
+		productRepo: productRepo,
+		indexers:    indexers,
+		queue:       make(chan uuid.UUID, productIndexQueueSize),
+	}
+}
+
+func (s *ProductIndexSync) IndexProduct(product *domain.Product) {
+	s.publish(product.ID)
+}
+
+func (s *ProductIndexSync) RemoveProduct(productID uuid.UUID) {
+	s.publish(productID)
+}
+
+func (s *ProductIndexSync) publish(productID uuid.UUID) {
+	payload, err := json.Marshal(productIndexEvent{ProductID: productID, ReplicaID: s.replicaID})
+	if err != nil {
+		return
+	}
+	if err := s.broker.Publish(context.Background(), productIndexTopic, payload); err != nil {
+		log.Printf("Failed to publish index update of product %s: %v", productID, err)
+	}
+}
+
+// Run reindexes the products other replicas wrote until ctx is done
+func (s *ProductIndexSync) Run(ctx context.Context) error {
+	cancel, err := s.broker.Subscribe(productIndexTopic, s.deliver)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case productID := <-s.queue:
+			s.reindex(productID)
+		}
+	}
+}
+
+// deliver queues the product so a slow database never holds up the broker
+func (s *ProductIndexSync) deliver(payload []byte) {
+	var event productIndexEvent
+	if err := json.Unmarshal(payload, &event); err != nil || event.ReplicaID == s.replicaID {
+		// This replica indexed its own writes already
+		return
+	}
+	select {
+	case s.queue <- event.ProductID:
+	default:
+		log.Printf("Index sync queue full, product %s will be indexed on next start", event.ProductID)
+	}
+}
+
+// reindex reads the product as it is now, so updates that arrive out of
+// order still leave the indexes right
+func (s *ProductIndexSync) reindex(productID uuid.UUID) {
+	product, err := s.productRepo.FindByID(productID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		for _, indexer := range s.indexers {
+			indexer.RemoveProduct(productID)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load product %s for indexing: %v", productID, err)
+		return
+	}
+	for _, indexer := range s.indexers {
+		indexer.IndexProduct(product)
+	}
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"log"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+)
+
+const (
+	productSearchCandidateLimit = 1000
+	productSearchBackfillPage   = 100
+)
+
+// ProductSearcher answers the search text of product filters
+type ProductSearcher interface {
+	// Resolve returns filters with Search replaced by the matching product
+	// IDs and their scores, best match first
+	Resolve(filters *domain.ProductFilters) *domain.ProductFilters
+}
+
+// ProductSearchUseCase keeps listings in a full-text index that understands
+// Japanese, which MySQL FULLTEXT does not split into words. Until Rebuild has
+// loaded every listing, searches are left to the database.
+type ProductSearchUseCase interface {
+	ProductIndexer
+	ProductSearcher
+	// Rebuild indexes every product for sale
+	Rebuild() error
+}
+
+type productSearchUseCase struct {
+	index       infrastructure.SearchIndex
+	productRepo domain.ProductRepository
+	ready       atomic.Bool
+}
+
+func NewProductSearchUseCase(index infrastructure.SearchIndex, productRepo domain.ProductRepository) ProductSearchUseCase {
+	return &productSearchUseCase{
+		index:       index,
+		productRepo: productRepo,
+	}
+}
+
+func (u *productSearchUseCase) IndexProduct(product *domain.Product) {
+	if product.Status != domain.StatusActive {
+		u.index.Remove(product.ID)
+		return
+	}
+	u.index.Index(infrastructure.SearchDocument{
+		ID:    product.ID,
+		Title: product.Title,
+		Body:  product.Category + "\n" + product.Description,
+	})
+}
+
+func (u *productSearchUseCase) RemoveProduct(productID uuid.UUID) {
+	u.index.Remove(productID)
+}
+
+func (u *productSearchUseCase) Rebuild() error {
+	for page := 1; ; page++ {
+		products, pagination, err := u.productRepo.List(&domain.ProductFilters{Page: page, Limit: productSearchBackfillPage})
+		if err != nil {
+			return err
+		}
+		for _, p := range products {
+			u.IndexProduct(p)
+		}
+		if page >= pagination.TotalPages {
+			break
+		}
+	}
+	u.ready.Store(true)
+	log.Printf("Search index ready with %d products", u.index.Len())
+	return nil
+}
+
+func (u *productSearchUseCase) Resolve(filters *domain.ProductFilters) *domain.ProductFilters {
+	query := strings.TrimSpace(filters.Search)
+	if query == "" || !u.ready.Load() {
+		return filters
+	}
+
+	hits := u.index.Search(query, productSearchCandidateLimit)
+	resolved := *filters
+	resolved.Search = ""
+	resolved.IDs = make([]uuid.UUID, 0, len(hits))
+	resolved.Scores = make([]float64, 0, len(hits))
+	for _, hit := range hits {
+		resolved.IDs = append(resolved.IDs, hit.ID)
+		resolved.Scores = append(resolved.Scores, hit.Score)
+	}
+	return &resolved
+}
@@ -0,0 +1,148 @@
+package usecase_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+	"github.com/yourusername/ecomate/backend/internal/realtime"
+	"github.com/yourusername/ecomate/backend/internal/usecase"
+	"gorm.io/gorm"
+)
+
+func TestProductSearchUseCase_Resolve_MatchesJapaneseVariants(t *testing.T) {
+	// Arrange
+	phoneCase := &domain.Product{ID: uuid.New(), Title: "スマートフォンケース 手帳型", Category: "electronics", Status: domain.StatusActive}
+	stand := &domain.Product{ID: uuid.New(), Title: "ｽﾏﾎ スタンド", Description: "卓上用", Category: "electronics", Status: domain.StatusActive}
+	bookshelf := &domain.Product{ID: uuid.New(), Title: "木製 本棚", Description: "3段", Category: "furniture", Status: domain.StatusActive}
+	bike := &domain.Product{ID: uuid.New(), Title: "Road bike", Description: "ロードバイク 軽量", Category: "sports", Status: domain.StatusActive}
+	iphone := &domain.Product{ID: uuid.New(), Title: "iPhone 12 64GB", Category: "electronics", Status: domain.StatusActive}
+
+	mockRepo := new(MockProductRepository)
+	mockRepo.On("List", &domain.ProductFilters{Page: 1, Limit: 100}).
+		Return([]*domain.Product{phoneCase, stand, bookshelf, bike, iphone}, &domain.PaginationResponse{TotalPages: 1}, nil)
+	search := usecase.NewProductSearchUseCase(infrastructure.NewInvertedIndex(nil), mockRepo)
+
+	ids := func(query string) []uuid.UUID {
+		return search.Resolve(&domain.ProductFilters{Search: query}).IDs
+	}
+
+	// Until the index is loaded the database answers
+	assert.Nil(t, ids("スマホ"))
+	assert.NoError(t, search.Rebuild())
+
+	// Act & Assert
+	assert.ElementsMatch(t, []uuid.UUID{phoneCase.ID, stand.ID}, ids("スマホ"))
+	assert.ElementsMatch(t, []uuid.UUID{phoneCase.ID, stand.ID}, ids("sumaho"))
+	assert.Equal(t, []uuid.UUID{phoneCase.ID}, ids("スマホケース"))
+	assert.Equal(t, []uuid.UUID{bookshelf.ID}, ids("本"))
+	assert.Equal(t, []uuid.UUID{bike.ID}, ids("bicycles"))
+	assert.Equal(t, []uuid.UUID{iphone.ID}, ids("iphnoe"))
+	assert.Equal(t, []uuid.UUID{}, ids("冷蔵庫"))
+
+	// Sold products leave the index
+	bike.Status = domain.StatusSold
+	search.IndexProduct(bike)
+	assert.Equal(t, []uuid.UUID{}, ids("bike"))
+}
+
+func TestProductUseCase_ListProducts_UsesSearchIndex(t *testing.T) {
+	// Arrange
+	sofa := &domain.Product{ID: uuid.New(), Title: "ソファー 2人掛け", Category: "furniture", Status: domain.StatusActive}
+	mockRepo := new(MockProductRepository)
+	mockRepo.On("List", &domain.ProductFilters{Page: 1, Limit: 100}).
+		Return([]*domain.Product{sofa}, &domain.PaginationResponse{TotalPages: 1}, nil).Once()
+	search := usecase.NewProductSearchUseCase(infrastructure.NewInvertedIndex(nil), mockRepo)
+	assert.NoError(t, search.Rebuild())
+	useCase := usecase.NewProductUseCase(mockRepo, nil, search)
+
+	mockRepo.On("List", mock.MatchedBy(func(f *domain.ProductFilters) bool {
+		return f.Search == "" && f.Category == "furniture" && len(f.IDs) == 1 && f.IDs[0] == sofa.ID
+	})).Return([]*domain.Product{sofa}, &domain.PaginationResponse{Total: 1}, nil)
+
+	// Act
+	products, pagination, err := useCase.ListProducts(&domain.ProductFilters{Search: "couch", Category: "furniture"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pagination.Total)
+	assert.Equal(t, []*domain.Product{sofa}, products)
+	mockRepo.AssertExpectations(t)
+}
+
+// syncProductRepo is the product table both replicas read
+type syncProductRepo struct {
+	domain.ProductRepository
+	mu       sync.Mutex
+	products map[uuid.UUID]*domain.Product
+}
+
+func (r *syncProductRepo) FindByID(id uuid.UUID) (*domain.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if product, ok := r.products[id]; ok {
+		return product, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *syncProductRepo) List(filters *domain.ProductFilters) ([]*domain.Product, *domain.PaginationResponse, error) {
+	return nil, &domain.PaginationResponse{TotalPages: 1}, nil
+}
+
+func (r *syncProductRepo) set(product *domain.Product, exists bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if exists {
+		r.products[product.ID] = product
+	} else {
+		delete(r.products, product.ID)
+	}
+}
+
+func TestProductIndexSync_AppliesWritesOfOtherReplicas(t *testing.T) {
+	// Arrange: two replicas, each with its own index, share a broker
+	lamp := &domain.Product{ID: uuid.New(), Title: "デスクライト LED", Category: "furniture", Status: domain.StatusActive}
+	repo := &syncProductRepo{products: make(map[uuid.UUID]*domain.Product)}
+	broker := realtime.NewMemoryBroker()
+
+	newReplica := func(replicaID string) (usecase.ProductSearchUseCase, *usecase.ProductIndexSync) {
+		search := usecase.NewProductSearchUseCase(infrastructure.NewInvertedIndex(nil), repo)
+		assert.NoError(t, search.Rebuild())
+		indexSync := usecase.NewProductIndexSync(broker, replicaID, repo, search)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		go indexSync.Run(ctx)
+		return search, indexSync
+	}
+	searchA, syncA := newReplica("replica-a")
+	searchB, _ := newReplica("replica-b")
+	found := func(search usecase.ProductSearchUseCase) int {
+		return len(search.Resolve(&domain.ProductFilters{Search: "ライト"}).IDs)
+	}
+
+	// Act: replica A lists the lamp and then deletes it. Writes are
+	// repeated until replica B has subscribed.
+	repo.set(lamp, true)
+	listed := assert.Eventually(t, func() bool {
+		syncA.IndexProduct(lamp)
+		return found(searchB) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	repo.set(lamp, false)
+	deleted := assert.Eventually(t, func() bool {
+		syncA.RemoveProduct(lamp.ID)
+		return found(searchB) == 0
+	}, time.Second, 5*time.Millisecond)
+
+	// Assert
+	assert.True(t, listed)
+	assert.True(t, deleted)
+	assert.Zero(t, found(searchA), "a replica leaves its own writes to its product use case")
+}
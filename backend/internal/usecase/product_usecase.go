@@ -16,13 +16,25 @@ import (
 type ProductUseCase struct {
 	productRepo domain.ProductRepository
 	aiClient    *infrastructure.AIClient
+	indexers    []ProductIndexer
+	searcher    ProductSearcher
 }
 
-func NewProductUseCase(productRepo domain.ProductRepository, aiClient *infrastructure.AIClient) *ProductUseCase {
-	return &ProductUseCase{
+// NewProductUseCase builds the product use case; indexers are told about
+// every product it creates, updates or deletes. An indexer that is also a
+// ProductSearcher answers the search text of listings.
+func NewProductUseCase(productRepo domain.ProductRepository, aiClient *infrastructure.AIClient, indexers ...ProductIndexer) *ProductUseCase {
+	uc := &ProductUseCase{
 		productRepo: productRepo,
 		aiClient:    aiClient,
+		indexers:    indexers,
 	}
+	for _, indexer := range indexers {
+		if searcher, ok := indexer.(ProductSearcher); ok {
+			uc.searcher = searcher
+		}
+	}
+	return uc
 }
 
 func (uc *ProductUseCase) CreateProduct(
@@ -122,6 +134,7 @@ func (uc *ProductUseCase) CreateProduct(
 	if err := uc.productRepo.Create(product); err != nil {
 		return nil, fmt.Errorf("failed to create product: %w", err)
 	}
+	uc.indexProduct(product)
 
 	return product, nil
 }
@@ -142,7 +155,14 @@ func (uc *ProductUseCase) GetProduct(id uuid.UUID) (*domain.Product, error) {
 }
 
 func (uc *ProductUseCase) ListProducts(filters *domain.ProductFilters) ([]*domain.Product, *domain.PaginationResponse, error) {
-	return uc.productRepo.List(filters)
+	return uc.productRepo.List(uc.resolveSearch(filters))
+}
+
+func (uc *ProductUseCase) resolveSearch(filters *domain.ProductFilters) *domain.ProductFilters {
+	if uc.searcher == nil {
+		return filters
+	}
+	return uc.searcher.Resolve(filters)
 }
 
 func (uc *ProductUseCase) GetCO2Comparison(product *domain.Product) *domain.CO2Comparison {
@@ -199,6 +219,7 @@ func (uc *ProductUseCase) Create(sellerID uuid.UUID, title, description, categor
 	if err := uc.productRepo.Create(product); err != nil {
 		return nil, fmt.Errorf("failed to create product: %w", err)
 	}
+	uc.indexProduct(product)
 
 	return product, nil
 }
@@ -266,6 +287,7 @@ func (uc *ProductUseCase) Update(productID, sellerID uuid.UUID, updates map[stri
 	if err := uc.productRepo.Update(product); err != nil {
 		return nil, fmt.Errorf("failed to update product: %w", err)
 	}
+	uc.indexProduct(product)
 
 	return product, nil
 }
@@ -292,6 +314,15 @@ func (uc *ProductUseCase) Delete(productID, sellerID uuid.UUID) error {
 	if err := uc.productRepo.Delete(productID); err != nil {
 		return fmt.Errorf("failed to delete product: %w", err)
 	}
+	for _, indexer := range uc.indexers {
+		indexer.RemoveProduct(productID)
+	}
 
 	return nil
 }
+
+func (uc *ProductUseCase) indexProduct(product *domain.Product) {
+	for _, indexer := range uc.indexers {
+		indexer.IndexProduct(product)
+	}
+}
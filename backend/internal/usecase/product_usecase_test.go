@@ -28,6 +28,19 @@ func (m *MockProductRepository) FindByID(id uuid.UUID) (*domain.Product, error)
 	return args.Get(0).(*domain.Product), args.Error(1)
 }
 
+func (m *MockProductRepository) FindByIDForUpdate(id uuid.UUID) (*domain.Product, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) FindByIDs(ids []uuid.UUID) ([]*domain.Product, error) {
+	args := m.Called(ids)
+	return args.Get(0).([]*domain.Product), args.Error(1)
+}
+
 func (m *MockProductRepository) List(filters *domain.ProductFilters) ([]*domain.Product, *domain.PaginationResponse, error) {
 	args := m.Called(filters)
 	return args.Get(0).([]*domain.Product), args.Get(1).(*domain.PaginationResponse), args.Error(2)
@@ -48,6 +61,16 @@ func (m *MockProductRepository) IncrementViewCount(id uuid.UUID) error {
 	return args.Error(0)
 }
 
+func (m *MockProductRepository) FindRecentSoldPrices(category string, limit int) ([]int, error) {
+	args := m.Called(category, limit)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockProductRepository) CountFacets(filters *domain.ProductFilters) ([]*domain.ProductFacetCount, error) {
+	args := m.Called(filters)
+	return args.Get(0).([]*domain.ProductFacetCount), args.Error(1)
+}
+
 func TestProductUseCase_GetByID(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockProductRepository)
@@ -63,12 +86,16 @@ func TestProductUseCase_GetByID(t *testing.T) {
 	}
 
 	mockRepo.On("FindByID", productID).Return(expectedProduct, nil)
-	mockRepo.On("IncrementViewCount", productID).Return(nil)
+	viewCounted := make(chan struct{})
+	mockRepo.On("IncrementViewCount", productID).Return(nil).Run(func(mock.Arguments) {
+		close(viewCounted)
+	})
 
 	// Act
 	result, err := useCase.GetByID(productID)
 
 	// Assert
+	<-viewCounted // view count is incremented asynchronously
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, expectedProduct.ID, result.ID)
@@ -124,3 +151,48 @@ func TestProductUseCase_Delete_Unauthorized(t *testing.T) {
 	assert.Contains(t, err.Error(), "unauthorized")
 	mockRepo.AssertExpectations(t)
 }
+
+func TestProductUseCase_Facets_IgnoresOwnSelection(t *testing.T) {
+	// Arrange: electronics and books are selected, with a price range
+	mockRepo := new(MockProductRepository)
+	useCase := usecase.NewProductUseCase(mockRepo, nil)
+	filters := domain.ProductFilters{Categories: []string{"electronics", "books"}, MinPrice: 1000, MaxPrice: 2999}
+	mockRepo.On("CountFacets", &filters).Return([]*domain.ProductFacetCount{
+		{Category: "electronics", Condition: domain.ConditionGood, PriceBucket: 1, CO2Bucket: 2, RatingFloor: 4, PriceOK: true, RatingOK: true, Count: 5},
+		{Category: "electronics", Condition: domain.ConditionNew, PriceBucket: 4, CO2Bucket: 3, RatingFloor: 3, PriceOK: false, RatingOK: true, Count: 2},
+		{Category: "books", Condition: domain.ConditionFair, PriceBucket: 1, CO2Bucket: 0, RatingFloor: 0, PriceOK: true, RatingOK: true, Count: 3},
+		{Category: "furniture", Condition: domain.ConditionGood, PriceBucket: 1, CO2Bucket: 4, RatingFloor: 2, PriceOK: true, RatingOK: true, Count: 4},
+	}, nil)
+
+	// Act
+	facets, err := useCase.Facets(filters)
+
+	// Assert: other categories still count so they can be added
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.FacetBucket{
+		{Value: "electronics", Count: 5, Selected: true},
+		{Value: "furniture", Count: 4},
+		{Value: "books", Count: 3, Selected: true},
+	}, facets.Categories)
+	assert.Equal(t, []domain.FacetBucket{
+		{Value: "good", Count: 5},
+		{Value: "fair", Count: 3},
+	}, facets.Conditions)
+
+	// Price counts ignore the price filter but keep the categories
+	assert.Equal(t, 8, facets.PriceRanges[1].Count)
+	assert.True(t, facets.PriceRanges[1].Selected)
+	assert.Equal(t, 2, facets.PriceRanges[4].Count)
+	assert.Equal(t, 0, facets.PriceRanges[0].Count)
+
+	// CO2 counts apply every filter
+	assert.Equal(t, 3, facets.CO2Impact[0].Count)
+	assert.Equal(t, 5, facets.CO2Impact[2].Count)
+	assert.Equal(t, 0, facets.CO2Impact[3].Count)
+
+	// Seller rating is cumulative: "3+" includes sellers rated 4
+	assert.Equal(t, "4+", facets.SellerRating[0].Value)
+	assert.Equal(t, 5, facets.SellerRating[0].Count)
+	assert.Equal(t, 5, facets.SellerRating[1].Count)
+	assert.Equal(t, 5, facets.SellerRating[3].Count)
+}
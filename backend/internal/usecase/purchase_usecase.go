@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,108 +13,733 @@ type PurchaseUseCase interface {
 	Create(userID uuid.UUID, req *domain.CreatePurchaseRequest) (*domain.Purchase, error)
 	GetByID(id uuid.UUID) (*domain.Purchase, error)
 	ListByUser(userID uuid.UUID, role string, page, limit int) ([]*domain.Purchase, *domain.PaginationResponse, error)
+	Pay(id uuid.UUID, userID uuid.UUID) (*domain.Purchase, error)
+	MarkShipped(id uuid.UUID, userID uuid.UUID) (*domain.Purchase, error)
+	MarkDelivered(id uuid.UUID, userID uuid.UUID) (*domain.Purchase, error)
 	CompletePurchase(id uuid.UUID, userID uuid.UUID) error
+	Cancel(id uuid.UUID, userID uuid.UUID, req *domain.CancelPurchaseRequest) (*domain.Purchase, error)
+	CheckoutOffer(offerID uuid.UUID, userID uuid.UUID, req *domain.CheckoutOfferRequest) (*domain.Purchase, error)
+	ReleaseReservations(now time.Time) (int, error)
 }
 
 type purchaseUseCase struct {
+	uow          domain.UnitOfWork
 	purchaseRepo domain.PurchaseRepository
 	productRepo  domain.ProductRepository
 	userRepo     domain.UserRepository
+	payments     domain.PaymentProvider
+	notifier     NotificationUseCase
 }
 
 func NewPurchaseUseCase(
+	uow domain.UnitOfWork,
 	purchaseRepo domain.PurchaseRepository,
 	productRepo domain.ProductRepository,
 	userRepo domain.UserRepository,
+	payments domain.PaymentProvider,
+	notifier NotificationUseCase,
 ) PurchaseUseCase {
 	return &purchaseUseCase{
+		uow:          uow,
 		purchaseRepo: purchaseRepo,
 		productRepo:  productRepo,
 		userRepo:     userRepo,
+		payments:     payments,
+		notifier:     notifier,
 	}
 }
 
 func (u *purchaseUseCase) Create(userID uuid.UUID, req *domain.CreatePurchaseRequest) (*domain.Purchase, error) {
-	// Get product
-	product, err := u.productRepo.FindByID(req.ProductID)
+	var purchase *domain.Purchase
+
+	// Lock the product row so concurrent buyers are serialized and only the
+	// first one sees it as active
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		product, err := repos.Products.FindByIDForUpdate(req.ProductID)
+		if err != nil {
+			return errors.New("product not found")
+		}
+
+		// Check if product is available
+		if product.Status != domain.StatusActive {
+			return errors.New("product is not available for purchase")
+		}
+
+		// Can't buy own product
+		if product.SellerID == userID {
+			return errors.New("cannot purchase your own product")
+		}
+
+		purchase = &domain.Purchase{
+			BuyerID:         userID,
+			Price:           product.Price,
+			ShippingAddress: req.ShippingAddress,
+			PaymentMethod:   req.PaymentMethod,
+		}
+		return sellProduct(repos, product, purchase, time.Now())
+	})
 	if err != nil {
-		return nil, errors.New("product not found")
+		return nil, err
 	}
 
-	// Check if product is available
-	if product.Status != domain.StatusActive {
-		return nil, errors.New("product is not available for purchase")
+	// Reload with relations
+	return u.purchaseRepo.FindByID(purchase.ID)
+}
+
+// CheckoutOffer buys a product reserved by an accepted offer at the agreed price
+func (u *purchaseUseCase) CheckoutOffer(offerID uuid.UUID, userID uuid.UUID, req *domain.CheckoutOfferRequest) (*domain.Purchase, error) {
+	var purchase *domain.Purchase
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		found, err := repos.Offers.FindByID(offerID)
+		if err != nil {
+			return errors.New("offer not found")
+		}
+
+		// Lock in the order Cancel does: an earlier checkout first, then the product
+		var previous *domain.Purchase
+		if found.PurchaseID != nil {
+			previous, err = repos.Purchases.FindByIDForUpdate(*found.PurchaseID)
+			if err != nil {
+				return errors.New("purchase not found")
+			}
+		}
+
+		product, err := repos.Products.FindByIDForUpdate(found.ProductID)
+		if err != nil {
+			return errors.New("product not found")
+		}
+		offer, err := repos.Offers.FindByIDForUpdate(offerID)
+		if err != nil {
+			return errors.New("offer not found")
+		}
+
+		if offer.BuyerID != userID {
+			return errors.New("only the buyer can check out this offer")
+		}
+		// A checkout that was cancelled leaves the reservation to the buyer
+		if offer.PurchaseID != nil && (previous == nil || previous.ID != *offer.PurchaseID ||
+			(previous.Status != domain.PurchaseStatusCancelled && previous.Status != domain.PurchaseStatusRefunded)) {
+			return errors.New("offer already checked out")
+		}
+		now := time.Now()
+		if !offer.HoldsReservation(now) {
+			return errors.New("offer reservation is no longer valid")
+		}
+		if product.Status != domain.StatusReserved {
+			return errors.New("product is not available for purchase")
+		}
+
+		purchase = &domain.Purchase{
+			BuyerID:         userID,
+			Price:           offer.OfferPrice,
+			ShippingAddress: req.ShippingAddress,
+			PaymentMethod:   req.PaymentMethod,
+		}
+		if err := sellProduct(repos, product, purchase, now); err != nil {
+			return err
+		}
+
+		offer.PurchaseID = &purchase.ID
+		return repos.Offers.Update(offer)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Can't buy own product
-	if product.SellerID == userID {
-		return nil, errors.New("cannot purchase your own product")
+	return u.purchaseRepo.FindByID(purchase.ID)
+}
+
+// ReleaseReservations reopens products held by accepted offers whose buyer
+// did not check out and pay before the reservation ran out. It is run
+// periodically and returns how many reservations were released.
+func (u *purchaseUseCase) ReleaseReservations(now time.Time) (int, error) {
+	var candidates []*domain.Offer
+	if err := u.uow.Do(func(repos *domain.Repositories) error {
+		var err error
+		candidates, err = repos.Offers.FindLapsedReservations(now, 100)
+		return err
+	}); err != nil {
+		return 0, err
 	}
 
-	// Create purchase
-	purchase := &domain.Purchase{
-		ProductID:       product.ID,
-		BuyerID:         userID,
-		SellerID:        product.SellerID,
-		Price:           product.Price,
-		CO2SavedKg:      product.CO2ImpactKg,
-		Status:          domain.PurchaseStatusPending,
-		ShippingAddress: req.ShippingAddress,
-		PaymentMethod:   req.PaymentMethod,
-		CreatedAt:       time.Now(),
+	released := 0
+	for _, candidate := range candidates {
+		var offer *domain.Offer
+		var product *domain.Product
+		err := u.uow.Do(func(repos *domain.Repositories) error {
+			offer = nil
+
+			// Lock in the order Cancel does: purchase first, then product
+			var purchase *domain.Purchase
+			if candidate.PurchaseID != nil {
+				var err error
+				purchase, err = repos.Purchases.FindByIDForUpdate(*candidate.PurchaseID)
+				if err != nil {
+					return err
+				}
+			}
+			var err error
+			product, err = repos.Products.FindByIDForUpdate(candidate.ProductID)
+			if err != nil {
+				return err
+			}
+			locked, err := repos.Offers.FindByIDForUpdate(candidate.ID)
+			if err != nil {
+				return err
+			}
+
+			if locked.Status != domain.OfferStatusAccepted || locked.ReservedUntil == nil || locked.HoldsReservation(now) {
+				return nil
+			}
+			locked.ReservedUntil = nil
+
+			switch {
+			case purchase == nil || purchase.Status == domain.PurchaseStatusCancelled ||
+				purchase.Status == domain.PurchaseStatusRefunding || purchase.Status == domain.PurchaseStatusRefunded:
+				// Never checked out, or the checkout was called off
+				locked.ResponseMessage = "reservation lapsed before checkout"
+			case purchase.Status == domain.PurchaseStatusHolding:
+				// The payment is in flight; look again on the next run
+				return nil
+			case purchase.Status == domain.PurchaseStatusAwaitingPayment:
+				purchase.CancelReason = domain.CancelReasonPaymentNotReceived
+				purchase.CancelNote = "offer reservation lapsed"
+				purchase.CancelledAt = &now
+				if err := u.unwind(repos, purchase, now); err != nil {
+					return err
+				}
+				locked.ResponseMessage = "reservation lapsed before payment"
+			default:
+				// A paid checkout only needs the reservation cleared
+				return repos.Offers.Update(locked)
+			}
+
+			// unwind hands the product back to the reservation, which is now over
+			product, err = repos.Products.FindByIDForUpdate(candidate.ProductID)
+			if err != nil {
+				return err
+			}
+			if product.Status == domain.StatusReserved {
+				product.Status = domain.StatusActive
+				if err := repos.Products.Update(product); err != nil {
+					return err
+				}
+			}
+
+			locked.Status = domain.OfferStatusExpired
+			offer = locked
+			if err := repos.Offers.Update(locked); err != nil {
+				return err
+			}
+			return repos.Offers.AddRound(&domain.OfferRound{
+				OfferID:   offer.ID,
+				Round:     offer.Round,
+				ActorID:   offer.BuyerID,
+				Action:    domain.OfferActionExpire,
+				Price:     offer.OfferPrice,
+				Message:   offer.ResponseMessage,
+				CreatedAt: now,
+			})
+		})
+		if err != nil {
+			return released, err
+		}
+		if offer == nil {
+			continue
+		}
+
+		released++
+		u.notifyReservationReleased(offer, product)
 	}
 
-	if err := u.purchaseRepo.Create(purchase); err != nil {
+	return released, nil
+}
+
+func (u *purchaseUseCase) GetByID(id uuid.UUID) (*domain.Purchase, error) {
+	return u.purchaseRepo.FindByID(id)
+}
+
+func (u *purchaseUseCase) ListByUser(userID uuid.UUID, role string, page, limit int) ([]*domain.Purchase, *domain.PaginationResponse, error) {
+	return u.purchaseRepo.FindByUser(userID, role, page, limit)
+}
+
+// Pay captures the buyer's payment into escrow. The holding intent is
+// committed before the provider is called and its answer recorded in a second
+// transaction, so the provider is never called with a row lock held and a
+// crash in between leaves the purchase in holding, from where Pay can be
+// retried: the provider holds each purchase ID only once.
+func (u *purchaseUseCase) Pay(id uuid.UUID, userID uuid.UUID) (*domain.Purchase, error) {
+	var purchase *domain.Purchase
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		var err error
+		purchase, err = repos.Purchases.FindByIDForUpdate(id)
+		if err != nil {
+			return errors.New("purchase not found")
+		}
+
+		if purchase.BuyerID != userID {
+			return errors.New("only buyer can pay for the purchase")
+		}
+
+		// A hold whose result was never recorded is retried
+		if purchase.Status == domain.PurchaseStatusHolding {
+			return nil
+		}
+
+		if err := transitionPurchase(purchase, domain.PurchaseStatusHolding); err != nil {
+			return err
+		}
+		return repos.Purchases.Update(purchase)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Update product status to sold
-	product.Status = domain.StatusSold
-	if err := u.productRepo.Update(product); err != nil {
+	ref, holdErr := u.payments.Hold(purchase.ID, purchase.Price, purchase.PaymentMethod)
+
+	err = u.uow.Do(func(repos *domain.Repositories) error {
+		purchase, err := repos.Purchases.FindByIDForUpdate(id)
+		if err != nil {
+			return errors.New("purchase not found")
+		}
+
+		// A concurrent retry already recorded the result
+		if purchase.Status != domain.PurchaseStatusHolding {
+			return nil
+		}
+
+		if holdErr != nil {
+			if err := transitionPurchase(purchase, domain.PurchaseStatusAwaitingPayment); err != nil {
+				return err
+			}
+			return repos.Purchases.Update(purchase)
+		}
+
+		if err := transitionPurchase(purchase, domain.PurchaseStatusPaid); err != nil {
+			return err
+		}
+		now := time.Now()
+		purchase.PaymentRef = ref
+		purchase.PaidAt = &now
+		return repos.Purchases.Update(purchase)
+	})
+	if holdErr != nil {
+		return nil, fmt.Errorf("payment failed: %w", holdErr)
+	}
+	if err != nil {
 		return nil, err
 	}
 
-	// Reload with relations
-	return u.purchaseRepo.FindByID(purchase.ID)
+	return u.purchaseRepo.FindByID(id)
 }
 
-func (u *purchaseUseCase) GetByID(id uuid.UUID) (*domain.Purchase, error) {
+// MarkShipped is called by the seller once the item has been handed to the carrier
+func (u *purchaseUseCase) MarkShipped(id uuid.UUID, userID uuid.UUID) (*domain.Purchase, error) {
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		purchase, err := repos.Purchases.FindByIDForUpdate(id)
+		if err != nil {
+			return errors.New("purchase not found")
+		}
+
+		if purchase.SellerID != userID {
+			return errors.New("only seller can mark the purchase as shipped")
+		}
+
+		if err := transitionPurchase(purchase, domain.PurchaseStatusShipped); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		purchase.ShippedAt = &now
+		return repos.Purchases.Update(purchase)
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return u.purchaseRepo.FindByID(id)
 }
 
-func (u *purchaseUseCase) ListByUser(userID uuid.UUID, role string, page, limit int) ([]*domain.Purchase, *domain.PaginationResponse, error) {
-	return u.purchaseRepo.FindByUser(userID, role, page, limit)
+// MarkDelivered records that the carrier delivered the item. Either party may report it.
+func (u *purchaseUseCase) MarkDelivered(id uuid.UUID, userID uuid.UUID) (*domain.Purchase, error) {
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		purchase, err := repos.Purchases.FindByIDForUpdate(id)
+		if err != nil {
+			return errors.New("purchase not found")
+		}
+
+		if purchase.BuyerID != userID && purchase.SellerID != userID {
+			return errors.New("unauthorized: not a party to this purchase")
+		}
+
+		if err := transitionPurchase(purchase, domain.PurchaseStatusDelivered); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		purchase.DeliveredAt = &now
+		return repos.Purchases.Update(purchase)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return u.purchaseRepo.FindByID(id)
 }
 
+// CompletePurchase is the buyer's confirmation of receipt. It releases the
+// escrowed funds to the seller and credits the buyer's CO2 savings.
 func (u *purchaseUseCase) CompletePurchase(id uuid.UUID, userID uuid.UUID) error {
-	purchase, err := u.purchaseRepo.FindByID(id)
+	var purchase *domain.Purchase
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		var err error
+		purchase, err = repos.Purchases.FindByIDForUpdate(id)
+		if err != nil {
+			return errors.New("purchase not found")
+		}
+
+		// Only buyer can complete
+		if purchase.BuyerID != userID {
+			return errors.New("only buyer can complete the purchase")
+		}
+
+		switch purchase.Status {
+		case domain.PurchaseStatusReleasing:
+			// A previous release was interrupted; retry it
+			return nil
+		case domain.PurchaseStatusBuyerConfirmed:
+		default:
+			if err := transitionPurchase(purchase, domain.PurchaseStatusBuyerConfirmed); err != nil {
+				return err
+			}
+			now := time.Now()
+			purchase.ConfirmedAt = &now
+		}
+
+		return beginRelease(repos, purchase)
+	})
+	if err != nil {
+		return err
+	}
+
+	return releaseFunds(u.uow, u.payments, purchase)
+}
+
+// Cancel calls off a purchase before it ships. Paid purchases are refunded
+// once the cancellation is committed; a refund that failed is retried by
+// cancelling again.
+func (u *purchaseUseCase) Cancel(id uuid.UUID, userID uuid.UUID, req *domain.CancelPurchaseRequest) (*domain.Purchase, error) {
+	var purchase *domain.Purchase
+	retry := false
+	err := u.uow.Do(func(repos *domain.Repositories) error {
+		var err error
+		purchase, err = repos.Purchases.FindByIDForUpdate(id)
+		if err != nil {
+			return errors.New("purchase not found")
+		}
+
+		if purchase.Status == domain.PurchaseStatusRefunding {
+			if userID != purchase.BuyerID && userID != purchase.SellerID {
+				return errors.New("unauthorized: not a party to this purchase")
+			}
+			retry = true
+			return nil
+		}
+
+		if purchase.Status != domain.PurchaseStatusAwaitingPayment && purchase.Status != domain.PurchaseStatusPaid {
+			return errors.New("purchase can no longer be cancelled")
+		}
+
+		now := time.Now()
+		switch userID {
+		case purchase.BuyerID:
+			// Buyers may call off a purchase until it ships
+		case purchase.SellerID:
+			purchase.SellerPenalty = sellerCancelPenalty(purchase, req.Reason, now)
+			if purchase.SellerPenalty > 0 {
+				if err := repos.Users.AddPenaltyDue(purchase.SellerID, purchase.SellerPenalty); err != nil {
+					return err
+				}
+			}
+		default:
+			return errors.New("unauthorized: not a party to this purchase")
+		}
+
+		purchase.CancelledBy = &userID
+		purchase.CancelReason = req.Reason
+		purchase.CancelNote = req.Note
+		purchase.CancelledAt = &now
+
+		return u.unwind(repos, purchase, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if purchase.Status == domain.PurchaseStatusRefunding {
+		if err := refundEscrow(u.uow, u.payments, purchase); err != nil {
+			return nil, err
+		}
+	}
+	if !retry {
+		u.notifyCancellation(purchase)
+	}
+
+	return u.purchaseRepo.FindByID(id)
+}
+
+// unwind reverses a purchase that will not go through: the product goes back
+// to the status it had before the sale, so an item bought through an accepted
+// offer is reserved for that buyer again while the reservation lasts, any CO2
+// credit is taken back and escrowed funds are marked for refunding.
+func (u *purchaseUseCase) unwind(repos *domain.Repositories, purchase *domain.Purchase, now time.Time) error {
+	product, err := repos.Products.FindByIDForUpdate(purchase.ProductID)
+	if err != nil {
+		return errors.New("product not found")
+	}
+	product.Status = purchase.ListingStatus
+	if product.Status == "" {
+		product.Status = domain.StatusActive
+	}
+	if product.Status == domain.StatusReserved {
+		held, err := reservationHeld(repos, purchase, now)
+		if err != nil {
+			return err
+		}
+		// ReleaseReservations no longer watches a lapsed reservation, so
+		// reopen the product here
+		if !held {
+			product.Status = domain.StatusActive
+		}
+	}
+	product.SoldAt = nil
+	if err := repos.Products.Update(product); err != nil {
+		return err
+	}
+
+	// The refund and the CO2 reversal follow once this is committed
+	if purchase.PaymentRef != "" {
+		return beginRefund(repos, purchase)
+	}
+
+	if err := transitionPurchase(purchase, domain.PurchaseStatusCancelled); err != nil {
+		return err
+	}
+	if err := repos.Purchases.Update(purchase); err != nil {
+		return err
+	}
+	return reverseSustainability(repos, purchase, now)
+}
+
+// reservationHeld reports whether the accepted offer checked out as purchase
+// still reserves the product at now
+func reservationHeld(repos *domain.Repositories, purchase *domain.Purchase, now time.Time) (bool, error) {
+	offers, err := repos.Offers.FindByProductID(purchase.ProductID)
+	if err != nil {
+		return false, err
+	}
+	for _, offer := range offers {
+		if offer.PurchaseID != nil && *offer.PurchaseID == purchase.ID {
+			return offer.HoldsReservation(now), nil
+		}
+	}
+	return false, nil
+}
+
+// sellProduct records purchase of a locked, available product: the purchase is
+// created awaiting payment, the product marked sold and the buyer's CO2
+// saving logged. The caller sets BuyerID, Price and the checkout details.
+func sellProduct(repos *domain.Repositories, product *domain.Product, purchase *domain.Purchase, now time.Time) error {
+	purchase.ProductID = product.ID
+	purchase.SellerID = product.SellerID
+	purchase.CO2SavedKg = product.CO2ImpactKg
+	purchase.Status = domain.PurchaseStatusAwaitingPayment
+	purchase.ListingStatus = product.Status
+	purchase.CreatedAt = now
+
+	if err := repos.Purchases.Create(purchase); err != nil {
+		return err
+	}
+
+	// Update product status to sold
+	product.Status = domain.StatusSold
+	product.SoldAt = &now
+	if err := repos.Products.Update(product); err != nil {
+		return err
+	}
+
+	return repos.Sustainability.CreateLog(&domain.SustainabilityLog{
+		UserID:      purchase.BuyerID,
+		PurchaseID:  &purchase.ID,
+		ActionType:  "purchase",
+		CO2SavedKg:  purchase.CO2SavedKg,
+		Description: product.Title,
+		CreatedAt:   now,
+	})
+}
+
+// beginRelease records the intent to pay the seller, withholding the
+// cancellation penalties the seller still owes. It must be committed before
+// releaseFunds calls the provider.
+func beginRelease(repos *domain.Repositories, purchase *domain.Purchase) error {
+	if err := transitionPurchase(purchase, domain.PurchaseStatusReleasing); err != nil {
+		return err
+	}
+
+	deduction, err := repos.Users.TakePenaltyDue(purchase.SellerID, purchase.Price)
 	if err != nil {
-		return errors.New("purchase not found")
+		return err
+	}
+	purchase.PayoutDeduction = deduction
+	return repos.Purchases.Update(purchase)
+}
+
+// releaseFunds pays the seller out of escrow for a purchase committed in
+// releasing, then records the payout and credits the buyer's CO2 savings in a
+// second transaction. A failure leaves the purchase in releasing, from where
+// it can be retried: the provider releases each ref only once.
+func releaseFunds(uow domain.UnitOfWork, payments domain.PaymentProvider, purchase *domain.Purchase) error {
+	if err := payments.Release(purchase.PaymentRef, purchase.PayoutDeduction); err != nil {
+		return fmt.Errorf("failed to release funds: %w", err)
 	}
 
-	// Only seller can complete
-	if purchase.SellerID != userID {
-		return errors.New("only seller can complete the purchase")
+	return uow.Do(func(repos *domain.Repositories) error {
+		purchase, err := repos.Purchases.FindByIDForUpdate(purchase.ID)
+		if err != nil {
+			return errors.New("purchase not found")
+		}
+
+		// A concurrent retry already recorded the payout
+		if purchase.Status != domain.PurchaseStatusReleasing {
+			return nil
+		}
+
+		if err := transitionPurchase(purchase, domain.PurchaseStatusFundsReleased); err != nil {
+			return err
+		}
+		now := time.Now()
+		purchase.CompletedAt = &now
+		if err := repos.Purchases.Update(purchase); err != nil {
+			return err
+		}
+
+		// Update buyer's CO2 saved
+		return repos.Users.UpdateSustainabilityStats(purchase.BuyerID, purchase.CO2SavedKg)
+	})
+}
+
+// beginRefund records the intent to refund the buyer. It must be committed
+// before refundEscrow calls the provider.
+func beginRefund(repos *domain.Repositories, purchase *domain.Purchase) error {
+	if err := transitionPurchase(purchase, domain.PurchaseStatusRefunding); err != nil {
+		return err
 	}
+	return repos.Purchases.Update(purchase)
+}
 
-	if purchase.Status != domain.PurchaseStatusPending {
-		return errors.New("purchase is not in pending status")
+// refundEscrow returns the buyer's payment for a purchase committed in
+// refunding, then records the refund and takes back the buyer's CO2 saving in
+// a second transaction. A failure leaves the purchase in refunding, from where
+// it can be retried: the provider refunds each ref only once.
+func refundEscrow(uow domain.UnitOfWork, payments domain.PaymentProvider, purchase *domain.Purchase) error {
+	if err := payments.Refund(purchase.PaymentRef); err != nil {
+		return fmt.Errorf("refund failed: %w", err)
 	}
 
-	// Update status
-	if err := u.purchaseRepo.UpdateStatus(id, domain.PurchaseStatusCompleted); err != nil {
+	return uow.Do(func(repos *domain.Repositories) error {
+		purchase, err := repos.Purchases.FindByIDForUpdate(purchase.ID)
+		if err != nil {
+			return errors.New("purchase not found")
+		}
+
+		// A concurrent retry already recorded the refund
+		if purchase.Status != domain.PurchaseStatusRefunding {
+			return nil
+		}
+
+		if err := transitionPurchase(purchase, domain.PurchaseStatusRefunded); err != nil {
+			return err
+		}
+		now := time.Now()
+		purchase.RefundedAt = &now
+		if err := repos.Purchases.Update(purchase); err != nil {
+			return err
+		}
+		return reverseSustainability(repos, purchase, now)
+	})
+}
+
+// reverseSustainability offsets the log written when the purchase was made
+func reverseSustainability(repos *domain.Repositories, purchase *domain.Purchase, now time.Time) error {
+	if err := repos.Sustainability.CreateLog(&domain.SustainabilityLog{
+		UserID:      purchase.BuyerID,
+		PurchaseID:  &purchase.ID,
+		ActionType:  "purchase_reversal",
+		CO2SavedKg:  -purchase.CO2SavedKg,
+		Description: string(purchase.Status),
+		CreatedAt:   now,
+	}); err != nil {
 		return err
 	}
 
-	// Update buyer's CO2 saved
-	buyer, err := u.userRepo.FindByID(purchase.BuyerID)
-	if err == nil {
-		buyer.TotalCO2SavedKg += purchase.CO2SavedKg
-		_ = u.userRepo.Update(buyer)
+	// Buyers are only credited once funds are released
+	if purchase.CompletedAt != nil {
+		return repos.Users.UpdateSustainabilityStats(purchase.BuyerID, -purchase.CO2SavedKg)
+	}
+	return nil
+}
+
+func (u *purchaseUseCase) notifyCancellation(purchase *domain.Purchase) {
+	if u.notifier == nil {
+		return
+	}
+
+	title := "Purchase cancelled"
+	if purchase.PaymentRef != "" {
+		title = "Purchase cancelled and refunded"
+	}
+	message := fmt.Sprintf("Reason: %s", purchase.CancelReason)
+	link := "/purchases"
+
+	_ = u.notifier.Create(purchase.BuyerID, domain.NotificationTypePurchase, title, message, link)
+	sellerMessage := message
+	if purchase.SellerPenalty > 0 {
+		sellerMessage = fmt.Sprintf("%s. A cancellation penalty of ¥%d will be withheld from your next payouts.", message, purchase.SellerPenalty)
 	}
+	_ = u.notifier.Create(purchase.SellerID, domain.NotificationTypePurchase, title, sellerMessage, link)
+}
+
+func (u *purchaseUseCase) notifyReservationReleased(offer *domain.Offer, product *domain.Product) {
+	if u.notifier == nil {
+		return
+	}
+
+	link := fmt.Sprintf("/products/%s", product.ID)
+	_ = u.notifier.Create(offer.BuyerID, domain.NotificationTypePurchase, "Reservation released",
+		fmt.Sprintf("Your reservation of %s at ¥%d has lapsed", product.Title, offer.OfferPrice), link)
+	_ = u.notifier.Create(product.SellerID, domain.NotificationTypePurchase, "Item available again",
+		fmt.Sprintf("The buyer did not complete the purchase of %s in time", product.Title), link)
+}
 
-	now := time.Now()
-	purchase.CompletedAt = &now
+// sellerCancelPenalty is waived only when the buyer never paid in time
+func sellerCancelPenalty(purchase *domain.Purchase, reason domain.CancelReason, now time.Time) int {
+	if purchase.Status == domain.PurchaseStatusAwaitingPayment &&
+		reason == domain.CancelReasonPaymentNotReceived &&
+		now.Sub(purchase.CreatedAt) > domain.PaymentDeadline {
+		return 0
+	}
 
+	penalty := int(float64(purchase.Price) * domain.SellerCancelPenaltyRate)
+	if penalty < domain.MinSellerCancelPenalty {
+		penalty = domain.MinSellerCancelPenalty
+	}
+	return penalty
+}
+
+func transitionPurchase(purchase *domain.Purchase, next domain.PurchaseStatus) error {
+	if !purchase.Status.CanTransitionTo(next) {
+		return fmt.Errorf("cannot change purchase status from %s to %s", purchase.Status, next)
+	}
+	purchase.Status = next
 	return nil
 }
@@ -0,0 +1,597 @@
+package usecase_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+	"github.com/yourusername/ecomate/backend/internal/usecase"
+)
+
+// memStore is an in-memory stand-in for the database. Its mutex plays the
+// role of the row lock taken by FindByIDForUpdate: a unit of work holds it
+// from start to commit, so concurrent transactions are serialized.
+type memStore struct {
+	mu        sync.Mutex
+	products  map[uuid.UUID]domain.Product
+	purchases map[uuid.UUID]domain.Purchase
+	logs      []domain.SustainabilityLog
+	co2Saved  map[uuid.UUID]float64
+	penalties map[uuid.UUID]int
+	disputes  map[uuid.UUID]domain.Dispute
+	messages  []domain.DisputeMessage
+	auctions  map[uuid.UUID]domain.Auction
+	bids      []domain.Bid
+	bidErr    error // fails bid lookups, like a database outage
+	proxies   []domain.ProxyBid
+	offers    map[uuid.UUID]domain.Offer
+	rounds    []domain.OfferRound
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		products:  make(map[uuid.UUID]domain.Product),
+		purchases: make(map[uuid.UUID]domain.Purchase),
+		co2Saved:  make(map[uuid.UUID]float64),
+		penalties: make(map[uuid.UUID]int),
+		disputes:  make(map[uuid.UUID]domain.Dispute),
+		auctions:  make(map[uuid.UUID]domain.Auction),
+		offers:    make(map[uuid.UUID]domain.Offer),
+	}
+}
+
+type memUnitOfWork struct {
+	store *memStore
+}
+
+func (u *memUnitOfWork) Do(fn func(repos *domain.Repositories) error) error {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+
+	// Snapshot so a failed unit of work can be rolled back
+	products := make(map[uuid.UUID]domain.Product, len(u.store.products))
+	for k, v := range u.store.products {
+		products[k] = v
+	}
+	purchases := make(map[uuid.UUID]domain.Purchase, len(u.store.purchases))
+	for k, v := range u.store.purchases {
+		purchases[k] = v
+	}
+	logs := append([]domain.SustainabilityLog(nil), u.store.logs...)
+	co2Saved := make(map[uuid.UUID]float64, len(u.store.co2Saved))
+	for k, v := range u.store.co2Saved {
+		co2Saved[k] = v
+	}
+	penalties := make(map[uuid.UUID]int, len(u.store.penalties))
+	for k, v := range u.store.penalties {
+		penalties[k] = v
+	}
+	disputes := make(map[uuid.UUID]domain.Dispute, len(u.store.disputes))
+	for k, v := range u.store.disputes {
+		disputes[k] = v
+	}
+	messages := append([]domain.DisputeMessage(nil), u.store.messages...)
+	bids := append([]domain.Bid(nil), u.store.bids...)
+	proxies := append([]domain.ProxyBid(nil), u.store.proxies...)
+	auctions := make(map[uuid.UUID]domain.Auction, len(u.store.auctions))
+	for k, v := range u.store.auctions {
+		auctions[k] = v
+	}
+	offers := make(map[uuid.UUID]domain.Offer, len(u.store.offers))
+	for k, v := range u.store.offers {
+		offers[k] = v
+	}
+	rounds := append([]domain.OfferRound(nil), u.store.rounds...)
+
+	err := fn(&domain.Repositories{
+		Products:       &memProductRepo{store: u.store},
+		Purchases:      &memPurchaseRepo{store: u.store},
+		Users:          &memUserRepo{store: u.store},
+		Sustainability: &memSustainabilityRepo{store: u.store},
+		Disputes:       &memDisputeRepo{store: u.store},
+		Auctions:       &memAuctionRepo{store: u.store},
+		Bids:           &memBidRepo{store: u.store},
+		ProxyBids:      &memProxyBidRepo{store: u.store},
+		Offers:         &memOfferRepo{store: u.store},
+	})
+	if err != nil {
+		u.store.products = products
+		u.store.purchases = purchases
+		u.store.logs = logs
+		u.store.co2Saved = co2Saved
+		u.store.penalties = penalties
+		u.store.disputes = disputes
+		u.store.messages = messages
+		u.store.auctions = auctions
+		u.store.bids = bids
+		u.store.proxies = proxies
+		u.store.offers = offers
+		u.store.rounds = rounds
+	}
+	return err
+}
+
+// memProductRepo is only used inside a unit of work, so the store is already locked
+type memProductRepo struct {
+	domain.ProductRepository
+	store *memStore
+}
+
+func (r *memProductRepo) FindByIDForUpdate(id uuid.UUID) (*domain.Product, error) {
+	p, ok := r.store.products[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &p, nil
+}
+
+func (r *memProductRepo) Update(product *domain.Product) error {
+	r.store.products[product.ID] = *product
+	return nil
+}
+
+type memPurchaseRepo struct {
+	domain.PurchaseRepository
+	store *memStore
+}
+
+func (r *memPurchaseRepo) Create(purchase *domain.Purchase) error {
+	if purchase.ID == uuid.Nil {
+		purchase.ID = uuid.New()
+	}
+	r.store.purchases[purchase.ID] = *purchase
+	return nil
+}
+
+func (r *memPurchaseRepo) FindByIDForUpdate(id uuid.UUID) (*domain.Purchase, error) {
+	p, ok := r.store.purchases[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &p, nil
+}
+
+func (r *memPurchaseRepo) Update(purchase *domain.Purchase) error {
+	r.store.purchases[purchase.ID] = *purchase
+	return nil
+}
+
+type memUserRepo struct {
+	domain.UserRepository
+	store *memStore
+}
+
+func (r *memUserRepo) UpdateSustainabilityStats(userID uuid.UUID, co2SavedKg float64) error {
+	r.store.co2Saved[userID] += co2SavedKg
+	return nil
+}
+
+func (r *memUserRepo) AddPenaltyDue(userID uuid.UUID, amount int) error {
+	r.store.penalties[userID] += amount
+	return nil
+}
+
+func (r *memUserRepo) TakePenaltyDue(userID uuid.UUID, max int) (int, error) {
+	taken := r.store.penalties[userID]
+	if taken > max {
+		taken = max
+	}
+	r.store.penalties[userID] -= taken
+	return taken, nil
+}
+
+type memSustainabilityRepo struct {
+	domain.SustainabilityRepository
+	store *memStore
+}
+
+func (r *memSustainabilityRepo) CreateLog(log *domain.SustainabilityLog) error {
+	r.store.logs = append(r.store.logs, *log)
+	return nil
+}
+
+// readPurchaseRepo serves the reload after the unit of work commits
+type readPurchaseRepo struct {
+	domain.PurchaseRepository
+	store *memStore
+}
+
+func (r *readPurchaseRepo) FindByID(id uuid.UUID) (*domain.Purchase, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.purchases[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	return &p, nil
+}
+
+func TestPurchaseUseCase_Create_ConcurrentBuyersOnlyOneSucceeds(t *testing.T) {
+	// Arrange
+	store := newMemStore()
+	product := domain.Product{
+		ID:          uuid.New(),
+		SellerID:    uuid.New(),
+		Title:       "Road Bike",
+		Price:       20000,
+		CO2ImpactKg: 12.5,
+		Status:      domain.StatusActive,
+	}
+	store.products[product.ID] = product
+
+	useCase := usecase.NewPurchaseUseCase(
+		&memUnitOfWork{store: store},
+		&readPurchaseRepo{store: store},
+		nil,
+		nil,
+		infrastructure.NewLocalPaymentProvider(),
+		nil,
+	)
+
+	const buyers = 50
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	successes := 0
+	start := make(chan struct{})
+
+	// Act
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := useCase.Create(uuid.New(), &domain.CreatePurchaseRequest{
+				ProductID:       product.ID,
+				ShippingAddress: "Tokyo",
+				PaymentMethod:   domain.PaymentMethodCreditCard,
+			})
+			if err == nil {
+				mu.Lock()
+				successes++
+				mu.Unlock()
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, 1, successes)
+	assert.Len(t, store.purchases, 1)
+	assert.Len(t, store.logs, 1)
+	assert.Equal(t, domain.StatusSold, store.products[product.ID].Status)
+}
+
+func TestPurchaseUseCase_Create_OwnProductRollsBack(t *testing.T) {
+	// Arrange
+	store := newMemStore()
+	sellerID := uuid.New()
+	product := domain.Product{
+		ID:       uuid.New(),
+		SellerID: sellerID,
+		Price:    1000,
+		Status:   domain.StatusActive,
+	}
+	store.products[product.ID] = product
+
+	useCase := usecase.NewPurchaseUseCase(&memUnitOfWork{store: store}, &readPurchaseRepo{store: store}, nil, nil, infrastructure.NewLocalPaymentProvider(), nil)
+
+	// Act
+	result, err := useCase.Create(sellerID, &domain.CreatePurchaseRequest{ProductID: product.ID})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "cannot purchase your own product")
+	assert.Empty(t, store.purchases)
+	assert.Equal(t, domain.StatusActive, store.products[product.ID].Status)
+}
+
+func newPurchaseFixture(t *testing.T) (*memStore, *infrastructure.LocalPaymentProvider, usecase.PurchaseUseCase, *domain.Purchase) {
+	t.Helper()
+
+	store := newMemStore()
+	product := domain.Product{
+		ID:          uuid.New(),
+		SellerID:    uuid.New(),
+		Price:       5000,
+		CO2ImpactKg: 3,
+		Status:      domain.StatusActive,
+	}
+	store.products[product.ID] = product
+
+	payments := infrastructure.NewLocalPaymentProvider()
+	useCase := usecase.NewPurchaseUseCase(&memUnitOfWork{store: store}, &readPurchaseRepo{store: store}, nil, nil, payments, nil)
+
+	purchase, err := useCase.Create(uuid.New(), &domain.CreatePurchaseRequest{
+		ProductID:       product.ID,
+		ShippingAddress: "Osaka",
+		PaymentMethod:   domain.PaymentMethodCreditCard,
+	})
+	if err != nil {
+		t.Fatalf("create purchase: %v", err)
+	}
+	return store, payments, useCase, purchase
+}
+
+func TestPurchaseUseCase_SellerCannotCompleteBeforeShipping(t *testing.T) {
+	// Arrange
+	_, _, useCase, purchase := newPurchaseFixture(t)
+
+	// Act
+	_, payErr := useCase.Pay(purchase.ID, purchase.BuyerID)
+	sellerErr := useCase.CompletePurchase(purchase.ID, purchase.SellerID)
+	buyerErr := useCase.CompletePurchase(purchase.ID, purchase.BuyerID)
+
+	// Assert
+	assert.NoError(t, payErr)
+	assert.Error(t, sellerErr)
+	assert.Contains(t, sellerErr.Error(), "only buyer can complete")
+	assert.Error(t, buyerErr)
+	assert.Contains(t, buyerErr.Error(), "cannot change purchase status from paid")
+}
+
+func TestPurchaseUseCase_EscrowLifecycleReleasesFunds(t *testing.T) {
+	// Arrange
+	store, payments, useCase, purchase := newPurchaseFixture(t)
+	assert.Equal(t, domain.PurchaseStatusAwaitingPayment, purchase.Status)
+
+	// Act
+	paid, err := useCase.Pay(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+	assert.Equal(t, "held", payments.State(store.purchases[purchase.ID].PaymentRef))
+
+	_, err = useCase.MarkShipped(purchase.ID, purchase.BuyerID)
+	assert.Error(t, err)
+	_, err = useCase.MarkShipped(purchase.ID, purchase.SellerID)
+	assert.NoError(t, err)
+	_, err = useCase.MarkDelivered(purchase.ID, purchase.SellerID)
+	assert.NoError(t, err)
+	err = useCase.CompletePurchase(purchase.ID, purchase.BuyerID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PurchaseStatusPaid, paid.Status)
+	final := store.purchases[purchase.ID]
+	assert.Equal(t, domain.PurchaseStatusFundsReleased, final.Status)
+	assert.NotNil(t, final.CompletedAt)
+	assert.Equal(t, "released", payments.State(final.PaymentRef))
+	assert.Equal(t, 3.0, store.co2Saved[purchase.BuyerID])
+}
+
+func TestPurchaseUseCase_Cancel_PaidPurchaseIsRefunded(t *testing.T) {
+	// Arrange
+	store, payments, useCase, purchase := newPurchaseFixture(t)
+	_, err := useCase.Pay(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+
+	// Act
+	cancelled, err := useCase.Cancel(purchase.ID, purchase.BuyerID, &domain.CancelPurchaseRequest{
+		Reason: domain.CancelReasonChangedMind,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PurchaseStatusRefunded, cancelled.Status)
+	assert.NotNil(t, cancelled.RefundedAt)
+	assert.Equal(t, purchase.BuyerID, *cancelled.CancelledBy)
+	assert.Zero(t, cancelled.SellerPenalty)
+	assert.Equal(t, "refunded", payments.State(cancelled.PaymentRef))
+	assert.Equal(t, domain.StatusActive, store.products[purchase.ProductID].Status)
+
+	var co2 float64
+	for _, log := range store.logs {
+		co2 += log.CO2SavedKg
+	}
+	assert.Zero(t, co2)
+}
+
+func TestPurchaseUseCase_Cancel_SellerIsPenalizedAndCannotCancelTwice(t *testing.T) {
+	// Arrange
+	_, _, useCase, purchase := newPurchaseFixture(t)
+	_, err := useCase.Pay(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+
+	// Act
+	cancelled, err := useCase.Cancel(purchase.ID, purchase.SellerID, &domain.CancelPurchaseRequest{
+		Reason: domain.CancelReasonOutOfStock,
+	})
+	_, againErr := useCase.Cancel(purchase.ID, purchase.SellerID, &domain.CancelPurchaseRequest{
+		Reason: domain.CancelReasonOther,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 500, cancelled.SellerPenalty)
+	assert.Error(t, againErr)
+	assert.Contains(t, againErr.Error(), "can no longer be cancelled")
+}
+
+// flakyPayments fails the next calls it is told to, like a provider timing out
+type flakyPayments struct {
+	*infrastructure.LocalPaymentProvider
+	failHolds    int
+	failReleases int
+	failRefunds  int
+}
+
+func (p *flakyPayments) Hold(purchaseID uuid.UUID, amount int, method string) (string, error) {
+	if p.failHolds > 0 {
+		p.failHolds--
+		return "", errors.New("provider timeout")
+	}
+	return p.LocalPaymentProvider.Hold(purchaseID, amount, method)
+}
+
+func (p *flakyPayments) Release(ref string, withheld int) error {
+	if p.failReleases > 0 {
+		p.failReleases--
+		return errors.New("provider timeout")
+	}
+	return p.LocalPaymentProvider.Release(ref, withheld)
+}
+
+func (p *flakyPayments) Refund(ref string) error {
+	if p.failRefunds > 0 {
+		p.failRefunds--
+		return errors.New("provider timeout")
+	}
+	return p.LocalPaymentProvider.Refund(ref)
+}
+
+func TestPurchaseUseCase_PaymentCallsAreRecordedAndRetried(t *testing.T) {
+	// Arrange
+	store, local, _, purchase := newPurchaseFixture(t)
+	payments := &flakyPayments{LocalPaymentProvider: local, failHolds: 1, failReleases: 1}
+	useCase := usecase.NewPurchaseUseCase(&memUnitOfWork{store: store}, &readPurchaseRepo{store: store}, nil, nil, payments, nil)
+
+	// Act: the first hold fails and is retried
+	_, failedPay := useCase.Pay(purchase.ID, purchase.BuyerID)
+	afterFailedPay := store.purchases[purchase.ID].Status
+	_, err := useCase.Pay(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+	_, err = useCase.MarkShipped(purchase.ID, purchase.SellerID)
+	assert.NoError(t, err)
+	_, err = useCase.MarkDelivered(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+
+	// The first payout fails after the intent was committed
+	failedRelease := useCase.CompletePurchase(purchase.ID, purchase.BuyerID)
+	afterFailedRelease := store.purchases[purchase.ID]
+	escrowAfterFailedRelease := local.State(afterFailedRelease.PaymentRef)
+	co2AfterFailedRelease := store.co2Saved[purchase.BuyerID]
+	err = useCase.CompletePurchase(purchase.ID, purchase.BuyerID)
+
+	// Assert
+	assert.Error(t, failedPay)
+	assert.Equal(t, domain.PurchaseStatusAwaitingPayment, afterFailedPay)
+	assert.Error(t, failedRelease)
+	assert.Equal(t, domain.PurchaseStatusReleasing, afterFailedRelease.Status)
+	assert.Equal(t, "held", escrowAfterFailedRelease)
+	assert.Zero(t, co2AfterFailedRelease)
+
+	assert.NoError(t, err)
+	final := store.purchases[purchase.ID]
+	assert.Equal(t, domain.PurchaseStatusFundsReleased, final.Status)
+	assert.Equal(t, "released", local.State(final.PaymentRef))
+	assert.Equal(t, 3.0, store.co2Saved[purchase.BuyerID])
+}
+
+func TestPurchaseUseCase_Pay_InterruptedHoldIsNotTakenTwice(t *testing.T) {
+	// Arrange: the provider took the money but the result was never recorded
+	store, payments, useCase, purchase := newPurchaseFixture(t)
+	ref, err := payments.Hold(purchase.ID, purchase.Price, purchase.PaymentMethod)
+	assert.NoError(t, err)
+	interrupted := store.purchases[purchase.ID]
+	interrupted.Status = domain.PurchaseStatusHolding
+	store.purchases[purchase.ID] = interrupted
+
+	// Act
+	paid, err := useCase.Pay(purchase.ID, purchase.BuyerID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PurchaseStatusPaid, paid.Status)
+	assert.Equal(t, ref, paid.PaymentRef)
+	assert.NotNil(t, paid.PaidAt)
+}
+
+func TestPurchaseUseCase_SellerPenaltyIsWithheldFromNextPayout(t *testing.T) {
+	// Arrange: the seller cancels one paid order and sells another item
+	store, payments, useCase, cancelled := newPurchaseFixture(t)
+	_, err := useCase.Pay(cancelled.ID, cancelled.BuyerID)
+	assert.NoError(t, err)
+	_, err = useCase.Cancel(cancelled.ID, cancelled.SellerID, &domain.CancelPurchaseRequest{Reason: domain.CancelReasonOutOfStock})
+	assert.NoError(t, err)
+
+	next := domain.Product{ID: uuid.New(), SellerID: cancelled.SellerID, Price: 8000, Status: domain.StatusActive}
+	store.products[next.ID] = next
+	purchase, err := useCase.Create(uuid.New(), &domain.CreatePurchaseRequest{
+		ProductID:       next.ID,
+		ShippingAddress: "Kyoto",
+		PaymentMethod:   domain.PaymentMethodBankTransfer,
+	})
+	assert.NoError(t, err)
+
+	// Act
+	_, err = useCase.Pay(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+	_, err = useCase.MarkShipped(purchase.ID, purchase.SellerID)
+	assert.NoError(t, err)
+	_, err = useCase.MarkDelivered(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+	err = useCase.CompletePurchase(purchase.ID, purchase.BuyerID)
+
+	// Assert
+	assert.NoError(t, err)
+	final := store.purchases[purchase.ID]
+	assert.Equal(t, 500, final.PayoutDeduction)
+	assert.Equal(t, 500, payments.Withheld(final.PaymentRef))
+	assert.Zero(t, store.penalties[purchase.SellerID])
+}
+
+func TestPurchaseUseCase_Cancel_BuyerMayCancelUntilShipped(t *testing.T) {
+	// Arrange: paid three days ago and not yet shipped
+	store, _, useCase, purchase := newPurchaseFixture(t)
+	_, err := useCase.Pay(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+	paid := store.purchases[purchase.ID]
+	paidAt := paid.PaidAt.Add(-72 * time.Hour)
+	paid.PaidAt = &paidAt
+	store.purchases[purchase.ID] = paid
+
+	shipped, _, shippedUseCase, shippedPurchase := newPurchaseFixture(t)
+	_, err = shippedUseCase.Pay(shippedPurchase.ID, shippedPurchase.BuyerID)
+	assert.NoError(t, err)
+	_, err = shippedUseCase.MarkShipped(shippedPurchase.ID, shippedPurchase.SellerID)
+	assert.NoError(t, err)
+
+	// Act
+	cancelled, err := useCase.Cancel(purchase.ID, purchase.BuyerID, &domain.CancelPurchaseRequest{Reason: domain.CancelReasonShippingDelay})
+	_, shippedErr := shippedUseCase.Cancel(shippedPurchase.ID, shippedPurchase.BuyerID, &domain.CancelPurchaseRequest{Reason: domain.CancelReasonChangedMind})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PurchaseStatusRefunded, cancelled.Status)
+	assert.EqualError(t, shippedErr, "purchase can no longer be cancelled")
+	assert.Equal(t, domain.PurchaseStatusShipped, shipped.purchases[shippedPurchase.ID].Status)
+}
+
+func TestPurchaseUseCase_Cancel_FailedRefundIsRecordedAndRetried(t *testing.T) {
+	// Arrange
+	store, local, _, purchase := newPurchaseFixture(t)
+	payments := &flakyPayments{LocalPaymentProvider: local, failRefunds: 1}
+	useCase := usecase.NewPurchaseUseCase(&memUnitOfWork{store: store}, &readPurchaseRepo{store: store}, nil, nil, payments, nil)
+	_, err := useCase.Pay(purchase.ID, purchase.BuyerID)
+	assert.NoError(t, err)
+
+	// Act: the refund fails after the seller's cancellation was committed
+	_, failedRefund := useCase.Cancel(purchase.ID, purchase.SellerID, &domain.CancelPurchaseRequest{Reason: domain.CancelReasonOutOfStock})
+	afterFailedRefund := store.purchases[purchase.ID]
+	escrowAfterFailedRefund := local.State(afterFailedRefund.PaymentRef)
+	logsAfterFailedRefund := len(store.logs)
+	_, outsiderErr := useCase.Cancel(purchase.ID, uuid.New(), &domain.CancelPurchaseRequest{Reason: domain.CancelReasonOther})
+	refunded, err := useCase.Cancel(purchase.ID, purchase.BuyerID, &domain.CancelPurchaseRequest{Reason: domain.CancelReasonChangedMind})
+
+	// Assert
+	assert.Error(t, failedRefund)
+	assert.Equal(t, domain.PurchaseStatusRefunding, afterFailedRefund.Status)
+	assert.Equal(t, "held", escrowAfterFailedRefund)
+	assert.Equal(t, 1, logsAfterFailedRefund, "the CO2 saving is only reversed once refunded")
+	assert.Equal(t, domain.StatusActive, store.products[purchase.ProductID].Status)
+	assert.EqualError(t, outsiderErr, "unauthorized: not a party to this purchase")
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.PurchaseStatusRefunded, refunded.Status)
+	assert.NotNil(t, refunded.RefundedAt)
+	assert.Equal(t, domain.CancelReasonOutOfStock, refunded.CancelReason, "the retry keeps the original cancellation")
+	assert.Equal(t, "refunded", local.State(refunded.PaymentRef))
+	assert.Equal(t, 500, store.penalties[purchase.SellerID], "the seller is penalized once")
+	assert.Len(t, store.logs, 2)
+}
@@ -43,7 +43,9 @@ func (u *reviewUseCase) Create(reviewerID, productID, purchaseID uuid.UUID, rati
 		return nil, errors.New("product mismatch")
 	}
 
-	if purchase.Status != domain.PurchaseStatusCompleted {
+	switch purchase.Status {
+	case domain.PurchaseStatusBuyerConfirmed, domain.PurchaseStatusReleasing, domain.PurchaseStatusFundsReleased:
+	default:
 		return nil, errors.New("can only review completed purchases")
 	}
 
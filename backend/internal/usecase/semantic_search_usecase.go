@@ -0,0 +1,283 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+)
+
+const (
+	semanticQueueSize      = 256
+	semanticCandidateLimit = 200
+	semanticBackfillPage   = 100
+	similarProductsLimit   = 10
+)
+
+// ProductIndexer keeps a search index in step with product writes
+type ProductIndexer interface {
+	IndexProduct(product *domain.Product)
+	RemoveProduct(productID uuid.UUID)
+}
+
+// SemanticSearchUseCase finds products by meaning rather than by exact words,
+// using embeddings of each product's title and description
+type SemanticSearchUseCase interface {
+	ProductIndexer
+	// Run loads stored embeddings into the index, embeds products that have
+	// none and then embeds queued product writes until ctx is done
+	Run(ctx context.Context) error
+	// Reindex embeds one product now
+	Reindex(ctx context.Context, productID uuid.UUID) error
+	Similar(ctx context.Context, productID uuid.UUID, limit int) ([]*domain.Product, error)
+	// Search ranks active products by similarity to filters.Search and
+	// applies the other filters and pagination to the ranked list
+	Search(ctx context.Context, filters *domain.ProductFilters) ([]*domain.Product, int, error)
+}
+
+type semanticSearchUseCase struct {
+	embedder      infrastructure.Embedder
+	index         infrastructure.VectorIndex
+	embeddingRepo domain.ProductEmbeddingRepository
+	productRepo   domain.ProductRepository
+	queue         chan uuid.UUID
+}
+
+func NewSemanticSearchUseCase(
+	embedder infrastructure.Embedder,
+	index infrastructure.VectorIndex,
+	embeddingRepo domain.ProductEmbeddingRepository,
+	productRepo domain.ProductRepository,
+) SemanticSearchUseCase {
+	return &semanticSearchUseCase{
+		embedder:      embedder,
+		index:         index,
+		embeddingRepo: embeddingRepo,
+		productRepo:   productRepo,
+		queue:         make(chan uuid.UUID, semanticQueueSize),
+	}
+}
+
+// IndexProduct queues the product to be embedded; writes never wait on the
+// embedding model
+func (u *semanticSearchUseCase) IndexProduct(product *domain.Product) {
+	select {
+	case u.queue <- product.ID:
+	default:
+		log.Printf("Embedding queue full, product %s will be indexed on next start", product.ID)
+	}
+}
+
+func (u *semanticSearchUseCase) RemoveProduct(productID uuid.UUID) {
+	u.index.Remove(productID)
+	if err := u.embeddingRepo.Delete(productID); err != nil {
+		log.Printf("Failed to delete embedding of product %s: %v", productID, err)
+	}
+}
+
+func (u *semanticSearchUseCase) Run(ctx context.Context) error {
+	stored, err := u.embeddingRepo.FindByModel(u.embedder.Name())
+	if err != nil {
+		return err
+	}
+	embedded := make(map[uuid.UUID]bool, len(stored))
+	for _, e := range stored {
+		if err := u.index.Upsert(e.ProductID, e.Values()); err == nil {
+			embedded[e.ProductID] = true
+		}
+	}
+
+	for page := 1; ; page++ {
+		products, pagination, err := u.productRepo.List(&domain.ProductFilters{Page: page, Limit: semanticBackfillPage})
+		if err != nil {
+			return err
+		}
+		for _, p := range products {
+			if !embedded[p.ID] {
+				if err := u.embed(ctx, p); err != nil {
+					log.Printf("Failed to embed product %s: %v", p.ID, err)
+				}
+			}
+		}
+		if page >= pagination.TotalPages {
+			break
+		}
+	}
+	log.Printf("Semantic index ready with %d products", u.index.Len())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case productID := <-u.queue:
+			if err := u.Reindex(ctx, productID); err != nil {
+				log.Printf("Failed to embed product %s: %v", productID, err)
+			}
+		}
+	}
+}
+
+func (u *semanticSearchUseCase) Reindex(ctx context.Context, productID uuid.UUID) error {
+	product, err := u.productRepo.FindByID(productID)
+	if err != nil || product.Status != domain.StatusActive {
+		// Only products for sale are searchable
+		u.RemoveProduct(productID)
+		return nil
+	}
+	return u.embed(ctx, product)
+}
+
+// embed stores and indexes the product's vector, reusing the stored one when
+// neither the text nor the model changed
+func (u *semanticSearchUseCase) embed(ctx context.Context, product *domain.Product) error {
+	text := embeddingText(product)
+	hash := sha256.Sum256([]byte(text))
+	contentHash := hex.EncodeToString(hash[:])
+
+	if existing, err := u.embeddingRepo.FindByProductID(product.ID); err == nil &&
+		existing.Model == u.embedder.Name() && existing.ContentHash == contentHash {
+		return u.index.Upsert(product.ID, existing.Values())
+	}
+
+	vectors, err := u.embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return err
+	}
+
+	embedding := &domain.ProductEmbedding{
+		ProductID:   product.ID,
+		Model:       u.embedder.Name(),
+		ContentHash: contentHash,
+	}
+	embedding.SetValues(vectors[0])
+	if err := u.embeddingRepo.Save(embedding); err != nil {
+		return err
+	}
+	return u.index.Upsert(product.ID, vectors[0])
+}
+
+func (u *semanticSearchUseCase) Similar(ctx context.Context, productID uuid.UUID, limit int) ([]*domain.Product, error) {
+	if limit <= 0 || limit > similarProductsLimit*5 {
+		limit = similarProductsLimit
+	}
+
+	product, err := u.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	var vector []float32
+	if existing, err := u.embeddingRepo.FindByProductID(productID); err == nil && existing.Model == u.embedder.Name() {
+		vector = existing.Values()
+	} else {
+		vectors, err := u.embedder.Embed(ctx, []string{embeddingText(product)})
+		if err != nil {
+			return nil, err
+		}
+		vector = vectors[0]
+	}
+
+	// Ask for extra hits as the product itself and sold items are dropped
+	products, err := u.activeProducts(u.index.Search(vector, 2*limit+1), productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(products) > limit {
+		products = products[:limit]
+	}
+	return products, nil
+}
+
+func (u *semanticSearchUseCase) Search(ctx context.Context, filters *domain.ProductFilters) ([]*domain.Product, int, error) {
+	query := strings.TrimSpace(filters.Search)
+	if query == "" {
+		return nil, 0, errors.New("search query is required")
+	}
+
+	vectors, err := u.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ranked, err := u.activeProducts(u.index.Search(vectors[0], semanticCandidateLimit), uuid.Nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	matched := make([]*domain.Product, 0, len(ranked))
+	for _, p := range ranked {
+		if matchesFilters(p, filters) {
+			matched = append(matched, p)
+		}
+	}
+
+	page, limit := filters.Page, filters.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	start := (page - 1) * limit
+	if start >= len(matched) {
+		return []*domain.Product{}, len(matched), nil
+	}
+	end := start + limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return matched[start:end], len(matched), nil
+}
+
+// activeProducts loads the hits that are still for sale, best first
+func (u *semanticSearchUseCase) activeProducts(hits []infrastructure.VectorHit, exclude uuid.UUID) ([]*domain.Product, error) {
+	ids := make([]uuid.UUID, 0, len(hits))
+	for _, hit := range hits {
+		if hit.ID != exclude {
+			ids = append(ids, hit.ID)
+		}
+	}
+
+	found, err := u.productRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*domain.Product, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+
+	products := make([]*domain.Product, 0, len(ids))
+	for _, id := range ids {
+		if p, ok := byID[id]; ok && p.Status == domain.StatusActive {
+			products = append(products, p)
+		}
+	}
+	return products, nil
+}
+
+func matchesFilters(p *domain.Product, filters *domain.ProductFilters) bool {
+	if categories := filters.CategoryValues(); len(categories) > 0 && !containsString(categories, p.Category) {
+		return false
+	}
+	if conditions := filters.ConditionValues(); len(conditions) > 0 && !containsCondition(conditions, p.Condition) {
+		return false
+	}
+	if filters.MinPrice > 0 && p.Price < filters.MinPrice {
+		return false
+	}
+	if filters.MaxPrice > 0 && p.Price > filters.MaxPrice {
+		return false
+	}
+	return true
+}
+
+func embeddingText(product *domain.Product) string {
+	return product.Title + "\n" + product.Category + "\n" + product.Description
+}
@@ -0,0 +1,114 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yourusername/ecomate/backend/internal/domain"
+	"github.com/yourusername/ecomate/backend/internal/infrastructure"
+	"github.com/yourusername/ecomate/backend/internal/usecase"
+)
+
+// memEmbeddingRepo keeps embeddings in memory
+type memEmbeddingRepo struct {
+	embeddings map[uuid.UUID]*domain.ProductEmbedding
+}
+
+func (r *memEmbeddingRepo) Save(embedding *domain.ProductEmbedding) error {
+	saved := *embedding
+	r.embeddings[embedding.ProductID] = &saved
+	return nil
+}
+
+func (r *memEmbeddingRepo) FindByProductID(productID uuid.UUID) (*domain.ProductEmbedding, error) {
+	if embedding, ok := r.embeddings[productID]; ok {
+		return embedding, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memEmbeddingRepo) FindByModel(model string) ([]*domain.ProductEmbedding, error) {
+	var found []*domain.ProductEmbedding
+	for _, embedding := range r.embeddings {
+		if embedding.Model == model {
+			found = append(found, embedding)
+		}
+	}
+	return found, nil
+}
+
+func (r *memEmbeddingRepo) Delete(productID uuid.UUID) error {
+	delete(r.embeddings, productID)
+	return nil
+}
+
+// countingEmbedder counts the texts sent to the model
+type countingEmbedder struct {
+	infrastructure.Embedder
+	texts int
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.texts += len(texts)
+	return e.Embedder.Embed(ctx, texts)
+}
+
+func TestSemanticSearchUseCase_SimilarAndSearch(t *testing.T) {
+	// Arrange
+	roadBike := &domain.Product{ID: uuid.New(), Title: "Road bike", Description: "Aluminium road bicycle, 21 gears", Category: "sports", Price: 30000, Status: domain.StatusActive}
+	mountainBike := &domain.Product{ID: uuid.New(), Title: "Mountain bike", Description: "Bicycle with front suspension and 21 gears", Category: "sports", Price: 25000, Status: domain.StatusActive}
+	kidsBike := &domain.Product{ID: uuid.New(), Title: "Kids bike", Description: "Small bicycle with training wheels", Category: "toys", Price: 5000, Status: domain.StatusActive}
+	sofa := &domain.Product{ID: uuid.New(), Title: "Leather sofa", Description: "Three seat sofa for the living room", Category: "furniture", Price: 40000, Status: domain.StatusActive}
+	soldBike := &domain.Product{ID: uuid.New(), Title: "Road bike", Description: "Carbon road bicycle, 22 gears", Category: "sports", Price: 90000, Status: domain.StatusSold}
+	all := []*domain.Product{roadBike, mountainBike, kidsBike, sofa, soldBike}
+
+	mockProductRepo := new(MockProductRepository)
+	for _, p := range all {
+		mockProductRepo.On("FindByID", p.ID).Return(p, nil)
+	}
+	mockProductRepo.On("FindByIDs", mock.Anything).Return(all, nil)
+
+	embedder := &countingEmbedder{Embedder: infrastructure.NewHashingEmbedder(256)}
+	embeddings := &memEmbeddingRepo{embeddings: map[uuid.UUID]*domain.ProductEmbedding{}}
+	index := infrastructure.NewHNSWIndex(16, 200, 64)
+	useCase := usecase.NewSemanticSearchUseCase(embedder, index, embeddings, mockProductRepo)
+
+	ctx := context.Background()
+	for _, p := range all {
+		assert.NoError(t, useCase.Reindex(ctx, p.ID))
+	}
+
+	// Assert: only products for sale are embedded
+	assert.Equal(t, 4, index.Len())
+	assert.Equal(t, 4, embedder.texts)
+	assert.NotContains(t, embeddings.embeddings, soldBike.ID)
+
+	// Unchanged products are not sent to the model again
+	assert.NoError(t, useCase.Reindex(ctx, roadBike.ID))
+	assert.Equal(t, 4, embedder.texts)
+
+	similar, err := useCase.Similar(ctx, roadBike.ID, 2)
+	assert.NoError(t, err)
+	assert.Len(t, similar, 2)
+	assert.Equal(t, mountainBike.ID, similar[0].ID)
+	for _, p := range similar {
+		assert.NotEqual(t, roadBike.ID, p.ID)
+		assert.NotEqual(t, soldBike.ID, p.ID)
+	}
+	assert.Equal(t, 4, embedder.texts)
+
+	products, total, err := useCase.Search(ctx, &domain.ProductFilters{Search: "bicycle with gears", Category: "sports"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.ElementsMatch(t, []uuid.UUID{roadBike.ID, mountainBike.ID}, []uuid.UUID{products[0].ID, products[1].ID})
+
+	// Deleted products drop out of the index
+	useCase.RemoveProduct(mountainBike.ID)
+	similar, err = useCase.Similar(ctx, roadBike.ID, 2)
+	assert.NoError(t, err)
+	assert.NotEqual(t, mountainBike.ID, similar[0].ID)
+}
@@ -1,6 +1,6 @@
 import { useState, useEffect } from 'react'
 import { useNavigate, useParams } from 'react-router-dom'
-import { purchaseService } from '@/services/purchases'
+import { purchaseService, PaymentMethod } from '@/services/purchases'
 import { productService } from '@/services/products'
 import { Button } from '@/components/common/Button'
 import { Card } from '@/components/common/Card'
@@ -12,7 +12,7 @@ export const PurchaseProduct = () => {
   const navigate = useNavigate()
   const [loading, setLoading] = useState(false)
   const [product, setProduct] = useState<Product | null>(null)
-  const [formData, setFormData] = useState({
+  const [formData, setFormData] = useState<{ shipping_address: string; payment_method: PaymentMethod }>({
     shipping_address: '',
     payment_method: 'credit_card',
   })
@@ -105,7 +105,7 @@ export const PurchaseProduct = () => {
                 className="w-full px-3 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-primary-500 focus:border-transparent"
                 value={formData.payment_method}
                 onChange={(e) =>
-                  setFormData({ ...formData, payment_method: e.target.value })
+                  setFormData({ ...formData, payment_method: e.target.value as PaymentMethod })
                 }
               >
                 <option value="credit_card">Credit Card</option>
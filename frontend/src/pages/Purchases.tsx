@@ -1,6 +1,7 @@
 import { useState, useEffect } from 'react'
 import { useNavigate } from 'react-router-dom'
-import { purchaseService, Purchase } from '@/services/purchases'
+import { purchaseService, Purchase, PurchaseStatus } from '@/services/purchases'
+import { useAuthStore } from '@/store/authStore'
 import { Button } from '@/components/common/Button'
 import { Card } from '@/components/common/Card'
 import { Header } from '@/components/layout/Header'
@@ -8,6 +9,7 @@ import toast from 'react-hot-toast'
 
 export const Purchases = () => {
   const navigate = useNavigate()
+  const { user } = useAuthStore()
   const [purchases, setPurchases] = useState<Purchase[]>([])
   const [loading, setLoading] = useState(true)
   const [filter, setFilter] = useState<'all' | 'buyer' | 'seller'>('all')
@@ -29,29 +31,100 @@ export const Purchases = () => {
     }
   }
 
-  const handleComplete = async (id: string) => {
+  const runAction = async (action: () => Promise<unknown>, success: string) => {
     try {
-      await purchaseService.complete(id)
-      toast.success('Purchase completed!')
+      await action()
+      toast.success(success)
       loadPurchases()
     } catch (error: any) {
-      toast.error(error.response?.data?.error || 'Failed to complete purchase')
+      toast.error(error.response?.data?.error || 'Failed to update purchase')
     }
   }
 
-  const getStatusBadge = (status: string) => {
-    const colors = {
-      pending: 'bg-yellow-100 text-yellow-800',
-      completed: 'bg-green-100 text-green-800',
+  const statusLabels: Record<PurchaseStatus, string> = {
+    awaiting_payment: 'Awaiting payment',
+    holding: 'Processing payment',
+    paid: 'Paid',
+    shipped: 'Shipped',
+    delivered: 'Delivered',
+    buyer_confirmed: 'Receipt confirmed',
+    releasing: 'Releasing payment',
+    funds_released: 'Completed',
+    cancelled: 'Cancelled',
+    refunding: 'Refunding payment',
+    refunded: 'Refunded',
+    disputed: 'Disputed',
+  }
+
+  const getStatusBadge = (status: PurchaseStatus) => {
+    const colors: Record<PurchaseStatus, string> = {
+      awaiting_payment: 'bg-yellow-100 text-yellow-800',
+      holding: 'bg-yellow-100 text-yellow-800',
+      paid: 'bg-blue-100 text-blue-800',
+      shipped: 'bg-blue-100 text-blue-800',
+      delivered: 'bg-blue-100 text-blue-800',
+      buyer_confirmed: 'bg-green-100 text-green-800',
+      releasing: 'bg-green-100 text-green-800',
+      funds_released: 'bg-green-100 text-green-800',
       cancelled: 'bg-red-100 text-red-800',
+      refunding: 'bg-red-100 text-red-800',
+      refunded: 'bg-red-100 text-red-800',
+      disputed: 'bg-orange-100 text-orange-800',
     }
     return (
-      <span className={`px-2 py-1 rounded-full text-xs font-medium ${colors[status as keyof typeof colors]}`}>
-        {status}
+      <span className={`px-2 py-1 rounded-full text-xs font-medium ${colors[status]}`}>
+        {statusLabels[status]}
       </span>
     )
   }
 
+  const renderActions = (purchase: Purchase) => {
+    const isBuyer = purchase.buyer_id === user?.id
+    const isSeller = purchase.seller_id === user?.id
+    // Cancelling a purchase stuck in refunding retries the refund
+    const canCancel =
+      (isBuyer || isSeller) && ['awaiting_payment', 'paid', 'refunding'].includes(purchase.status)
+
+    return (
+      <div className="flex flex-col gap-2">
+        {isBuyer && (purchase.status === 'awaiting_payment' || purchase.status === 'holding') && (
+          <Button size="sm" onClick={() => runAction(() => purchaseService.pay(purchase.id), 'Payment received')}>
+            Pay
+          </Button>
+        )}
+        {isSeller && purchase.status === 'paid' && (
+          <Button size="sm" onClick={() => runAction(() => purchaseService.ship(purchase.id), 'Marked as shipped')}>
+            Mark Shipped
+          </Button>
+        )}
+        {(isBuyer || isSeller) && purchase.status === 'shipped' && (
+          <Button size="sm" onClick={() => runAction(() => purchaseService.deliver(purchase.id), 'Marked as delivered')}>
+            Mark Delivered
+          </Button>
+        )}
+        {isBuyer && ['delivered', 'buyer_confirmed', 'releasing'].includes(purchase.status) && (
+          <Button size="sm" onClick={() => runAction(() => purchaseService.complete(purchase.id), 'Purchase completed!')}>
+            Confirm Receipt
+          </Button>
+        )}
+        {canCancel && (
+          <Button
+            size="sm"
+            variant="outline"
+            onClick={() =>
+              runAction(
+                () => purchaseService.cancel(purchase.id, isBuyer ? 'changed_mind' : 'out_of_stock'),
+                'Purchase cancelled'
+              )
+            }
+          >
+            Cancel
+          </Button>
+        )}
+      </div>
+    )
+  }
+
   return (
     <div className="min-h-screen">
       <Header />
@@ -119,16 +192,7 @@ export const Purchases = () => {
                       {new Date(purchase.created_at).toLocaleDateString()}
                     </p>
                   </div>
-                  <div className="ml-4">
-                    {purchase.status === 'pending' && filter === 'seller' && (
-                      <Button
-                        size="sm"
-                        onClick={() => handleComplete(purchase.id)}
-                      >
-                        Complete
-                      </Button>
-                    )}
-                  </div>
+                  <div className="ml-4">{renderActions(purchase)}</div>
                 </div>
               </Card>
             ))}
@@ -1,5 +1,30 @@
 import { api } from './api'
 
+// Escrow lifecycle: holding, releasing and refunding mean the payment provider is being called
+export type PurchaseStatus =
+  | 'awaiting_payment'
+  | 'holding'
+  | 'paid'
+  | 'shipped'
+  | 'delivered'
+  | 'buyer_confirmed'
+  | 'releasing'
+  | 'funds_released'
+  | 'cancelled'
+  | 'refunding'
+  | 'refunded'
+  | 'disputed'
+
+export type PaymentMethod = 'credit_card' | 'bank_transfer' | 'cash_on_delivery'
+
+export type CancelReason =
+  | 'changed_mind'
+  | 'payment_not_received'
+  | 'out_of_stock'
+  | 'item_damaged'
+  | 'shipping_delay'
+  | 'other'
+
 export interface Purchase {
   id: string
   product_id: string
@@ -7,10 +32,16 @@ export interface Purchase {
   seller_id: string
   price: number
   co2_saved_kg: number
-  status: 'pending' | 'completed' | 'cancelled'
-  payment_method: string
+  status: PurchaseStatus
+  payment_method: PaymentMethod
   shipping_address: string
+  paid_at?: string
+  shipped_at?: string
+  delivered_at?: string
   completed_at?: string
+  cancelled_at?: string
+  cancel_reason?: CancelReason
+  seller_penalty: number
   created_at: string
   product?: any
   buyer?: any
@@ -20,7 +51,7 @@ export interface Purchase {
 export interface CreatePurchaseRequest {
   product_id: string
   shipping_address: string
-  payment_method: string
+  payment_method: PaymentMethod
 }
 
 export interface PurchaseListResponse {
@@ -56,9 +87,29 @@ class PurchaseService {
     return response.data
   }
 
+  async pay(id: string): Promise<Purchase> {
+    const response = await api.post(`/purchases/${id}/pay`)
+    return response.data
+  }
+
+  async ship(id: string): Promise<Purchase> {
+    const response = await api.post(`/purchases/${id}/ship`)
+    return response.data
+  }
+
+  async deliver(id: string): Promise<Purchase> {
+    const response = await api.post(`/purchases/${id}/deliver`)
+    return response.data
+  }
+
   async complete(id: string): Promise<void> {
     await api.patch(`/purchases/${id}/complete`)
   }
+
+  async cancel(id: string, reason: CancelReason, note = ''): Promise<Purchase> {
+    const response = await api.post(`/purchases/${id}/cancel`, { reason, note })
+    return response.data
+  }
 }
 
 export const purchaseService = new PurchaseService()
@@ -23,8 +23,10 @@ export const useWebSocket = (conversationId: string | null, token: string | null
   useEffect(() => {
     if (!conversationId || !token) return;
 
+    // The token goes in the subprotocols rather than the URL, which servers log
     const ws = new WebSocket(
-      `ws://localhost:8080/v1/ws/conversations/${conversationId}?token=${token}`
+      `ws://localhost:8080/v1/ws/conversations/${conversationId}`,
+      ['bearer', token]
     );
 
     ws.onopen = () => {
@@ -60,8 +60,20 @@ export interface Purchase {
   seller?: User
   price: number
   co2_saved_kg: number
-  status: 'pending' | 'completed' | 'cancelled'
-  payment_method?: string
+  status:
+    | 'awaiting_payment'
+    | 'holding'
+    | 'paid'
+    | 'shipped'
+    | 'delivered'
+    | 'buyer_confirmed'
+    | 'releasing'
+    | 'funds_released'
+    | 'cancelled'
+    | 'refunding'
+    | 'refunded'
+    | 'disputed'
+  payment_method?: 'credit_card' | 'bank_transfer' | 'cash_on_delivery'
   shipping_address?: string
   completed_at?: string
   created_at: string